@@ -6,9 +6,19 @@ import (
 	"math/big"
 
 	//bn256 "github.com/ethereum/go-ethereum/crypto/bn256/google"
+	"github.com/AUKUS561/PVOABE/opcount"
 	"github.com/fentec-project/bn256"
 )
 
+// ProofCost 与 VerifyCost 是 Proof 与 Verify 的运算次数，供调用方的 opcount.Counter 累加，
+// ProofG1Cost 与 VerifyG1Cost 是 ProofG1 与 VerifyG1 的运算次数
+var (
+	ProofCost    = opcount.Counts{GTExp: 1, G1Mul: 1}
+	VerifyCost   = opcount.Counts{GTExp: 2, G1Mul: 2}
+	ProofG1Cost  = opcount.Counts{G1Mul: 2}
+	VerifyG1Cost = opcount.Counts{G1Mul: 4}
+)
+
 type Prfs struct {
 	C, T *big.Int
 	A    *bn256.GT
@@ -16,6 +26,12 @@ type Prfs struct {
 }
 
 func Proof(x *big.Int, u, y1 *bn256.GT, v, y2 *bn256.G1) (*Prfs, error) {
+	return ProofWithContext(nil, x, u, y1, v, y2)
+}
+
+// ProofWithContext 与 Proof 相同，但把上下文 ctx（如策略摘要）绑定进挑战，
+// 证明只能在相同的 ctx 下通过验证
+func ProofWithContext(ctx []byte, x *big.Int, u, y1 *bn256.GT, v, y2 *bn256.G1) (*Prfs, error) {
 	//生成承诺
 	r, err := rand.Int(rand.Reader, bn256.Order)
 	if err != nil {
@@ -25,15 +41,7 @@ func Proof(x *big.Int, u, y1 *bn256.GT, v, y2 *bn256.G1) (*Prfs, error) {
 	b := new(bn256.G1).ScalarMult(v, r)
 
 	// 计算挑战
-	new_hash := sha256.New()
-	new_hash.Write(a.Marshal())
-	new_hash.Write(b.Marshal())
-	new_hash.Write(y1.Marshal())
-	new_hash.Write(y2.Marshal())
-
-	cb := new_hash.Sum(nil)
-	c := new(big.Int).SetBytes(cb)
-	c.Mod(c, bn256.Order)
+	c := challenge(ctx, a, y1, b, y2)
 
 	// 生成响应 t=r-cx
 	t := new(big.Int).Mul(c, x)
@@ -47,11 +55,88 @@ func Proof(x *big.Int, u, y1 *bn256.GT, v, y2 *bn256.G1) (*Prfs, error) {
 
 // Verify verifies the DLEQ proof
 func Verify(pi *Prfs, u, y1 *bn256.GT, v, y2 *bn256.G1) bool {
+	return VerifyWithContext(nil, pi, u, y1, v, y2)
+}
+
+// VerifyWithContext verifies a proof produced by ProofWithContext under the same ctx
+func VerifyWithContext(ctx []byte, pi *Prfs, u, y1 *bn256.GT, v, y2 *bn256.G1) bool {
+	if pi == nil {
+		return false
+	}
 	ut := new(bn256.GT).ScalarMult(u, pi.T)
 	vt := new(bn256.G1).ScalarMult(v, pi.T)
 	cy1 := new(bn256.GT).ScalarMult(y1, pi.C)
 	cy2 := new(bn256.G1).ScalarMult(y2, pi.C)
 	a := new(bn256.GT).Add(ut, cy1)
 	b := new(bn256.G1).Add(vt, cy2)
-	return (pi.A.String() == a.String() && pi.B.String() == b.String())
+	if pi.A.String() != a.String() || pi.B.String() != b.String() {
+		return false
+	}
+	return pi.C.Cmp(challenge(ctx, pi.A, y1, pi.B, y2)) == 0
+}
+
+// challenge 计算 Fiat-Shamir 挑战 c = H(ctx, a, b, y1, y2)
+// ctx 为空时与原有的 H(a, b, y1, y2) 一致
+func challenge(ctx []byte, a, y1 *bn256.GT, b, y2 *bn256.G1) *big.Int {
+	new_hash := sha256.New()
+	if len(ctx) > 0 {
+		new_hash.Write(ctx)
+	}
+	new_hash.Write(a.Marshal())
+	new_hash.Write(b.Marshal())
+	new_hash.Write(y1.Marshal())
+	new_hash.Write(y2.Marshal())
+
+	cb := new_hash.Sum(nil)
+	c := new(big.Int).SetBytes(cb)
+	c.Mod(c, bn256.Order)
+	return c
+}
+
+// PrfsG1 是两对 G1 元素的 DLEQ 证明：log_u y1 = log_v y2
+type PrfsG1 struct {
+	C, T *big.Int
+	A, B *bn256.G1
+}
+
+// ProofG1 证明 y1 = u^x 与 y2 = v^x，u、y1、v、y2 都在 G1 中；挑战绑定 ctx 与两个底
+func ProofG1(ctx []byte, x *big.Int, u, y1, v, y2 *bn256.G1) (*PrfsG1, error) {
+	r, err := rand.Int(rand.Reader, bn256.Order)
+	if err != nil {
+		return nil, err
+	}
+	a := new(bn256.G1).ScalarMult(u, r)
+	b := new(bn256.G1).ScalarMult(v, r)
+	c := challengeG1(ctx, u, y1, v, y2, a, b)
+
+	// t = r - cx
+	t := new(big.Int).Mul(c, x)
+	t.Sub(r, t)
+	t.Mod(t, bn256.Order)
+	return &PrfsG1{C: c, T: t, A: a, B: b}, nil
+}
+
+// VerifyG1 验证 ProofG1 在相同 ctx 下给出的证明
+func VerifyG1(ctx []byte, pi *PrfsG1, u, y1, v, y2 *bn256.G1) bool {
+	if pi == nil || pi.C == nil || pi.T == nil || pi.A == nil || pi.B == nil {
+		return false
+	}
+	a := new(bn256.G1).Add(new(bn256.G1).ScalarMult(u, pi.T), new(bn256.G1).ScalarMult(y1, pi.C))
+	b := new(bn256.G1).Add(new(bn256.G1).ScalarMult(v, pi.T), new(bn256.G1).ScalarMult(y2, pi.C))
+	if pi.A.String() != a.String() || pi.B.String() != b.String() {
+		return false
+	}
+	return pi.C.Cmp(challengeG1(ctx, u, y1, v, y2, pi.A, pi.B)) == 0
+}
+
+// challengeG1 计算 ProofG1 的挑战 c = H(ctx, u, y1, v, y2, a, b)
+func challengeG1(ctx []byte, elems ...*bn256.G1) *big.Int {
+	h := sha256.New()
+	h.Write([]byte("DLEQ/G1"))
+	h.Write(ctx)
+	for _, e := range elems {
+		h.Write(e.Marshal())
+	}
+	c := new(big.Int).SetBytes(h.Sum(nil))
+	return c.Mod(c, bn256.Order)
 }
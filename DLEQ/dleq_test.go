@@ -24,3 +24,25 @@ func TestDLEQ(t *testing.T) {
 	result := Verify(proof, g, gs, h, hs)
 	t.Logf("result:%v", result)
 }
+
+func TestDLEQG1(t *testing.T) {
+	x := big.NewInt(666)
+	u := new(bn256.G1).ScalarBaseMult(big.NewInt(3))
+	v := new(bn256.G1).ScalarBaseMult(big.NewInt(5))
+	ux := new(bn256.G1).ScalarMult(u, x)
+	vx := new(bn256.G1).ScalarMult(v, x)
+
+	proof, err := ProofG1([]byte("ctx"), x, u, ux, v, vx)
+	if err != nil {
+		t.Fatal("fail to generate proof")
+	}
+	if !VerifyG1([]byte("ctx"), proof, u, ux, v, vx) {
+		t.Error("valid proof rejected")
+	}
+	if VerifyG1([]byte("other"), proof, u, ux, v, vx) {
+		t.Error("proof accepted under another context")
+	}
+	if VerifyG1([]byte("ctx"), proof, u, ux, v, new(bn256.G1).Add(vx, v)) {
+		t.Error("proof accepted for unequal logarithms")
+	}
+}
@@ -6,16 +6,24 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
-	"strconv"
 
 	"github.com/AUKUS561/PVOABE/LSSS"
+	"github.com/AUKUS561/PVOABE/opcount"
+	"github.com/AUKUS561/PVOABE/policy"
+	"github.com/AUKUS561/PVOABE/policygen"
 	"github.com/fentec-project/bn256"
 	"github.com/fentec-project/gofe/abe"
 	"github.com/fentec-project/gofe/sample"
 )
 
 type ECPABE struct {
-	P *big.Int
+	P   *big.Int
+	Ops *opcount.Counter //运算计数，nil 表示不计数
+}
+
+// SetCounter 设置运算计数器，c 为 nil 时关闭计数
+func (ecpabe *ECPABE) SetCounter(c *opcount.Counter) {
+	ecpabe.Ops = c
 }
 
 func NewECPABE() *ECPABE {
@@ -45,12 +53,12 @@ func (ecpabe *ECPABE) Setup() (*MK, *PK) {
 
 	g := new(bn256.G1).ScalarBaseMult(big.NewInt(1))
 	g2 := new(bn256.G2).ScalarBaseMult(big.NewInt(1))
-	hG2 := new(bn256.G2).ScalarMult(g2, beta)    //h = g^β
-	galpha := new(bn256.G1).ScalarMult(g, alpha) //g^alpha
+	hG2 := ecpabe.Ops.G2Mul(g2, beta)    //h = g^β
+	galpha := ecpabe.Ops.G1Mul(g, alpha) //g^alpha
 	//galpha2 := new(bn256.G2).ScalarMult(g2, alpha)
 
-	egg := bn256.Pair(g, g2)
-	base := new(bn256.GT).ScalarMult(egg, alpha)
+	egg := ecpabe.Ops.Pair(g, g2)
+	base := ecpabe.Ops.GTExp(egg, alpha)
 	return &MK{
 			Galpha: galpha,
 			//Galpha2: galpha2,
@@ -99,11 +107,11 @@ func (ecpabe *ECPABE) KeyGen(U []string, MK *MK, Si []string) (EncKey, DecKey *b
 		h2 := H2(buf, ecpabe.P)
 		invH2 := new(big.Int).ModInverse(h2, ecpabe.P)
 		// UP{i,u,1} = g^{1 / h2}
-		up.UP1[u] = new(bn256.G1).ScalarMult(g, invH2)
+		up.UP1[u] = ecpabe.Ops.G1Mul(g, invH2)
 
 		// UP{i,u,2} = H1(u)^{1 / h2}
-		Hu := H1(u)
-		up.UP2[u] = new(bn256.G1).ScalarMult(Hu, invH2)
+		Hu := ecpabe.Ops.HashG1(H1, u)
+		up.UP2[u] = ecpabe.Ops.G1Mul(Hu, invH2)
 	}
 	//3. Compute TKi
 	tk = &TKi{
@@ -114,12 +122,12 @@ func (ecpabe *ECPABE) KeyGen(U []string, MK *MK, Si []string) (EncKey, DecKey *b
 	ri, _ := sampler.Sample()
 	ri.Mod(ri, ecpabe.P)
 	//Compute Di = (Galpha * g^{ri})^{1/(β zi)}
-	gRi := new(bn256.G1).ScalarMult(g, ri)
+	gRi := ecpabe.Ops.G1Mul(g, ri)
 	num := new(bn256.G1).Add(MK.Galpha, gRi) // num = g^{α + ri}
 	den := new(big.Int).Mul(MK.beta, zi)     // β * z_i
 	den.Mod(den, ecpabe.P)
 	invDen := new(big.Int).ModInverse(den, ecpabe.P) //1/(β zi)
-	tk.D = new(bn256.G1).ScalarMult(num, invDen)
+	tk.D = ecpabe.Ops.G1Mul(num, invDen)
 
 	invZi := new(big.Int).ModInverse(zi, ecpabe.P) //1/zi
 	for _, j := range Si {
@@ -135,17 +143,17 @@ func (ecpabe *ECPABE) KeyGen(U []string, MK *MK, Si []string) (EncKey, DecKey *b
 		RijOverZi.Mod(RijOverZi, ecpabe.P)
 
 		// g^{ri / zi}
-		gRiOverZi := new(bn256.G2).ScalarMult(g2, RiOverZi)
+		gRiOverZi := ecpabe.Ops.G2Mul(g2, RiOverZi)
 
 		// H1(j)^{r{i,j} / zi}
-		Hj := H1toG2(j)
-		HjRijOverZi := new(bn256.G2).ScalarMult(Hj, RijOverZi)
+		Hj := ecpabe.Ops.HashG2(H1toG2, j)
+		HjRijOverZi := ecpabe.Ops.G2Mul(Hj, RijOverZi)
 
 		// D{i,j} = g^{ri / zi} * H1(j)^{r{i,j} / zi}
 		Dij := new(bn256.G2).Add(gRiOverZi, HjRijOverZi)
 
 		// D'{i,j} = g^{r{i,j} / zi}
-		Dpij := new(bn256.G2).ScalarMult(g2, RijOverZi)
+		Dpij := ecpabe.Ops.G2Mul(g2, RijOverZi)
 
 		tk.Dj[j] = Dij
 		tk.Djp[j] = Dpij
@@ -159,67 +167,37 @@ type PreCT struct {
 	C    *bn256.GT
 	Com  *bn256.G2        // h^s
 	Cpre map[int]*big.Int //Ci^pre = H2(ρ(i)||sB) * λi
+	//Mes 的承诺，仅 EncryptPolicyVerifiable 设置，见 verify.go
+	Tag []byte
 }
 
 // Generate an access structure
 func GeneratePolicy(attrCount int) string {
-
-	attrs := make([]string, attrCount)
-	for i := 0; i < attrCount; i++ {
-		attrs[i] = "Attr" + strconv.Itoa(i+1)
-	}
-
-	randInt := func(n int) int {
-		r, _ := rand.Int(rand.Reader, big.NewInt(int64(n)))
-		return int(r.Int64())
-	}
-
-	for i := attrCount - 1; i > 0; i-- {
-		j := randInt(i + 1)
-		attrs[i], attrs[j] = attrs[j], attrs[i]
-	}
-
-	var build func([]string) string
-	build = func(list []string) string {
-
-		if len(list) == 1 {
-			return list[0]
-		}
-
-		op := "AND"
-		if randInt(2) == 0 {
-			op = "OR"
-		}
-
-		split := randInt(len(list)-1) + 1 // [1, len-1]
-		left := build(list[:split])
-		right := build(list[split:])
-
-		return "(" + left + " " + op + " " + right + ")"
-	}
-
-	policy := build(attrs)
-
-	if len(policy) > 2 && policy[0] == '(' && policy[len(policy)-1] == ')' {
-		policy = policy[1 : len(policy)-1]
-	}
-
-	return policy
+	return policygen.GeneratePolicy(attrCount)
 }
 
 func (ecpabe *ECPABE) Encrypt(pk *PK, EKb *big.Int, attrNum int) (*PreCT, error) {
+	return ecpabe.EncryptPolicy(pk, EKb, GeneratePolicy(attrNum))
+}
+
+// EncryptPolicy 与 Encrypt 相同，但使用给定的策略字符串（支持门限门，如 policygen 生成的策略）
+func (ecpabe *ECPABE) EncryptPolicy(pk *PK, EKb *big.Int, policyStr string) (*PreCT, error) {
 	sampler := sample.NewUniform(ecpabe.P)
 	_, keyGt, err := bn256.RandomGT(rand.Reader)
-	policy := GeneratePolicy(attrNum)
-	//policy := "Attr2 OR (Attr1 AND Attr3)"
-	msp, _ := abe.BooleanToMSP(policy, false) //根据访问控制策略构建msp矩阵
+	if err != nil {
+		return nil, err
+	}
+	msp, _, err := policy.Canonicalize(policyStr) //根据访问控制策略构建msp矩阵
+	if err != nil {
+		return nil, err
+	}
 	// s ∈ Zp
 	s, _ := sampler.Sample()
 
-	C := new(bn256.GT).Add(keyGt, new(bn256.GT).ScalarMult(pk.Base, s))
+	C := new(bn256.GT).Add(keyGt, ecpabe.Ops.GTExp(pk.Base, s))
 
 	//Compute C = h^s
-	Com := new(bn256.G2).ScalarMult(pk.HG2, s)
+	Com := ecpabe.Ops.G2Mul(pk.HG2, s)
 
 	//LSSS.Share -> λi = Mi · v，v[0] = s
 	lambdaMap, err := LSSS.Share(msp, s, ecpabe.P)
@@ -261,6 +239,8 @@ type CipherText struct {
 	Com *bn256.G2         // C = h^s
 	C1  map[int]*bn256.G1 //Ci  = g^{λi}
 	C2  map[int]*bn256.G1 //Ci' = H1(ρ(i))^{λi}
+	//封装密钥的承诺，由 VerifyOutDecrypt 检查；为空表示密文不支持验证
+	Tag []byte
 }
 
 func (ecpabe *ECPABE) OutEncrypt(pk *PK, upB *UPi, preCT *PreCT) (*CipherText, error) {
@@ -281,8 +261,8 @@ func (ecpabe *ECPABE) OutEncrypt(pk *PK, upB *UPi, preCT *PreCT) (*CipherText, e
 		//Compute：
 		//Ci  = UP{B,attr,1}^{Ci^pre} = g^{λi}
 		//Ci' = UP{B,attr,2}^{Ci^pre} = H1(attr)^{λi}
-		C1[i] = new(bn256.G1).ScalarMult(up1, cpre)
-		C2[i] = new(bn256.G1).ScalarMult(up2, cpre)
+		C1[i] = ecpabe.Ops.G1Mul(up1, cpre)
+		C2[i] = ecpabe.Ops.G1Mul(up2, cpre)
 	}
 
 	ct := &CipherText{
@@ -291,6 +271,7 @@ func (ecpabe *ECPABE) OutEncrypt(pk *PK, upB *UPi, preCT *PreCT) (*CipherText, e
 		Com: preCT.Com,
 		C1:  C1,
 		C2:  C2,
+		Tag: preCT.Tag,
 	}
 	return ct, nil
 }
@@ -333,10 +314,10 @@ func (ecpabe *ECPABE) OutDecrypt(pk *PK, ct *CipherText, tkA *TKi) (*bn256.GT, e
 		}
 
 		// num = e(Ci, D{A,i})
-		num := bn256.Pair(Ci, Dij)
+		num := ecpabe.Ops.Pair(Ci, Dij)
 
 		// den = e(C'i, D'{A,i})
-		den := bn256.Pair(CiPrime, Dpij)
+		den := ecpabe.Ops.Pair(CiPrime, Dpij)
 
 		// den^{-1} = den^{p-1}
 		//invDen := new(bn256.GT).ScalarMult(den, negOne)
@@ -356,12 +337,13 @@ func (ecpabe *ECPABE) OutDecrypt(pk *PK, ct *CipherText, tkA *TKi) (*bn256.GT, e
 	if err != nil {
 		return nil, fmt.Errorf("OutDecrypt: LSSS.Recon failed: %w", err)
 	}
+	ecpabe.Ops.Add(opcount.GTExp, len(shares))
 
 	//Compute e(C, DA) / A
-	eCD := bn256.Pair(tkA.D, ct.Com)
+	eCD := ecpabe.Ops.Pair(tkA.D, ct.Com)
 
 	// A^{-1} = A^{p-1}
-	invA := new(bn256.GT).ScalarMult(A, negOne)
+	invA := ecpabe.Ops.GTExp(A, negOne)
 
 	// transCT = e(C, D_A) * A^{-1} = e(g,g)^{α·s/z_A}
 	transCT := new(bn256.GT).Add(eCD, invA)
@@ -378,7 +360,7 @@ func (ecpabe *ECPABE) Decrypt(ct *CipherText, transCT *bn256.GT, DKA *big.Int) (
 	exp := new(big.Int).Mod(DKA, ecpabe.P)
 
 	// Key = transCT^{DKA} = e(g,g)^{α·s}
-	Key := new(bn256.GT).ScalarMult(transCT, exp)
+	Key := ecpabe.Ops.GTExp(transCT, exp)
 	keyGT := new(bn256.GT).Add(ct.C, new(bn256.GT).Neg(Key))
 
 	return keyGT, nil
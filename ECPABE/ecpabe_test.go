@@ -2,6 +2,8 @@ package ecpabe
 
 import (
 	"bytes"
+	"crypto/rand"
+	"errors"
 	"fmt"
 	"math/big"
 	"strconv"
@@ -114,3 +116,96 @@ func TestECPABE_FullFlow_SatisfiedPolicy(t *testing.T) {
 			preCT.Mes.Marshal(), keyDec.Marshal())
 	}
 }
+
+// 测试：带密钥承诺的密文可以发现代理服务器返回的错误 transCT
+func TestECPABE_VerifyOutDecrypt(t *testing.T) {
+	e := NewECPABE()
+	mk, pk := e.Setup()
+	U := []string{"Attr1", "Attr2", "Attr3"}
+	EKb, _, UPb, _, err := e.KeyGen(U, mk, U)
+	if err != nil {
+		t.Fatalf("KeyGen for Bob failed: %v", err)
+	}
+	_, DKa, _, TKa, err := e.KeyGen(U, mk, []string{"Attr1", "Attr2"})
+	if err != nil {
+		t.Fatalf("KeyGen for Alice failed: %v", err)
+	}
+
+	preCT, err := e.EncryptPolicyVerifiable(pk, EKb, "Attr1 AND (Attr2 OR Attr3)")
+	if err != nil {
+		t.Fatalf("EncryptPolicyVerifiable failed: %v", err)
+	}
+	ct, err := e.OutEncrypt(pk, UPb, preCT)
+	if err != nil {
+		t.Fatalf("OutEncrypt failed: %v", err)
+	}
+	if len(ct.Tag) == 0 {
+		t.Fatalf("OutEncrypt dropped the key commitment")
+	}
+	transCT, err := e.OutDecrypt(pk, ct, TKa)
+	if err != nil {
+		t.Fatalf("OutDecrypt failed: %v", err)
+	}
+	if !e.VerifyOutDecrypt(ct, transCT, DKa) {
+		t.Fatalf("honest transCT rejected")
+	}
+	key, err := e.DecryptVerified(ct, transCT, DKa)
+	if err != nil {
+		t.Fatalf("DecryptVerified failed: %v", err)
+	}
+	if !bytes.Equal(preCT.Mes.Marshal(), key.Marshal()) {
+		t.Fatalf("decryption key mismatch")
+	}
+
+	// 代理服务器返回随机 GT 元素
+	for i := 0; i < 5; i++ {
+		_, random, err := bn256.RandomGT(rand.Reader)
+		if err != nil {
+			t.Fatalf("RandomGT failed: %v", err)
+		}
+		if e.VerifyOutDecrypt(ct, random, DKa) {
+			t.Fatalf("random transCT accepted")
+		}
+		if _, err := e.DecryptVerified(ct, random, DKa); !errors.Is(err, ErrOutDecryptFailed) {
+			t.Fatalf("DecryptVerified: got %v, want ErrOutDecryptFailed", err)
+		}
+		// 不验证时 Decrypt 照样返回一个错误的 key
+		wrong, err := e.Decrypt(ct, random, DKa)
+		if err != nil || bytes.Equal(wrong.Marshal(), key.Marshal()) {
+			t.Fatalf("unverified Decrypt should silently return a different key")
+		}
+	}
+
+	// 用错误的 DKA 或另一个密文的 transCT 同样被拒绝
+	if e.VerifyOutDecrypt(ct, transCT, new(big.Int).Add(DKa, big.NewInt(1))) {
+		t.Fatalf("wrong DKA accepted")
+	}
+	other, err := e.EncryptPolicyVerifiable(pk, EKb, "Attr1 AND Attr2")
+	if err != nil {
+		t.Fatalf("EncryptPolicyVerifiable failed: %v", err)
+	}
+	otherCT, err := e.OutEncrypt(pk, UPb, other)
+	if err != nil {
+		t.Fatalf("OutEncrypt failed: %v", err)
+	}
+	otherTrans, err := e.OutDecrypt(pk, otherCT, TKa)
+	if err != nil {
+		t.Fatalf("OutDecrypt failed: %v", err)
+	}
+	if e.VerifyOutDecrypt(ct, otherTrans, DKa) {
+		t.Fatalf("transCT of another ciphertext accepted")
+	}
+
+	// 未使用扩展的密文无法验证
+	plain, err := e.EncryptPolicy(pk, EKb, "Attr1 AND Attr2")
+	if err != nil {
+		t.Fatalf("EncryptPolicy failed: %v", err)
+	}
+	plainCT, err := e.OutEncrypt(pk, UPb, plain)
+	if err != nil {
+		t.Fatalf("OutEncrypt failed: %v", err)
+	}
+	if e.VerifyOutDecrypt(plainCT, transCT, DKa) {
+		t.Fatalf("ciphertext without a commitment cannot be verified")
+	}
+}
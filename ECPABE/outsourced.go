@@ -0,0 +1,164 @@
+package ecpabe
+
+import (
+	"errors"
+
+	"github.com/AUKUS561/PVOABE/oabe"
+	"github.com/fentec-project/bn256"
+)
+
+// Name 是 ECPABE 在 oabe 接口中的方案名
+const Name = "ECPABE"
+
+// VerifiableName 是带密钥承诺的 ECPABE（见 verify.go）在 oabe 接口中的方案名
+const VerifiableName = "ECPABE-V"
+
+// Outsourced 把 ECPABE 适配为 oabe.OutsourcedABE：
+// Encrypt/OutEncrypt/OutDecrypt/Decrypt 依次对应 Encrypt/ProxyEncrypt/ProxyDecrypt/Decrypt。
+// Verifiable 为 true 时加密使用 EncryptPolicyVerifiable，并以 VerifyOutDecrypt 支持 VerifyDecryption
+type Outsourced struct {
+	*ECPABE
+	Verifiable bool
+}
+
+func NewOutsourced() *Outsourced {
+	return &Outsourced{ECPABE: NewECPABE()}
+}
+
+// NewOutsourcedVerifiable 返回支持外包解密验证的适配器
+func NewOutsourcedVerifiable() *Outsourced {
+	return &Outsourced{ECPABE: NewECPABE(), Verifiable: true}
+}
+
+// master 是 MK 加上 KeyGen 需要的属性全集 U
+type master struct {
+	MK *MK
+	U  []string
+}
+
+// proxyKey 交给代理服务器：UPi 用于外包加密，TKi 用于外包解密
+type proxyKey struct {
+	UP *UPi
+	TK *TKi
+}
+
+// userKey 由用户自己保存：EKi 用于加密，DKi 用于解密
+type userKey struct {
+	EK EncKey
+	DK DecKey
+}
+
+// outsourcedCT 在 OutEncrypt 之前保存 preCT，之后保存完整密文
+type outsourcedCT struct {
+	Pre *PreCT
+	CT  *CipherText
+}
+
+// Final 返回 OutEncrypt 之后的完整密文
+func (v *outsourcedCT) Final() any { return v.CT }
+
+func (o *Outsourced) Name() string {
+	if o.Verifiable {
+		return VerifiableName
+	}
+	return Name
+}
+
+func (o *Outsourced) Supports() oabe.Support {
+	return oabe.Support{ProxyEncrypt: true, VerifyDecryption: o.Verifiable}
+}
+
+func (o *Outsourced) Setup(universe []string) (*oabe.Authority, error) {
+	mk, pk := o.ECPABE.Setup()
+	return &oabe.Authority{Scheme: o.Name(), Universe: universe, Public: pk, Master: &master{MK: mk, U: universe}}, nil
+}
+
+func (o *Outsourced) KeyGen(auth *oabe.Authority, id string, attrs []string) (*oabe.UserKey, error) {
+	if err := oabe.Check(o.Name(), auth.Scheme); err != nil {
+		return nil, err
+	}
+	m := auth.Master.(*master)
+	ek, dk, up, tk, err := o.ECPABE.KeyGen(m.U, m.MK, attrs)
+	if err != nil {
+		return nil, err
+	}
+	return &oabe.UserKey{
+		Scheme: o.Name(),
+		ID:     id,
+		Attrs:  attrs,
+		Proxy:  &proxyKey{UP: up, TK: tk},
+		User:   &userKey{EK: ek, DK: dk},
+	}, nil
+}
+
+func (o *Outsourced) Encrypt(auth *oabe.Authority, owner *oabe.UserKey, policy string) (*oabe.Ciphertext, *bn256.GT, error) {
+	if err := oabe.Check(o.Name(), owner.Scheme); err != nil {
+		return nil, nil, err
+	}
+	encrypt := o.EncryptPolicy
+	if o.Verifiable {
+		encrypt = o.EncryptPolicyVerifiable
+	}
+	pre, err := encrypt(auth.Public.(*PK), owner.User.(*userKey).EK, policy)
+	if err != nil {
+		return nil, nil, err
+	}
+	key := pre.Mes
+	pre.Mes = nil // 封装的密钥只返回给 DO，不随 preCT 交给代理服务器
+	return &oabe.Ciphertext{Scheme: o.Name(), Policy: policy, Value: &outsourcedCT{Pre: pre}}, key, nil
+}
+
+func (o *Outsourced) ProxyEncrypt(auth *oabe.Authority, owner *oabe.UserKey, ct *oabe.Ciphertext) error {
+	if err := oabe.Check(o.Name(), ct.Scheme); err != nil {
+		return err
+	}
+	v := ct.Value.(*outsourcedCT)
+	full, err := o.OutEncrypt(auth.Public.(*PK), owner.Proxy.(*proxyKey).UP, v.Pre)
+	if err != nil {
+		return err
+	}
+	v.CT, v.Pre = full, nil
+	return nil
+}
+
+func (o *Outsourced) VerifyEncryption(auth *oabe.Authority, owner *oabe.UserKey, ct *oabe.Ciphertext) error {
+	return oabe.ErrNotSupported
+}
+
+func (o *Outsourced) ProxyDecrypt(auth *oabe.Authority, user *oabe.UserKey, ct *oabe.Ciphertext) (*oabe.Transformed, error) {
+	if err := oabe.Check(o.Name(), ct.Scheme); err != nil {
+		return nil, err
+	}
+	v := ct.Value.(*outsourcedCT)
+	if v.CT == nil {
+		return nil, errors.New("ECPABE: ciphertext has not been processed by OutEncrypt")
+	}
+	transCT, err := o.OutDecrypt(auth.Public.(*PK), v.CT, user.Proxy.(*proxyKey).TK)
+	if err != nil {
+		return nil, err
+	}
+	return &oabe.Transformed{Scheme: o.Name(), Value: transCT}, nil
+}
+
+func (o *Outsourced) VerifyDecryption(auth *oabe.Authority, user *oabe.UserKey, ct *oabe.Ciphertext, tr *oabe.Transformed) error {
+	if !o.Verifiable {
+		return oabe.ErrNotSupported
+	}
+	if err := oabe.Check(o.Name(), tr.Scheme); err != nil {
+		return err
+	}
+	if !o.VerifyOutDecrypt(ct.Value.(*outsourcedCT).CT, tr.Value.(*bn256.GT), user.User.(*userKey).DK) {
+		return oabe.ErrVerificationFailed
+	}
+	return nil
+}
+
+func (o *Outsourced) Decrypt(auth *oabe.Authority, user *oabe.UserKey, ct *oabe.Ciphertext, tr *oabe.Transformed) (*bn256.GT, error) {
+	if err := oabe.Check(o.Name(), tr.Scheme); err != nil {
+		return nil, err
+	}
+	if o.Verifiable {
+		return o.DecryptVerified(ct.Value.(*outsourcedCT).CT, tr.Value.(*bn256.GT), user.User.(*userKey).DK)
+	}
+	return o.ECPABE.Decrypt(ct.Value.(*outsourcedCT).CT, tr.Value.(*bn256.GT), user.User.(*userKey).DK)
+}
@@ -0,0 +1,23 @@
+package ecpabe
+
+import "github.com/AUKUS561/PVOABE/size"
+
+// Size 列出密文 C、Com 与每行的 Ci、Ci'
+func (ct *CipherText) Size() size.Report {
+	return size.Breakdown("ECPABE.CipherText", ct)
+}
+
+// EncodedLen 返回密文在编码 enc 下的字节数
+func (ct *CipherText) EncodedLen(enc size.Encoding) int {
+	return ct.Size().Len(enc)
+}
+
+// Size 列出转换密钥 TK 的 D、{Dj}、{D'j}，属性集合按属性名的字节数计
+func (tk *TKi) Size() size.Report {
+	return size.Breakdown("ECPABE.TK", tk)
+}
+
+// EncodedLen 返回转换密钥在编码 enc 下的字节数
+func (tk *TKi) EncodedLen(enc size.Encoding) int {
+	return tk.Size().Len(enc)
+}
@@ -0,0 +1,59 @@
+package ecpabe
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"math/big"
+
+	"github.com/fentec-project/bn256"
+)
+
+// 可验证的外包解密（可选扩展）
+//
+// OutDecrypt 返回的 transCT 被用户直接求 DKA 次幂，代理服务器返回任意 GT 元素都不会被发现。
+// EncryptPolicyVerifiable 在密文中加入封装密钥的承诺 Tag = H("ECPABE/key-commit", key)，
+// 用户用 transCT^{DKA} 恢复 key 后重新计算 Tag 比较。key 是随机 GT 元素，Tag 不泄露 key；
+// 不满足承诺的 transCT 需要找到 H 的原像，因此代理服务器无法伪造。
+// 未使用该扩展的密文 Tag 为空，VerifyOutDecrypt 返回 false
+
+var ErrOutDecryptFailed = errors.New("ECPABE: outsourced decryption does not match the key commitment")
+
+// EncryptPolicyVerifiable 与 EncryptPolicy 相同，另在 preCT 中加入 Mes 的承诺，OutEncrypt 会把它带入密文
+func (ecpabe *ECPABE) EncryptPolicyVerifiable(pk *PK, EKb *big.Int, policyStr string) (*PreCT, error) {
+	preCT, err := ecpabe.EncryptPolicy(pk, EKb, policyStr)
+	if err != nil {
+		return nil, err
+	}
+	preCT.Tag = keyCommit(preCT.Mes)
+	return preCT, nil
+}
+
+// VerifyOutDecrypt 检查 transCT 是否为 ct 在 DKA 下正确的外包解密结果
+func (ecpabe *ECPABE) VerifyOutDecrypt(ct *CipherText, transCT *bn256.GT, DKA *big.Int) bool {
+	_, err := ecpabe.DecryptVerified(ct, transCT, DKA)
+	return err == nil
+}
+
+// DecryptVerified 与 Decrypt 相同，但检查恢复的 key 与 ct.Tag 一致，不一致时返回 ErrOutDecryptFailed
+func (ecpabe *ECPABE) DecryptVerified(ct *CipherText, transCT *bn256.GT, DKA *big.Int) (*bn256.GT, error) {
+	if ct == nil || len(ct.Tag) == 0 {
+		return nil, errors.New("DecryptVerified: ciphertext carries no key commitment")
+	}
+	key, err := ecpabe.Decrypt(ct, transCT, DKA)
+	if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal(ct.Tag, keyCommit(key)) {
+		return nil, ErrOutDecryptFailed
+	}
+	return key, nil
+}
+
+// keyCommit 计算封装密钥的承诺
+func keyCommit(key *bn256.GT) []byte {
+	h := sha256.New()
+	h.Write([]byte("ECPABE/key-commit"))
+	h.Write(key.Marshal())
+	return h.Sum(nil)
+}
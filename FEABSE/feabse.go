@@ -1,20 +1,27 @@
 package feabse
 
 import (
-	"crypto/rand"
 	"crypto/sha256"
 	"log"
 	"math/big"
-	"strconv"
 
 	"github.com/AUKUS561/PVOABE/LSSS"
+	"github.com/AUKUS561/PVOABE/opcount"
+	"github.com/AUKUS561/PVOABE/policy"
+	"github.com/AUKUS561/PVOABE/policygen"
 	"github.com/fentec-project/bn256"
 	"github.com/fentec-project/gofe/abe"
 	"github.com/fentec-project/gofe/sample"
 )
 
 type FEABSE struct {
-	P *big.Int
+	P   *big.Int
+	Ops *opcount.Counter //运算计数，nil 表示不计数
+}
+
+// SetCounter 设置运算计数器，c 为 nil 时关闭计数
+func (feabse *FEABSE) SetCounter(c *opcount.Counter) {
+	feabse.Ops = c
 }
 
 func NewFEABSE() *FEABSE {
@@ -25,48 +32,7 @@ func NewFEABSE() *FEABSE {
 
 // Generate an access structure
 func GeneratePolicy(attrCount int) string {
-
-	attrs := make([]string, attrCount)
-	for i := 0; i < attrCount; i++ {
-		attrs[i] = "Attr" + strconv.Itoa(i+1)
-	}
-
-	randInt := func(n int) int {
-		r, _ := rand.Int(rand.Reader, big.NewInt(int64(n)))
-		return int(r.Int64())
-	}
-
-	for i := attrCount - 1; i > 0; i-- {
-		j := randInt(i + 1)
-		attrs[i], attrs[j] = attrs[j], attrs[i]
-	}
-
-	var build func([]string) string
-	build = func(list []string) string {
-
-		if len(list) == 1 {
-			return list[0]
-		}
-
-		op := "AND"
-		if randInt(2) == 0 {
-			op = "OR"
-		}
-
-		split := randInt(len(list)-1) + 1 // [1, len-1]
-		left := build(list[:split])
-		right := build(list[split:])
-
-		return "(" + left + " " + op + " " + right + ")"
-	}
-
-	policy := build(attrs)
-
-	if len(policy) > 2 && policy[0] == '(' && policy[len(policy)-1] == ')' {
-		policy = policy[1 : len(policy)-1]
-	}
-
-	return policy
+	return policygen.GeneratePolicy(attrCount)
 }
 
 // MPK = (PP, g^β, g^γ, e(g,g)^α, {PAK_x})
@@ -104,17 +70,17 @@ func (feabse *FEABSE) Setup(U []string) (*MPK, *MSK) {
 	g2 := new(bn256.G2).ScalarBaseMult(big.NewInt(1)) // g2 ∈ G2
 
 	// e(g, g2)
-	egg := bn256.Pair(g, g2)
+	egg := feabse.Ops.Pair(g, g2)
 
 	// e(g, g2)^α
-	eggAlpha := new(bn256.GT).ScalarMult(egg, alpha)
+	eggAlpha := feabse.Ops.GTExp(egg, alpha)
 
 	// g^β, g^γ
-	gBeta := new(bn256.G1).ScalarMult(g, beta)
-	gGamma := new(bn256.G1).ScalarMult(g, gamma)
+	gBeta := feabse.Ops.G1Mul(g, beta)
+	gGamma := feabse.Ops.G1Mul(g, gamma)
 
 	// g2^β
-	g2Beta := new(bn256.G2).ScalarMult(g2, beta)
+	g2Beta := feabse.Ops.G2Mul(g2, beta)
 
 	//For each attr x ∈ U compute ηx 和 PAKx = g^{ηx}
 	pak := make(map[string]*bn256.G1)
@@ -123,7 +89,7 @@ func (feabse *FEABSE) Setup(U []string) (*MPK, *MSK) {
 	for _, x := range U {
 		eta, _ := sampler.Sample() // ηx ← Zp
 		etaMap[x] = new(big.Int).Set(eta)
-		pak[x] = new(bn256.G1).ScalarMult(g, eta) // PAKx = g^{ηx}
+		pak[x] = feabse.Ops.G1Mul(g, eta) // PAKx = g^{ηx}
 	}
 
 	mpk := &MPK{
@@ -168,21 +134,21 @@ func (feabse *FEABSE) KeyGen(mpk *MPK, msk *MSK, SID []string) *SKdu {
 	y, _ := sampler.Sample()
 
 	// D1 = g2^α · g2^{γt}
-	D1alpha := new(bn256.G1).ScalarMult(mpk.G, msk.Alpha)
-	g2Gamma := new(bn256.G1).ScalarMult(mpk.G, msk.Gamma)
-	g2GammaT := new(bn256.G1).ScalarMult(g2Gamma, t)
+	D1alpha := feabse.Ops.G1Mul(mpk.G, msk.Alpha)
+	g2Gamma := feabse.Ops.G1Mul(mpk.G, msk.Gamma)
+	g2GammaT := feabse.Ops.G1Mul(g2Gamma, t)
 	D1 := new(bn256.G1).Add(D1alpha, g2GammaT)
 
 	// D2 = g2^t
-	D2 := new(bn256.G2).ScalarMult(mpk.G2, t)
+	D2 := feabse.Ops.G2Mul(mpk.G2, t)
 
 	// D3 = g2^{y β}
 	yBeta := new(big.Int).Mul(y, msk.Beta)
 	yBeta.Mod(yBeta, feabse.P)
-	D3 := new(bn256.G1).ScalarMult(mpk.G, yBeta)
+	D3 := feabse.Ops.G1Mul(mpk.G, yBeta)
 
 	// D4 = g2^y
-	D4 := new(bn256.G1).ScalarMult(mpk.G, y)
+	D4 := feabse.Ops.G1Mul(mpk.G, y)
 
 	// D5 = β / y = β · y^{-1} mod p
 	yInv := new(big.Int).ModInverse(y, feabse.P)
@@ -199,7 +165,7 @@ func (feabse *FEABSE) KeyGen(mpk *MPK, msk *MSK, SID []string) *SKdu {
 		}
 
 		// H0G2(x) ∈ G2，to compute Dx
-		hxG2 := HashToG2(x)
+		hxG2 := feabse.Ops.HashG2(HashToG2, x)
 
 		// exponent = t / ηx = t · ηx^{-1} mod p
 		etaInv := new(big.Int).ModInverse(etaX, feabse.P)
@@ -207,12 +173,12 @@ func (feabse *FEABSE) KeyGen(mpk *MPK, msk *MSK, SID []string) *SKdu {
 		expShare.Mod(expShare, feabse.P)
 
 		// Dx = H0_G2(x)^{t/ηx}
-		Dx[x] = new(bn256.G2).ScalarMult(hxG2, expShare)
+		Dx[x] = feabse.Ops.G2Mul(hxG2, expShare)
 
 		// H0_G1(x) ∈ G1，to compute Tx
-		hxG1 := HashToG1(x)
+		hxG1 := feabse.Ops.HashG1(HashToG1, x)
 		// Tx = H0_G1(x)^β
-		Tx[x] = new(bn256.G1).ScalarMult(hxG1, msk.Beta)
+		Tx[x] = feabse.Ops.G1Mul(hxG1, msk.Beta)
 	}
 
 	sk := &SKdu{
@@ -245,10 +211,10 @@ func (feabse *FEABSE) OfflineEnc(mpk *MPK) *IC {
 	s, _ := sampler.Sample()
 
 	// IC0 = e(g,g)^{αs} = (EGGAlpha)^s
-	IC0 := new(bn256.GT).ScalarMult(mpk.EGGAlpha, s)
+	IC0 := feabse.Ops.GTExp(mpk.EGGAlpha, s)
 
 	// IC1 = g^s
-	IC1 := new(bn256.G2).ScalarMult(mpk.G2, s)
+	IC1 := feabse.Ops.G2Mul(mpk.G2, s)
 
 	// For each attr x ∈ U compute (ICi}, ICi2)
 	ICAttr1 := make(map[string]*bn256.G1, len(mpk.PAK))
@@ -259,15 +225,15 @@ func (feabse *FEABSE) OfflineEnc(mpk *MPK) *IC {
 		gamma, _ := sampler.Sample()
 
 		// H0(x) ∈ G1
-		hx := HashToG1(x)
+		hx := feabse.Ops.HashG1(HashToG1, x)
 
 		// ICi = H0(x)^{-γx} = H0(x)^{p - γx}
 		negGamma := new(big.Int).Sub(feabse.P, gamma)
 		negGamma.Mod(negGamma, feabse.P)
-		ICAttr1[x] = new(bn256.G1).ScalarMult(hx, negGamma)
+		ICAttr1[x] = feabse.Ops.G1Mul(hx, negGamma)
 
 		// ICi2 = PAKx^{γx}
-		ICAttr2[x] = new(bn256.G1).ScalarMult(pakx, gamma)
+		ICAttr2[x] = feabse.Ops.G1Mul(pakx, gamma)
 	}
 
 	return &IC{
@@ -296,15 +262,23 @@ type CT struct {
 }
 
 func (feabse *FEABSE) OnlineEnc(mpk *MPK, ic *IC, Ktheta *bn256.GT, attrNum int) (*CT, error) {
+	return feabse.OnlineEncPolicy(mpk, ic, Ktheta, GeneratePolicy(attrNum))
+}
+
+// OnlineEncPolicy 与 OnlineEnc 相同，但使用给定的策略字符串
+func (feabse *FEABSE) OnlineEncPolicy(mpk *MPK, ic *IC, Ktheta *bn256.GT, policyStr string) (*CT, error) {
 	// 3. 构造访问策略并转成 MSP
-	policy := GeneratePolicy(attrNum)
-	msp, err := abe.BooleanToMSP(policy, false)
+	msp, _, err := policy.Canonicalize(policyStr)
+	if err != nil {
+		return nil, err
+	}
 
-	//用 LSSS.Share 计算每一行的 share λi (ξi)
-	lambdaMap, err := LSSS.Share(msp, ic.S, feabse.P)
+	//在指数上执行 LSSS.Share：每一行 g^{γ λi} = (g^γ)^{λi}
+	gGammaLambdas, err := LSSS.ShareInExponent(msp, mpk.GGamma, ic.S, feabse.P)
 	if err != nil {
 		return nil, err
 	}
+	feabse.Ops.Add(opcount.G1Mul, len(gGammaLambdas))
 	//Compute C0, C1
 	// C0 = Kθ · IC0
 	var C0 *bn256.GT
@@ -325,8 +299,6 @@ func (feabse *FEABSE) OnlineEnc(mpk *MPK, ic *IC, Ktheta *bn256.GT, attrNum int)
 	C2i := make(map[int]*bn256.G1, numRows)
 
 	for i := 0; i < numRows; i++ {
-		lambdaI := lambdaMap[i]
-
 		attr := msp.RowToAttrib[i]
 
 		ic1x, ok1 := ic.ICAttr1[attr]
@@ -335,11 +307,8 @@ func (feabse *FEABSE) OnlineEnc(mpk *MPK, ic *IC, Ktheta *bn256.GT, attrNum int)
 			log.Fatalf("IC for attribute %s not found", attr)
 		}
 
-		// g^{γ λi} = (g^γ)^{λi}
-		gGammaLambda := new(bn256.G1).ScalarMult(mpk.GGamma, lambdaI)
-
 		// Ci^{<1>} = g^{γ λi} · ICx^{<1>}
-		C1i[i] = new(bn256.G1).Add(gGammaLambda, ic1x)
+		C1i[i] = new(bn256.G1).Add(gGammaLambdas[i], ic1x)
 
 		// Ci^{<2>} = ICx^{<2>}
 		C2i[i] = new(bn256.G1).Set(ic2x)
@@ -351,8 +320,8 @@ func (feabse *FEABSE) OnlineEnc(mpk *MPK, ic *IC, Ktheta *bn256.GT, attrNum int)
 		if _, exists := CTx[attr]; exists {
 			continue
 		}
-		hx := HashToG1(attr)
-		CTx[attr] = bn256.Pair(hx, mpk.G2Beta)
+		hx := feabse.Ops.HashG1(HashToG1, attr)
+		CTx[attr] = feabse.Ops.Pair(hx, mpk.G2Beta)
 	}
 
 	ct := &CT{
@@ -381,7 +350,7 @@ func (feabse *FEABSE) TKGen(mpk *MPK, sk *SKdu) *TK {
 
 	// u ∈ Zp，Du = g2^u
 	u, _ := sampler.Sample()
-	Du := new(bn256.G1).ScalarMult(mpk.G, u)
+	Du := feabse.Ops.G1Mul(mpk.G, u)
 
 	// D5 inv: (1 / D5) = D5^{-1} mod p = (y/β)
 	invD5 := new(big.Int).ModInverse(sk.D5, feabse.P)
@@ -390,18 +359,18 @@ func (feabse *FEABSE) TKGen(mpk *MPK, sk *SKdu) *TK {
 	}
 
 	// D1^{1/D5} = D1^{invD5} ∈ G2
-	D1Pow := new(bn256.G1).ScalarMult(sk.D1, invD5)
+	D1Pow := feabse.Ops.G1Mul(sk.D1, invD5)
 
 	// D6 = D1^{1/D5} · Du
 	D6 := new(bn256.G1).Add(D1Pow, Du)
 
 	// D2' = D2^{1/D5}
-	D2Prime := new(bn256.G2).ScalarMult(sk.D2, invD5)
+	D2Prime := feabse.Ops.G2Mul(sk.D2, invD5)
 
 	//For each attr x: Dx' = Dx^{1/D5}
 	DxPrime := make(map[string]*bn256.G2, len(sk.Dx))
 	for x, Dx := range sk.Dx {
-		DxPrime[x] = new(bn256.G2).ScalarMult(Dx, invD5)
+		DxPrime[x] = feabse.Ops.G2Mul(Dx, invD5)
 	}
 
 	tk := &TK{
@@ -422,12 +391,11 @@ func (feabse *FEABSE) TCTGen(mpk *MPK, ct *CT, SID []string, tk *TK) (*bn256.GT,
 	}
 
 	// numerator = e(C1, D6)
-	numerator := bn256.Pair(tk.D6, ct.C1)
-
-	// denominator 初始化为单位元 1，用 EGG^0 实现
-	denominator := new(bn256.GT).ScalarMult(mpk.EGG, big.NewInt(0))
+	numerator := feabse.Ops.Pair(tk.D6, ct.C1)
 
-	for i, omega := range omegaMap {
+	// e(Ci1, D2') e(Ci2, D'_{ρ(i)})，再由 LSSS.Combine 求 ∏ (...)^{ωi}
+	TCTi := make(map[int]*bn256.GT, len(omegaMap))
+	for i := range omegaMap {
 		attr := ct.MSP.RowToAttrib[i]
 
 		Ci1, ok1 := ct.C1i[i]
@@ -442,19 +410,18 @@ func (feabse *FEABSE) TCTGen(mpk *MPK, ct *CT, SID []string, tk *TK) (*bn256.GT,
 		}
 
 		// e(Ci1, D2')
-		e1 := bn256.Pair(Ci1, tk.D2Prime)
+		e1 := feabse.Ops.Pair(Ci1, tk.D2Prime)
 		// e(Ci2, D'_{ρ(i)})
-		e2 := bn256.Pair(Ci2, DxPrime)
-
-		tmp := new(bn256.GT).Add(e1, e2)
-		TCTi := new(bn256.GT).ScalarMult(tmp, omega)
+		e2 := feabse.Ops.Pair(Ci2, DxPrime)
 
-		denominator.Add(denominator, TCTi)
+		TCTi[i] = new(bn256.GT).Add(e1, e2)
 	}
+	denominator := LSSS.Combine(TCTi, omegaMap)
+	feabse.Ops.Add(opcount.GTExp, len(omegaMap))
 
 	// denominator^{-1} = denominator^{p-1}
 	pMinusOne := new(big.Int).Sub(feabse.P, big.NewInt(1))
-	denInv := new(bn256.GT).ScalarMult(denominator, pMinusOne)
+	denInv := feabse.Ops.GTExp(denominator, pMinusOne)
 
 	// TCT = numerator * denominator^{-1}
 	TCT := new(bn256.GT).Add(numerator, denInv)
@@ -465,17 +432,17 @@ func (feabse *FEABSE) TCTGen(mpk *MPK, ct *CT, SID []string, tk *TK) (*bn256.GT,
 // Dec(CT2, TCT, SKdu, TK) → Kθ
 func (feabse *FEABSE) Dec(ct *CT, TCT *bn256.GT, sk *SKdu, tk *TK) *bn256.GT {
 	//Compute e(C1, Du)
-	eC1Du := bn256.Pair(tk.Du, ct.C1)
+	eC1Du := feabse.Ops.Pair(tk.Du, ct.C1)
 
 	// e(C1, Du)^{D5}
-	eC1DuD5 := new(bn256.GT).ScalarMult(eC1Du, sk.D5)
+	eC1DuD5 := feabse.Ops.GTExp(eC1Du, sk.D5)
 
 	//Compute TCT^{D5}
-	TCTD5 := new(bn256.GT).ScalarMult(TCT, sk.D5)
+	TCTD5 := feabse.Ops.GTExp(TCT, sk.D5)
 
 	//Compute(TCT^{D5})^{-1}
 	pMinusOne := new(big.Int).Sub(feabse.P, big.NewInt(1))
-	TCTD5Inv := new(bn256.GT).ScalarMult(TCTD5, pMinusOne)
+	TCTD5Inv := feabse.Ops.GTExp(TCTD5, pMinusOne)
 
 	//Kθ = C0 * e(C1, Du)^{D5} * (TCT^{D5})^{-1}
 	tmp := new(bn256.GT).Add(ct.C0, eC1DuD5)
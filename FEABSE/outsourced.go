@@ -0,0 +1,102 @@
+package feabse
+
+import (
+	"math/big"
+
+	"github.com/AUKUS561/PVOABE/oabe"
+	"github.com/fentec-project/bn256"
+	"github.com/fentec-project/gofe/sample"
+)
+
+// Name 是 FEABSE 在 oabe 接口中的方案名
+const Name = "FEABSE"
+
+// Outsourced 把 FEABSE 适配为 oabe.OutsourcedABE：
+// OfflineEnc + OnlineEnc 都由 DO 完成，对应 Encrypt；TCTGen/Dec 对应 ProxyDecrypt/Decrypt，
+// 没有外包加密，也不支持验证
+type Outsourced struct {
+	*FEABSE
+}
+
+func NewOutsourced() *Outsourced {
+	return &Outsourced{FEABSE: NewFEABSE()}
+}
+
+// userKey 由用户自己保存，Dec 同时需要 SKdu 与 TK
+type userKey struct {
+	SK *SKdu
+	TK *TK
+}
+
+func (o *Outsourced) Name() string { return Name }
+
+func (o *Outsourced) Supports() oabe.Support {
+	return oabe.Support{}
+}
+
+func (o *Outsourced) Setup(universe []string) (*oabe.Authority, error) {
+	mpk, msk := o.FEABSE.Setup(universe)
+	return &oabe.Authority{Scheme: Name, Universe: universe, Public: mpk, Master: msk}, nil
+}
+
+func (o *Outsourced) KeyGen(auth *oabe.Authority, id string, attrs []string) (*oabe.UserKey, error) {
+	if err := oabe.Check(Name, auth.Scheme); err != nil {
+		return nil, err
+	}
+	mpk := auth.Public.(*MPK)
+	sk := o.FEABSE.KeyGen(mpk, auth.Master.(*MSK), attrs)
+	tk := o.TKGen(mpk, sk)
+	return &oabe.UserKey{Scheme: Name, ID: id, Attrs: attrs, Proxy: tk, User: &userKey{SK: sk, TK: tk}}, nil
+}
+
+func (o *Outsourced) Encrypt(auth *oabe.Authority, owner *oabe.UserKey, policy string) (*oabe.Ciphertext, *bn256.GT, error) {
+	if err := oabe.Check(Name, auth.Scheme); err != nil {
+		return nil, nil, err
+	}
+	mpk := auth.Public.(*MPK)
+	ic := o.OfflineEnc(mpk)
+
+	// Kθ = EGG^k
+	k, err := sample.NewUniformRange(big.NewInt(1), o.P).Sample()
+	if err != nil {
+		return nil, nil, err
+	}
+	Ktheta := new(bn256.GT).ScalarMult(mpk.EGG, k)
+
+	ct, err := o.OnlineEncPolicy(mpk, ic, Ktheta, policy)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &oabe.Ciphertext{Scheme: Name, Policy: policy, Value: ct}, Ktheta, nil
+}
+
+func (o *Outsourced) ProxyEncrypt(auth *oabe.Authority, owner *oabe.UserKey, ct *oabe.Ciphertext) error {
+	return oabe.Check(Name, ct.Scheme)
+}
+
+func (o *Outsourced) VerifyEncryption(auth *oabe.Authority, owner *oabe.UserKey, ct *oabe.Ciphertext) error {
+	return oabe.ErrNotSupported
+}
+
+func (o *Outsourced) ProxyDecrypt(auth *oabe.Authority, user *oabe.UserKey, ct *oabe.Ciphertext) (*oabe.Transformed, error) {
+	if err := oabe.Check(Name, ct.Scheme); err != nil {
+		return nil, err
+	}
+	TCT, err := o.TCTGen(auth.Public.(*MPK), ct.Value.(*CT), user.Attrs, user.Proxy.(*TK))
+	if err != nil {
+		return nil, err
+	}
+	return &oabe.Transformed{Scheme: Name, Value: TCT}, nil
+}
+
+func (o *Outsourced) VerifyDecryption(auth *oabe.Authority, user *oabe.UserKey, ct *oabe.Ciphertext, tr *oabe.Transformed) error {
+	return oabe.ErrNotSupported
+}
+
+func (o *Outsourced) Decrypt(auth *oabe.Authority, user *oabe.UserKey, ct *oabe.Ciphertext, tr *oabe.Transformed) (*bn256.GT, error) {
+	if err := oabe.Check(Name, tr.Scheme); err != nil {
+		return nil, err
+	}
+	uk := user.User.(*userKey)
+	return o.Dec(ct.Value.(*CT), tr.Value.(*bn256.GT), uk.SK, uk.TK), nil
+}
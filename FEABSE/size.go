@@ -0,0 +1,33 @@
+package feabse
+
+import "github.com/AUKUS561/PVOABE/size"
+
+// Size 列出密文 C0、C1、每行的 Ci1、Ci2 以及每个属性的 CTx
+func (ct *CT) Size() size.Report {
+	return size.Breakdown("FEABSE.CT", ct)
+}
+
+// EncodedLen 返回密文在编码 enc 下的字节数
+func (ct *CT) EncodedLen(enc size.Encoding) int {
+	return ct.Size().Len(enc)
+}
+
+// Size 列出转换密钥 TK 的各部分
+func (tk *TK) Size() size.Report {
+	return size.Breakdown("FEABSE.TK", tk)
+}
+
+// EncodedLen 返回转换密钥在编码 enc 下的字节数
+func (tk *TK) EncodedLen(enc size.Encoding) int {
+	return tk.Size().Len(enc)
+}
+
+// Size 列出用户私钥 SKdu 的各部分
+func (sk *SKdu) Size() size.Report {
+	return size.Breakdown("FEABSE.SKdu", sk)
+}
+
+// EncodedLen 返回用户私钥在编码 enc 下的字节数
+func (sk *SKdu) EncodedLen(enc size.Encoding) int {
+	return sk.Size().Len(enc)
+}
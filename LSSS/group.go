@@ -0,0 +1,151 @@
+package LSSS
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/fentec-project/bn256"
+	"github.com/fentec-project/gofe/abe"
+	"github.com/fentec-project/gofe/data"
+)
+
+/*
+Group 描述可以"在指数上"做秘密共享的群元素。
+
+	bn256.G1、bn256.G2、bn256.GT 以及下面的 Scalar 都满足该约束，
+	ScalarMult 即 a^k，Add 即群运算 a·b（对 GT 为乘法）
+
+使用方式:
+
+	LSSS.ShareInExponent(msp, g, s, p)     // {g^λi}
+	LSSS.ReconInExponent(msp, shares, p)   // ∏ shares_i^{wi}
+*/
+type Group[T any] interface {
+	*T
+	ScalarMult(a *T, k *big.Int) *T
+	Add(a, b *T) *T
+}
+
+// Scalar 是 Zp 上的元素，使标量与 G1/G2/GT 共用同一套泛型接口
+// 运算模 bn256.Order：ScalarMult 为乘法，Add 为加法
+type Scalar big.Int
+
+// NewScalar 把 big.Int 转为 Scalar
+func NewScalar(x *big.Int) *Scalar {
+	return (*Scalar)(new(big.Int).Mod(x, bn256.Order))
+}
+
+// Int 返回 Scalar 对应的 big.Int
+func (z *Scalar) Int() *big.Int {
+	return (*big.Int)(z)
+}
+
+func (z *Scalar) ScalarMult(a *Scalar, k *big.Int) *Scalar {
+	v := new(big.Int).Mul(a.Int(), k)
+	(*big.Int)(z).Mod(v, bn256.Order)
+	return z
+}
+
+func (z *Scalar) Add(a, b *Scalar) *Scalar {
+	v := new(big.Int).Add(a.Int(), b.Int())
+	(*big.Int)(z).Mod(v, bn256.Order)
+	return z
+}
+
+// ShareInExponent 计算 {base^λi}，其中 λ = M·v 且 v[0] = s
+// 返回值下标与 MSP 行号一致
+func ShareInExponent[T any, PT Group[T]](msp *abe.MSP, base *T, s *big.Int, p *big.Int) (map[int]*T, error) {
+	lambdaI, err := Share(msp, s, p)
+	if err != nil {
+		return nil, err
+	}
+	return ExpShares[T, PT](base, lambdaI), nil
+}
+
+// ExpShares 对已有的份额 {λi} 计算 {base^λi}
+func ExpShares[T any, PT Group[T]](base *T, lambdaI map[int]*big.Int) map[int]*T {
+	out := make(map[int]*T, len(lambdaI))
+	for i, lambda := range lambdaI {
+		out[i] = PT(new(T)).ScalarMult(base, lambda)
+	}
+	return out
+}
+
+// ReconInExponent 用 shares 中出现的所有行求重构系数 {wi}，并返回 ∏ shares_i^{wi}
+func ReconInExponent[T any, PT Group[T]](msp *abe.MSP, shares map[int]*T, p *big.Int) (*T, error) {
+	if len(shares) == 0 {
+		return nil, errors.New("no attributes satisfy the policy for reconstruction")
+	}
+	rows := make([]int, 0, len(shares))
+	for i := range shares {
+		rows = append(rows, i)
+	}
+	WI, err := Coefficients(msp, rows, p)
+	if err != nil {
+		return nil, err
+	}
+	return Combine[T, PT](shares, WI), nil
+}
+
+// Combine 计算 ∏_{i∈w} shares_i^{wi}，shares 必须包含 w 的每一行
+func Combine[T any, PT Group[T]](shares map[int]*T, w map[int]*big.Int) *T {
+	// 按行号顺序计算，结果与 map 的遍历顺序无关
+	rows := make([]int, 0, len(w))
+	for i := range w {
+		rows = append(rows, i)
+	}
+	sort.Ints(rows)
+
+	var result *T
+	for _, i := range rows {
+		term := PT(new(T)).ScalarMult(shares[i], w[i])
+		if result == nil {
+			result = term
+		} else {
+			PT(result).Add(result, term)
+		}
+	}
+	return result
+}
+
+// Coefficients 对给定的行集合 rows 求解 w·M_rows = (1, 0, ..., 0)，返回 {行号 -> wi mod p}
+func Coefficients(msp *abe.MSP, rows []int, p *big.Int) (map[int]*big.Int, error) {
+	if msp == nil || len(msp.Mat) == 0 {
+		return nil, errors.New("msp or msp.Mat is empty")
+	}
+	if len(rows) == 0 {
+		return nil, errors.New("no rows selected: attributes do not satisfy policy")
+	}
+	numCols := len(msp.Mat[0])
+	if numCols == 0 {
+		return nil, errors.New("msp.Mat has zero columns")
+	}
+
+	SubMatrix := make(data.Matrix, 0, len(rows))
+	for _, i := range rows {
+		// 确保行 i 存在于 MSP 矩阵中
+		if i < 0 || i >= len(msp.Mat) {
+			return nil, fmt.Errorf("invalid row index %d found in shares", i)
+		}
+		SubMatrix = append(SubMatrix, msp.Mat[i])
+	}
+
+	targetVector := make(data.Vector, numCols)
+	targetVector[0] = big.NewInt(1)
+	for i := 1; i < numCols; i++ {
+		targetVector[i] = big.NewInt(0)
+	}
+
+	WI, err := data.GaussianEliminationSolver(SubMatrix.Transpose(), targetVector, p)
+	if err != nil {
+		return nil, fmt.Errorf("LSSS system is not solvable: %w", err)
+	}
+
+	wMap := make(map[int]*big.Int, len(rows))
+	for k, wi := range WI {
+		wMap[rows[k]] = new(big.Int).Mod(wi, p)
+	}
+	return wMap, nil
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/fentec-project/bn256"
 	"github.com/fentec-project/gofe/abe"
+	"github.com/fentec-project/gofe/data"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -54,3 +55,207 @@ func TestLSSSCompleteFlow(t *testing.T) {
 	assert.True(t, reconstructed.String() == expected.String(),
 		"重构的秘密与原始秘密不匹配")
 }
+
+func TestShareAndReconInExponent(t *testing.T) {
+	p := bn256.Order
+	msp, err := abe.BooleanToMSP("A AND (B OR C) AND (D OR (E AND F))", false)
+	require.NoError(t, err)
+	secret := big.NewInt(424242)
+	// 满足策略的行：A, C, E, F
+	satisfied := map[string]bool{"A": true, "C": true, "E": true, "F": true}
+	pick := func(i int) bool { return satisfied[msp.RowToAttrib[i]] }
+
+	g1 := new(bn256.G1).ScalarBaseMult(big.NewInt(1))
+	shares1, err := ShareInExponent(msp, g1, secret, p)
+	require.NoError(t, err)
+	sub1 := make(map[int]*bn256.G1)
+	for i, v := range shares1 {
+		if pick(i) {
+			sub1[i] = v
+		}
+	}
+	rec1, err := ReconInExponent(msp, sub1, p)
+	require.NoError(t, err)
+	require.Equal(t, new(bn256.G1).ScalarMult(g1, secret).String(), rec1.String())
+
+	g2 := new(bn256.G2).ScalarBaseMult(big.NewInt(1))
+	shares2, err := ShareInExponent(msp, g2, secret, p)
+	require.NoError(t, err)
+	sub2 := make(map[int]*bn256.G2)
+	for i, v := range shares2 {
+		if pick(i) {
+			sub2[i] = v
+		}
+	}
+	rec2, err := ReconInExponent(msp, sub2, p)
+	require.NoError(t, err)
+	require.Equal(t, new(bn256.G2).ScalarMult(g2, secret).String(), rec2.String())
+
+	gt := bn256.Pair(g1, g2)
+	sharesT, err := ShareInExponent(msp, gt, secret, p)
+	require.NoError(t, err)
+	subT := make(map[int]*bn256.GT)
+	for i, v := range sharesT {
+		if pick(i) {
+			subT[i] = v
+		}
+	}
+	recT, err := ReconInExponent(msp, subT, p)
+	require.NoError(t, err)
+	require.Equal(t, new(bn256.GT).ScalarMult(gt, secret).String(), recT.String())
+
+	// Zp：base = 1 时份额即 λi 本身
+	sharesZ, err := ShareInExponent(msp, NewScalar(big.NewInt(1)), secret, p)
+	require.NoError(t, err)
+	subZ := make(map[int]*Scalar)
+	for i, v := range sharesZ {
+		if pick(i) {
+			subZ[i] = v
+		}
+	}
+	recZ, err := ReconInExponent(msp, subZ, p)
+	require.NoError(t, err)
+	require.Equal(t, 0, secret.Cmp(recZ.Int()))
+
+	// 不满足策略的行集合无法重构
+	delete(subZ, indexOf(msp, "A"))
+	_, err = ReconInExponent(msp, subZ, p)
+	require.Error(t, err)
+}
+
+func indexOf(msp *abe.MSP, attr string) int {
+	for i, a := range msp.RowToAttrib {
+		if a == attr {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestValidate(t *testing.T) {
+	msp, err := abe.BooleanToMSP("A AND (B OR C)", false)
+	require.NoError(t, err)
+	require.NoError(t, Validate(msp))
+
+	require.Error(t, Validate(nil))
+	require.Error(t, Validate(&abe.MSP{}))
+
+	bad := *msp
+	bad.RowToAttrib = []string{"A", "", "C"}
+	require.Error(t, Validate(&bad), "empty row attribute")
+
+	bad = *msp
+	bad.RowToAttrib = msp.RowToAttrib[:2]
+	require.Error(t, Validate(&bad), "row/attribute count mismatch")
+
+	// 行 (1, 1) 与 (2, 2) 只能张成 (k, k)，目标向量 (1, 0) 不可达
+	unreachable := &abe.MSP{
+		Mat: data.Matrix{
+			data.NewVector([]*big.Int{big.NewInt(1), big.NewInt(1)}),
+			data.NewVector([]*big.Int{big.NewInt(2), big.NewInt(2)}),
+		},
+		RowToAttrib: []string{"A", "B"},
+	}
+	require.Error(t, Validate(unreachable))
+}
+
+func TestReconPlanCache(t *testing.T) {
+	p := bn256.Order
+	msp, err := abe.BooleanToMSP("A OR (B AND C) OR D", false)
+	require.NoError(t, err)
+	attrs := []string{"C", "B", "X"}
+
+	cache := NewPlanCache()
+	plan, err := cache.Plan(nil, msp, attrs, p)
+	require.NoError(t, err)
+	require.Len(t, plan.Rows, 2)
+	// 属性顺序不影响缓存键
+	again, err := cache.Plan(nil, msp, []string{"X", "B", "C"}, p)
+	require.NoError(t, err)
+	require.Equal(t, plan, again)
+	require.Equal(t, 1, cache.Len())
+	// 返回的是副本，修改它不影响缓存
+	require.NotSame(t, plan, again)
+	again.Rows[0] = 99
+	again.Coeffs[plan.Rows[0]].SetInt64(0)
+	third, err := cache.Plan(nil, msp, attrs, p)
+	require.NoError(t, err)
+	require.Equal(t, plan, third)
+
+	_, err = cache.Plan(nil, msp, []string{"B"}, p)
+	require.Error(t, err)
+
+	// 同一计划用于多组份额
+	g := new(bn256.GT).ScalarBaseMult(big.NewInt(1))
+	for k := 0; k < 3; k++ {
+		secret := big.NewInt(int64(100 + k))
+		shares, err := ShareInExponent(msp, g, secret, p)
+		require.NoError(t, err)
+		rec, err := ReconWithPlan(plan, shares)
+		require.NoError(t, err)
+		require.Equal(t, new(bn256.GT).ScalarMult(g, secret).String(), rec.String())
+	}
+}
+
+func TestPlanCacheEviction(t *testing.T) {
+	p := bn256.Order
+	msp, err := abe.BooleanToMSP("A OR (B AND C) OR D", false)
+	require.NoError(t, err)
+
+	cache := NewPlanCacheSize(2)
+	_, err = cache.Plan(nil, msp, []string{"A"}, p)
+	require.NoError(t, err)
+	_, err = cache.Plan(nil, msp, []string{"B", "C"}, p)
+	require.NoError(t, err)
+	// 访问 A 使其成为最近使用，插入 D 时淘汰 (B, C)
+	_, err = cache.Plan(nil, msp, []string{"A"}, p)
+	require.NoError(t, err)
+	_, err = cache.Plan(nil, msp, []string{"D"}, p)
+	require.NoError(t, err)
+	require.Equal(t, 2, cache.Len())
+	_, kept := cache.plans[planKey(MSPDigest(msp), []string{"A"})]
+	require.True(t, kept)
+	_, kept = cache.plans[planKey(MSPDigest(msp), []string{"B", "C"})]
+	require.False(t, kept)
+
+	// 调用方给出的摘要与 msp 不符时，命中的计划被重新计算而不是直接使用
+	other, err := abe.BooleanToMSP("(A AND B) OR D", false)
+	require.NoError(t, err)
+	digest := []byte("policy")
+	all := []string{"A", "B", "C", "D"}
+	stale, err := cache.Plan(digest, msp, all, p)
+	require.NoError(t, err)
+	require.False(t, stale.fits(other, all, p))
+	plan, err := cache.Plan(digest, other, all, p)
+	require.NoError(t, err)
+	require.True(t, plan.fits(other, all, p))
+	g := new(bn256.GT).ScalarBaseMult(big.NewInt(1))
+	shares, err := ShareInExponent(other, g, big.NewInt(7), p)
+	require.NoError(t, err)
+	rec, err := ReconWithPlan(plan, shares)
+	require.NoError(t, err)
+	require.Equal(t, new(bn256.GT).ScalarMult(g, big.NewInt(7)).String(), rec.String())
+}
+
+func TestIndependentRows(t *testing.T) {
+	p := bn256.Order
+	msp, err := abe.BooleanToMSP("(A AND B) OR (A AND C) OR (A AND D)", false)
+	require.NoError(t, err)
+	all := make([]int, len(msp.Mat))
+	for i := range all {
+		all[i] = i
+	}
+	rows, err := IndependentRows(msp, all, p)
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+
+	// 三行 A 两两线性相关也无法单独满足策略
+	var onlyA []int
+	for i, attr := range msp.RowToAttrib {
+		if attr == "A" {
+			onlyA = append(onlyA, i)
+		}
+	}
+	_, err = IndependentRows(msp, onlyA, p)
+	require.Error(t, err)
+}
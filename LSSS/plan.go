@@ -0,0 +1,367 @@
+package LSSS
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/fentec-project/bn256"
+	"github.com/fentec-project/gofe/abe"
+)
+
+// Validate 检查 MSP 是否可用于秘密共享：
+//  1. 矩阵非空，每一行的列数一致，且每一列都至少有一个非零元素
+//  2. RowToAttrib 与矩阵行数一致，且每一行对应的属性名非空
+//  3. 所有行合起来能张成目标向量 (1, 0, ..., 0)，即策略至少可被全体属性满足
+func Validate(msp *abe.MSP) error {
+	if msp == nil || len(msp.Mat) == 0 {
+		return errors.New("msp or msp.Mat is empty")
+	}
+	numCols := len(msp.Mat[0])
+	if numCols == 0 {
+		return errors.New("msp.Mat has zero columns")
+	}
+	if len(msp.RowToAttrib) != len(msp.Mat) {
+		return fmt.Errorf("msp has %d rows but %d row attributes", len(msp.Mat), len(msp.RowToAttrib))
+	}
+	nonZero := make([]bool, numCols)
+	for i, row := range msp.Mat {
+		if len(row) != numCols {
+			return fmt.Errorf("msp row %d has %d columns, expected %d", i, len(row), numCols)
+		}
+		if strings.TrimSpace(msp.RowToAttrib[i]) == "" {
+			return fmt.Errorf("msp row %d is not mapped to an attribute", i)
+		}
+		for j, v := range row {
+			if v == nil {
+				return fmt.Errorf("msp entry (%d, %d) is nil", i, j)
+			}
+			if v.Sign() != 0 {
+				nonZero[j] = true
+			}
+		}
+	}
+	for j, ok := range nonZero {
+		if !ok {
+			return fmt.Errorf("msp column %d is all zero", j)
+		}
+	}
+
+	rows := make([]int, len(msp.Mat))
+	for i := range rows {
+		rows[i] = i
+	}
+	if _, err := Coefficients(msp, rows, fieldOrder(msp)); err != nil {
+		return fmt.Errorf("target vector (1, 0, ..., 0) is not reachable: %w", err)
+	}
+	return nil
+}
+
+// fieldOrder 返回 msp 所在域的阶，BooleanToMSP 不填写 msp.P 时使用 bn256 的阶
+func fieldOrder(msp *abe.MSP) *big.Int {
+	if msp.P != nil {
+		return msp.P
+	}
+	return bn256.Order
+}
+
+// ReconPlan 是一次重构所需的全部信息：参与重构的行以及对应的系数 wi
+// 同一策略、同一属性集合下的所有密文可以共用同一个 ReconPlan，
+// 不必为每次解密重新做高斯消元
+type ReconPlan struct {
+	Rows   []int            // 参与重构的行号（系数非零），升序
+	Coeffs map[int]*big.Int // 行号 -> wi
+}
+
+// NewReconPlan 为属性集合 attrs 在 msp 上求重构计划
+// 同一属性出现在多行时（如 "(A AND B) OR (A AND C)"），只保留一个线性无关且足以张成
+// 目标向量的行子集，其余冗余行不参与重构，也就不必为它们计算配对
+func NewReconPlan(msp *abe.MSP, attrs []string, p *big.Int) (*ReconPlan, error) {
+	if err := Validate(msp); err != nil {
+		return nil, err
+	}
+	attrSet := make(map[string]bool, len(attrs))
+	for _, a := range attrs {
+		attrSet[strings.TrimSpace(a)] = true
+	}
+	candidates := make([]int, 0)
+	for i := range msp.Mat {
+		if attrSet[msp.RowToAttrib[i]] {
+			candidates = append(candidates, i)
+		}
+	}
+	rows, err := IndependentRows(msp, candidates, p)
+	if err != nil {
+		return nil, err
+	}
+	w, err := Coefficients(msp, rows, p)
+	if err != nil {
+		return nil, err
+	}
+	plan := &ReconPlan{Coeffs: make(map[int]*big.Int, len(w))}
+	for i, wi := range w {
+		// 系数为 0 的行对结果没有贡献，不需要为它计算配对
+		if wi.Sign() == 0 {
+			continue
+		}
+		plan.Rows = append(plan.Rows, i)
+		plan.Coeffs[i] = wi
+	}
+	sort.Ints(plan.Rows)
+	return plan, nil
+}
+
+// IndependentRows 按 rows 的顺序贪心地挑选线性无关的行，
+// 一旦所选行张成目标向量 (1, 0, ..., 0) 就停止。
+// 返回的行子集线性无关，因此重构系数唯一；rows 不满足策略时返回错误
+func IndependentRows(msp *abe.MSP, rows []int, p *big.Int) ([]int, error) {
+	if len(rows) == 0 {
+		return nil, errors.New("no rows selected: attributes do not satisfy policy")
+	}
+	numCols := len(msp.Mat[0])
+	target := make([]*big.Int, numCols)
+	target[0] = big.NewInt(1)
+	for j := 1; j < numCols; j++ {
+		target[j] = big.NewInt(0)
+	}
+
+	// basis 保持约化行阶梯形：每个基向量在自己的主元列上为 1，在其他基向量的主元列上为 0
+	var basis [][]*big.Int
+	var pivots []int
+	reduce := func(v []*big.Int) []*big.Int {
+		out := make([]*big.Int, numCols)
+		for j := range v {
+			out[j] = new(big.Int).Mod(v[j], p)
+		}
+		for k, b := range basis {
+			f := new(big.Int).Set(out[pivots[k]])
+			if f.Sign() == 0 {
+				continue
+			}
+			for j := range out {
+				out[j].Sub(out[j], new(big.Int).Mul(f, b[j]))
+				out[j].Mod(out[j], p)
+			}
+		}
+		return out
+	}
+	isZero := func(v []*big.Int) bool {
+		for _, x := range v {
+			if x.Sign() != 0 {
+				return false
+			}
+		}
+		return true
+	}
+
+	var chosen []int
+	for _, i := range rows {
+		if i < 0 || i >= len(msp.Mat) {
+			return nil, fmt.Errorf("invalid row index %d", i)
+		}
+		v := reduce(msp.Mat[i])
+		pivot := -1
+		for j, x := range v {
+			if x.Sign() != 0 {
+				pivot = j
+				break
+			}
+		}
+		if pivot < 0 {
+			// 与已选行线性相关，跳过
+			continue
+		}
+		inv := new(big.Int).ModInverse(v[pivot], p)
+		for j := range v {
+			v[j].Mul(v[j], inv).Mod(v[j], p)
+		}
+		for k, b := range basis {
+			f := new(big.Int).Set(b[pivot])
+			if f.Sign() == 0 {
+				continue
+			}
+			for j := range b {
+				b[j].Sub(b[j], new(big.Int).Mul(f, v[j]))
+				b[j].Mod(b[j], p)
+			}
+			basis[k] = b
+		}
+		basis = append(basis, v)
+		pivots = append(pivots, pivot)
+		chosen = append(chosen, i)
+
+		if isZero(reduce(target)) {
+			return chosen, nil
+		}
+	}
+	return nil, errors.New("attributes do not satisfy policy: target vector not in span")
+}
+
+// ReconWithPlan 计算 ∏_{i∈plan.Rows} shares_i^{wi}
+func ReconWithPlan[T any, PT Group[T]](plan *ReconPlan, shares map[int]*T) (*T, error) {
+	if plan == nil || len(plan.Rows) == 0 {
+		return nil, errors.New("empty reconstruction plan")
+	}
+	for _, i := range plan.Rows {
+		if shares[i] == nil {
+			return nil, fmt.Errorf("missing share for row %d", i)
+		}
+	}
+	return Combine[T, PT](shares, plan.Coeffs), nil
+}
+
+// DefaultPlanCacheSize 是 NewPlanCache 的容量上限
+const DefaultPlanCacheSize = 1024
+
+// PlanCache 按 (策略摘要, 属性集合) 缓存 ReconPlan，可被多个 goroutine 共享
+// 缓存容量有上限，超出时淘汰最久未使用的计划，长期运行的云端内存占用不会无限增长
+type PlanCache struct {
+	mu    sync.Mutex
+	size  int
+	order *list.List               // 最近使用的在前，元素为 *planEntry
+	plans map[string]*list.Element // 缓存键 -> order 中的元素
+}
+
+type planEntry struct {
+	key  string
+	plan *ReconPlan
+}
+
+func NewPlanCache() *PlanCache {
+	return NewPlanCacheSize(DefaultPlanCacheSize)
+}
+
+// NewPlanCacheSize 创建最多保存 size 个计划的缓存，size <= 0 时使用 DefaultPlanCacheSize
+func NewPlanCacheSize(size int) *PlanCache {
+	if size <= 0 {
+		size = DefaultPlanCacheSize
+	}
+	return &PlanCache{size: size, order: list.New(), plans: make(map[string]*list.Element)}
+}
+
+// Plan 返回 digest 与 attrs 对应的计划，缓存未命中时在 msp 上计算并缓存
+// digest 为空时使用 MSPDigest(msp)；c 为 nil 时不缓存，直接计算。
+// digest 由调用方提供（如密文的策略摘要），不一定与 msp 对应：命中的计划会先在 msp 上核对
+// ∑ wi·Mi = (1, 0, ..., 0)，不符时重新计算并替换，被篡改的摘要不会让其他密文用错计划。
+// 返回的是缓存中计划的副本，调用方修改它不影响缓存
+func (c *PlanCache) Plan(digest []byte, msp *abe.MSP, attrs []string, p *big.Int) (*ReconPlan, error) {
+	if c == nil {
+		return NewReconPlan(msp, attrs, p)
+	}
+	if len(digest) == 0 {
+		digest = MSPDigest(msp)
+	}
+	key := planKey(digest, attrs)
+
+	c.mu.Lock()
+	var plan *ReconPlan
+	if e, ok := c.plans[key]; ok {
+		c.order.MoveToFront(e)
+		plan = e.Value.(*planEntry).plan
+	}
+	c.mu.Unlock()
+	if plan != nil && plan.fits(msp, attrs, p) {
+		return plan.clone(), nil
+	}
+
+	plan, err := NewReconPlan(msp, attrs, p)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	if e, ok := c.plans[key]; ok {
+		e.Value.(*planEntry).plan = plan
+		c.order.MoveToFront(e)
+	} else {
+		c.plans[key] = c.order.PushFront(&planEntry{key: key, plan: plan})
+		for c.order.Len() > c.size {
+			oldest := c.order.Back()
+			c.order.Remove(oldest)
+			delete(c.plans, oldest.Value.(*planEntry).key)
+		}
+	}
+	c.mu.Unlock()
+	return plan.clone(), nil
+}
+
+// Len 返回缓存中计划的数量
+func (c *PlanCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.plans)
+}
+
+// clone 深拷贝计划
+func (plan *ReconPlan) clone() *ReconPlan {
+	out := &ReconPlan{Rows: append([]int(nil), plan.Rows...), Coeffs: make(map[int]*big.Int, len(plan.Coeffs))}
+	for i, w := range plan.Coeffs {
+		out.Coeffs[i] = new(big.Int).Set(w)
+	}
+	return out
+}
+
+// fits 检查计划是否适用于 msp 与 attrs：每一行都在矩阵内且属于 attrs，
+// 并且 ∑ wi·Mi = (1, 0, ..., 0) (mod p)
+func (plan *ReconPlan) fits(msp *abe.MSP, attrs []string, p *big.Int) bool {
+	if msp == nil || len(msp.Mat) == 0 || len(plan.Rows) == 0 {
+		return false
+	}
+	attrSet := make(map[string]bool, len(attrs))
+	for _, a := range attrs {
+		attrSet[strings.TrimSpace(a)] = true
+	}
+	numCols := len(msp.Mat[0])
+	sum := make([]*big.Int, numCols)
+	for j := range sum {
+		sum[j] = new(big.Int)
+	}
+	for _, i := range plan.Rows {
+		w := plan.Coeffs[i]
+		if i < 0 || i >= len(msp.Mat) || i >= len(msp.RowToAttrib) || w == nil ||
+			!attrSet[msp.RowToAttrib[i]] || len(msp.Mat[i]) != numCols {
+			return false
+		}
+		for j, v := range msp.Mat[i] {
+			if v == nil {
+				return false
+			}
+			sum[j].Add(sum[j], new(big.Int).Mul(w, v))
+		}
+	}
+	for j, v := range sum {
+		v.Mod(v, p)
+		if (j == 0 && v.Cmp(big.NewInt(1)) != 0) || (j > 0 && v.Sign() != 0) {
+			return false
+		}
+	}
+	return true
+}
+
+func planKey(digest []byte, attrs []string) string {
+	sorted := append([]string(nil), attrs...)
+	sort.Strings(sorted)
+	return hex.EncodeToString(digest) + "|" + strings.Join(sorted, "\x00")
+}
+
+// MSPDigest 对 MSP 的矩阵与行属性映射做哈希，用于没有策略摘要的场景
+func MSPDigest(msp *abe.MSP) []byte {
+	h := sha256.New()
+	for i, row := range msp.Mat {
+		if i < len(msp.RowToAttrib) {
+			h.Write([]byte(msp.RowToAttrib[i]))
+		}
+		h.Write([]byte{0})
+		for _, v := range row {
+			h.Write([]byte(v.String()))
+			h.Write([]byte{','})
+		}
+		h.Write([]byte{'\n'})
+	}
+	return h.Sum(nil)
+}
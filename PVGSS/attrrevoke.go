@@ -0,0 +1,113 @@
+package PVGSS
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/fentec-project/bn256"
+	"github.com/fentec-project/gofe/abe"
+	"github.com/fentec-project/gofe/sample"
+)
+
+/*
+属性级吊销
+
+用户失去某个属性 x（其余属性保留）时，只更新 x 的版本，不需要重新生成整个系统的密钥：
+  - 权威机构选取 d←Zp，令 hx ← hx·g^d，pkx ← pkx·g^{ad}，版本号加一；
+  - 对仍持有 x 的每个用户签发 KeyUpdate：Ux = L^{ad}，用户令 Kx ← Kx·Ux = (pkx·g^{ad})^t；
+  - 云端用更新信息把 ρ(i) = x 的份额更新为 Ci ← Ci·(Ci')^{-ad} = b^{λi}·(pkx·g^{ad})^{-ri}。
+
+被吊销的用户没有 Ux，其 Kx 停留在旧版本，重构时该属性视为未持有。
+AttrUpdate 中的 ad 只交给权威机构与云端；云端由此可为任意 L 计算 Ux，因此假设云端不与被吊销用户合谋。
+*/
+
+// AttrUpdate 是属性 Attr 升级到 Version 的更新信息，Key = a·d mod p
+type AttrUpdate struct {
+	Attr    string
+	Version int
+	Key     *big.Int
+}
+
+// KeyUpdate 是签发给某个未被吊销用户的属性密钥更新 Ux = L^{ad}
+type KeyUpdate struct {
+	Attr    string
+	Version int
+	U       *bn256.G2
+}
+
+// RevokeAttribute 把属性 attr 升级到下一个版本，就地更新 pp 中的 hx 与 pkx
+func (pvgss *PVGSS) RevokeAttribute(pp *PublicParameter, sk *SecretKey, attr string) (*AttrUpdate, error) {
+	if _, ok := pp.PkXs[attr]; !ok {
+		return nil, fmt.Errorf("attribute %s not in public parameters", attr)
+	}
+	sampler := sample.NewUniformRange(big.NewInt(1), pp.Order)
+	d, _ := sampler.Sample()
+	ad := new(big.Int).Mul(sk.A, d)
+	ad.Mod(ad, pp.Order)
+
+	pp.HXs[attr] = new(bn256.G1).Add(pp.HXs[attr], pvgss.Ops.G1Base(d))
+	pp.HXsG2[attr] = new(bn256.G2).Add(pp.HXsG2[attr], pvgss.Ops.G2Base(d))
+	pp.PkXs[attr] = new(bn256.G1).Add(pp.PkXs[attr], pvgss.Ops.G1Base(ad))
+	pp.PkXsG2[attr] = new(bn256.G2).Add(pp.PkXsG2[attr], pvgss.Ops.G2Base(ad))
+	if pp.Versions == nil {
+		pp.Versions = make(map[string]int)
+	}
+	pp.Versions[attr]++
+
+	return &AttrUpdate{Attr: attr, Version: pp.Versions[attr], Key: ad}, nil
+}
+
+// KeyUpdate 为持有 upd.Attr 且仍应保留该属性的用户生成密钥更新，只用到 OSK 中公开的 L
+func (pvgss *PVGSS) KeyUpdate(upd *AttrUpdate, osk *OSK) (*KeyUpdate, error) {
+	if _, ok := osk.KXs[upd.Attr]; !ok {
+		return nil, fmt.Errorf("key does not hold attribute %s", upd.Attr)
+	}
+	if osk.Versions[upd.Attr] != upd.Version-1 {
+		return nil, fmt.Errorf("key holds version %d of %s, update is for version %d", osk.Versions[upd.Attr], upd.Attr, upd.Version)
+	}
+	return &KeyUpdate{Attr: upd.Attr, Version: upd.Version, U: pvgss.Ops.G2Mul(osk.L, upd.Key)}, nil
+}
+
+// ApplyKeyUpdate 由用户执行：Kx ← Kx·Ux，并记录新版本
+func (pvgss *PVGSS) ApplyKeyUpdate(osk *OSK, ku *KeyUpdate) error {
+	kx, ok := osk.KXs[ku.Attr]
+	if !ok {
+		return fmt.Errorf("key does not hold attribute %s", ku.Attr)
+	}
+	if osk.Versions[ku.Attr] != ku.Version-1 {
+		return fmt.Errorf("key holds version %d of %s, update is for version %d", osk.Versions[ku.Attr], ku.Attr, ku.Version)
+	}
+	osk.KXs[ku.Attr] = new(bn256.G2).Add(kx, ku.U)
+	if osk.Versions == nil {
+		osk.Versions = make(map[string]int)
+	}
+	osk.Versions[ku.Attr] = ku.Version
+	return nil
+}
+
+// UpdateShares 由云端执行，把 ρ(i) = upd.Attr 的各行份额更新到新版本：Ci ← Ci·(Ci')^{-ad}，
+// 其余行不变。任一行版本不符时不做任何修改。返回更新的行数
+func (pvgss *PVGSS) UpdateShares(upd *AttrUpdate, ct map[int]*CipherText, msp *abe.MSP) (int, error) {
+	var rows []int
+	for i, c := range ct {
+		if i < 0 || i >= len(msp.RowToAttrib) {
+			return 0, fmt.Errorf("share for unknown row %d", i)
+		}
+		if msp.RowToAttrib[i] != upd.Attr {
+			continue
+		}
+		if c.Version != upd.Version-1 {
+			return 0, fmt.Errorf("share for row %d is at version %d, update is for version %d", i, c.Version, upd.Version)
+		}
+		rows = append(rows, i)
+	}
+
+	negKey := new(big.Int).Neg(upd.Key)
+	negKey.Mod(negKey, pvgss.P)
+	for _, i := range rows {
+		c := ct[i]
+		c.Ci = new(bn256.G1).Add(c.Ci, pvgss.Ops.G1Mul(c.CiPrime, negKey))
+		c.Version = upd.Version
+	}
+	return len(rows), nil
+}
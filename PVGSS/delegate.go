@@ -0,0 +1,54 @@
+package PVGSS
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/AUKUS561/PVOABE/policy"
+	"github.com/fentec-project/bn256"
+	"github.com/fentec-project/gofe/sample"
+)
+
+// Delegate 由持有 osk 的用户执行，不经权威机构派生只含属性子集 subset 的子密钥。
+// 选取 t'←Zp，把 t 重新随机化为 t+t'：L' = L·g^{t'}，Kx' = Kx·pkx^{t'}，h^{t+t'} = h^t·h^{t'}，
+// 子密钥的群元素与父密钥的相互独立，子密钥持有者无法由此还原父密钥的其他 Kx。
+// 父密钥持有的否定属性 not_x 随子密钥保留，子密钥不能获得父密钥没有的属性。
+// 属性版本照搬父密钥；子密钥不继承父密钥的 ID，由 PVOABE.Delegate 分配新的标识，
+// 因此子密钥对云端可见的各部分都与父密钥无关，子密钥与父密钥不可链接
+func (pvgss *PVGSS) Delegate(pp *PublicParameter, osk *OSK, subset []string) (*OSK, error) {
+	attrs := append([]string(nil), subset...)
+	inSubset := make(map[string]bool, len(subset))
+	for _, x := range subset {
+		inSubset[x] = true
+	}
+	for x := range osk.KXs {
+		if policy.IsNegated(x) && !inSubset[x] {
+			attrs = append(attrs, x)
+		}
+	}
+
+	sampler := sample.NewUniformRange(big.NewInt(1), pp.Order)
+	tPrime, _ := sampler.Sample()
+	sub := &OSK{
+		L:        new(bn256.G2).Add(osk.L, pvgss.Ops.G2Base(tPrime)),
+		KXs:      make(map[string]*bn256.G2, len(attrs)),
+		Ht:       new(bn256.G1).Add(osk.Ht, pvgss.Ops.G1Mul(pp.H, tPrime)),
+		Versions: make(map[string]int, len(attrs)),
+	}
+	for _, x := range attrs {
+		kx, ok := osk.KXs[x]
+		if !ok {
+			return nil, fmt.Errorf("cannot delegate attribute %s: not held by the parent key", x)
+		}
+		pkx, ok := pp.PkXsG2[x]
+		if !ok {
+			return nil, fmt.Errorf("attribute %s not in public parameters", x)
+		}
+		if osk.Versions[x] != pp.Versions[x] {
+			return nil, fmt.Errorf("cannot delegate attribute %s: parent key is at version %d, current version is %d", x, osk.Versions[x], pp.Versions[x])
+		}
+		sub.KXs[x] = new(bn256.G2).Add(kx, pvgss.Ops.G2Mul(pkx, tPrime))
+		sub.Versions[x] = osk.Versions[x]
+	}
+	return sub, nil
+}
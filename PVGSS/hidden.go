@@ -0,0 +1,195 @@
+package PVGSS
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/AUKUS561/PVOABE/DLEQ"
+	"github.com/AUKUS561/PVOABE/LSSS"
+	"github.com/AUKUS561/PVOABE/opcount"
+	"github.com/fentec-project/bn256"
+	"github.com/fentec-project/gofe/abe"
+	"github.com/fentec-project/gofe/sample"
+)
+
+/*
+部分隐藏策略
+
+属性写作 name:value，name 公开、value 隐藏。隐藏取值的 pkx 不放进公共参数，
+由 SetupHidden 生成后只交给数据拥有者与签发密钥的权威机构（HiddenKeys）：
+  - 数据拥有者用 HidePolicy 把 MSP 中的 name:value 改写为 name，并为每个这样的行选取 u←Zp，
+    给出 G1 中的盲化公钥 X = pkx^u、U = g^u；
+  - 云端 ShareHidden 计算 Ci = b^{λi}·X^{-ri}，另给出 E = U^{ri}、T = X^{ri} 与证明 log_U E = log_X T 的 DLEQ；
+  - SVerifyHidden 验证 DLEQ，并用 e(Ci·T, g) 代替 e(Ci, g)e(pkρ(i), Ci')，因此无需知道取值即可公开验证；
+  - 用户的 Kx = pkx^t 按 name 分组存放在 OSK.Hidden 中，对第 i 行测试 e(T, L) = e(E, Kx)，
+    匹配的行按 e(Ci, L)·e(E, Kx) = e(b^{λi}, L) 参与重构，Recon 与 DVerify 不需要改动。
+
+隐藏行只公开 G1 元素，不公开 g^u 或 pkx^u 在 G2 中的像，因此无法用 e(X1, U2) = e(U1, X2) 之类的配对
+判断两行（包括不同密文中的行）是否为同一取值；在 G1 中判断需要解 DDH。
+持有匹配 Kx 的用户（以及拿到其 OSK 的云端）仍能识别该取值的行。Delegate 不派生隐藏属性。
+*/
+
+// HiddenKeys 是隐藏取值属性的公钥，只交给数据拥有者与权威机构
+type HiddenKeys struct {
+	PkXs   map[string]*bn256.G1
+	PkXsG2 map[string]*bn256.G2
+}
+
+// HiddenRow 是数据拥有者为隐藏取值的行提供的盲化公钥
+type HiddenRow struct {
+	X *bn256.G1 //pkx^u
+	U *bn256.G1 //g^u
+}
+
+// hiddenRowCtx 是隐藏行 DLEQ 证明的上下文
+var hiddenRowCtx = []byte("PVGSS/hidden-row")
+
+// HiddenName 返回隐藏属性 name:value 的名字部分，不是该形式时 ok 为 false
+func HiddenName(attr string) (name string, ok bool) {
+	name, value, found := strings.Cut(attr, ":")
+	return name, found && name != "" && value != ""
+}
+
+// SetupHidden 由权威机构执行，为 name:value 形式的属性生成不公开的 pkx = hx^a
+func (pvgss *PVGSS) SetupHidden(pp *PublicParameter, sk *SecretKey, attrs []string) (*HiddenKeys, error) {
+	hk := &HiddenKeys{PkXs: make(map[string]*bn256.G1, len(attrs)), PkXsG2: make(map[string]*bn256.G2, len(attrs))}
+	sampler := sample.NewUniformRange(big.NewInt(1), pp.Order)
+	for _, x := range attrs {
+		if _, ok := HiddenName(x); !ok {
+			return nil, fmt.Errorf("hidden attribute %s is not of the form name:value", x)
+		}
+		if _, ok := pp.PkXs[x]; ok {
+			return nil, fmt.Errorf("attribute %s is already public", x)
+		}
+		r, _ := sampler.Sample()
+		ra := new(big.Int).Mul(r, sk.A)
+		ra.Mod(ra, pp.Order)
+		hk.PkXs[x] = pvgss.Ops.G1Base(ra)
+		hk.PkXsG2[x] = pvgss.Ops.G2Base(ra)
+	}
+	return hk, nil
+}
+
+// KeyGenHidden 与 KeyGen 相同，另为 hidden 中的每个 name:value 签发 Kx = pkx^t，存入 OSK.Hidden[name]
+func (pvgss *PVGSS) KeyGenHidden(pp *PublicParameter, hk *HiddenKeys, attributeSet, hidden []string) (*OSK, error) {
+	osk, t, err := pvgss.keyGen(pp, attributeSet)
+	if err != nil {
+		return nil, err
+	}
+	osk.Hidden = make(map[string][]*bn256.G2)
+	for _, x := range hidden {
+		pkx, ok := hk.PkXsG2[x]
+		if !ok {
+			return nil, fmt.Errorf("hidden attribute %s not in hidden keys", x)
+		}
+		name, _ := HiddenName(x)
+		osk.Hidden[name] = append(osk.Hidden[name], pvgss.Ops.G2Mul(pkx, t))
+	}
+	return osk, nil
+}
+
+// HidePolicy 由数据拥有者执行：把 msp 中属于 hk 的 name:value 行改写为 name，并为这些行生成盲化公钥。
+// 返回新的 MSP，原 msp 不变
+func (pvgss *PVGSS) HidePolicy(pp *PublicParameter, hk *HiddenKeys, msp *abe.MSP) (*abe.MSP, map[int]*HiddenRow, error) {
+	hidden := &abe.MSP{P: msp.P, Mat: msp.Mat, RowToAttrib: append([]string(nil), msp.RowToAttrib...)}
+	rows := make(map[int]*HiddenRow)
+	sampler := sample.NewUniformRange(big.NewInt(1), pp.Order)
+	for i, x := range msp.RowToAttrib {
+		pkx, ok := hk.PkXs[x]
+		if !ok {
+			if _, public := pp.PkXs[x]; !public {
+				return nil, nil, fmt.Errorf("attribute %s not in public parameters or hidden keys", x)
+			}
+			continue
+		}
+		u, _ := sampler.Sample()
+		rows[i] = &HiddenRow{
+			X: pvgss.Ops.G1Mul(pkx, u),
+			U: pvgss.Ops.G1Base(u),
+		}
+		hidden.RowToAttrib[i], _ = HiddenName(x)
+	}
+	return hidden, rows, nil
+}
+
+// shareHiddenRow 用 r 为隐藏行生成份额：T = X^r，Ci = bi·T^{-1}，E = U^r，以及 log_U E = log_X T 的证明
+func (pvgss *PVGSS) shareHiddenRow(bi *bn256.G1, row *HiddenRow, r *big.Int) (*CipherText, error) {
+	T := pvgss.Ops.G1Mul(row.X, r)
+	E := pvgss.Ops.G1Mul(row.U, r)
+	prf, err := DLEQ.ProofG1(hiddenRowCtx, r, row.U, E, row.X, T)
+	if err != nil {
+		return nil, err
+	}
+	pvgss.Ops.AddCounts(DLEQ.ProofG1Cost)
+	return &CipherText{Ci: new(bn256.G1).Add(bi, new(bn256.G1).Neg(T)), E: E, T: T, Prf: prf}, nil
+}
+
+// verifyHiddenRow 检查 log_U E = log_X T 的证明
+func (pvgss *PVGSS) verifyHiddenRow(c *CipherText, row *HiddenRow) bool {
+	if c.Ci == nil || c.E == nil || c.T == nil || row == nil || row.X == nil || row.U == nil {
+		return false
+	}
+	pvgss.Ops.AddCounts(DLEQ.VerifyG1Cost)
+	return DLEQ.VerifyG1(hiddenRowCtx, c.Prf, row.U, c.E, row.X, c.T)
+}
+
+// reconTildeHidden 是含隐藏取值行时的 reconTilde：先用 e(T, L) = e(E, Kx) 找出用户匹配的隐藏行，
+// 与用户持有的公开属性行一起选出线性无关的行子集，再计算 ˜R。匹配结果依赖具体密文，因此不使用计划缓存
+func (pvgss *PVGSS) reconTildeHidden(pp *PublicParameter, ct map[int]*CipherText, msp *abe.MSP, osk *OSK) (*bn256.GT, error) {
+	matched := make(map[int]*bn256.G2)
+	var candidates []int
+	for i, x := range msp.RowToAttrib {
+		c, ok := ct[i]
+		if !ok || c == nil {
+			continue
+		}
+		if c.T == nil {
+			if kx, held := osk.KXs[x]; held && kx != nil && osk.Versions[x] == pp.Versions[x] && c.Version == pp.Versions[x] {
+				matched[i] = kx
+				candidates = append(candidates, i)
+			}
+			continue
+		}
+		keys := osk.Hidden[x]
+		if len(keys) == 0 || c.E == nil {
+			continue
+		}
+		tag := pvgss.Ops.Pair(c.T, osk.L).String()
+		for _, kx := range keys {
+			if pvgss.Ops.Pair(c.E, kx).String() == tag {
+				matched[i] = kx
+				candidates = append(candidates, i)
+				break
+			}
+		}
+	}
+	rows, err := LSSS.IndependentRows(msp, candidates, pp.Order)
+	if err != nil {
+		return nil, fmt.Errorf("attribute set does not satisfy the policy: %w", err)
+	}
+	w, err := LSSS.Coefficients(msp, rows, pp.Order)
+	if err != nil {
+		return nil, fmt.Errorf("attribute set does not satisfy the policy: %w", err)
+	}
+
+	//匹配的隐藏行 ˜Ri = e(Ci, L)e(E, Kx)，公开属性行 ˜Ri = e(Ci, L)e(Ci', Kρ(i))
+	riPrime := make(map[int]*bn256.GT, len(rows))
+	coeffs := make(map[int]*big.Int, len(rows))
+	for _, j := range rows {
+		if w[j].Sign() == 0 {
+			continue
+		}
+		c := ct[j]
+		partner := c.CiPrime
+		if c.T != nil {
+			partner = c.E
+		}
+		left := pvgss.Ops.Pair(c.Ci, osk.L)
+		right := pvgss.Ops.Pair(partner, matched[j])
+		riPrime[j] = new(bn256.GT).Add(left, right)
+		coeffs[j] = w[j]
+	}
+	pvgss.Ops.Add(opcount.GTExp, len(riPrime))
+	return LSSS.Combine(riPrime, coeffs), nil
+}
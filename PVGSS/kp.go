@@ -0,0 +1,212 @@
+package PVGSS
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/AUKUS561/PVOABE/DLEQ"
+	"github.com/AUKUS561/PVOABE/LSSS"
+	"github.com/AUKUS561/PVOABE/opcount"
+	"github.com/fentec-project/bn256"
+	"github.com/fentec-project/gofe/abe"
+	"github.com/fentec-project/gofe/sample"
+)
+
+/*
+密钥策略（KP）模式
+
+访问结构放在密钥中，密文只标记属性集 S。沿用 CP 模式的 pp、sk = a 与 B = pk^s、C' = g^s：
+  - KPSetup 为每个属性 x 选取 vx←Zp，公开 Vx = g2^{vx}，{vx} 与 a 一起由云端保存（KPSecretKey）；
+  - KPKeyGen 用 LSSS 把 t 分享为 {λi}，对第 i 行选取 ri，Ki = g2^{λi}·Vρ(i)^{ri}、Ki' = g2^{ri}，
+    h^t 与 CP 模式相同，用于 PVOABE 的 DSK；
+  - 云端 KPShare 对 x ∈ S 计算 Cx = B^{vx}，KPSVerify 公开检查 e(B, g) = e(pk, C') 与 e(Cx, g) = e(B, Vx)；
+  - 第 i 行 ˜Ri = e(B, Ki)·e(Cρ(i), Ki')^{-1} = e(B, g2)^{λi}，按重构系数合并得 ˜R = e(B, g2)^t，
+    与 CP 模式的 e(B, L) 相同，之后的 R = ˜R^{1/a} 与 DLEQ 证明不变。
+
+与 CP 模式一样，访问控制由云端执行：云端本就能对任何密文算出 e(B, L)，DVerify 保证的是输出正确。
+Ki 中的 Vρ(i)^{ri} 使用户无法在不持有 Cρ(i) 的情况下剥离出 g2^{λi}。
+*/
+
+// KPParameter 是密钥策略模式的属性公钥 Vx = g2^{vx}
+type KPParameter struct {
+	Vs map[string]*bn256.G2
+}
+
+// KPSecretKey 是云端保存的 {vx}，用于 KPShare
+type KPSecretKey struct {
+	Vs map[string]*big.Int
+}
+
+// KPOSK 是密钥策略模式的外包密钥：访问结构与每行的 (Ki, Ki')
+type KPOSK struct {
+	Msp    *abe.MSP
+	K      map[int]*bn256.G2
+	KPrime map[int]*bn256.G2
+	Ht     *bn256.G1 //h^t 用于PVOABE
+}
+
+// KPCipherText 是云端为属性集 S 计算的密文分量 {Cx = B^{vx}}
+type KPCipherText struct {
+	B  *bn256.G1
+	Cx map[string]*bn256.G1
+}
+
+// Attrs 返回密文标记的属性集 S（已排序）
+func (ct *KPCipherText) Attrs() []string {
+	attrs := make([]string, 0, len(ct.Cx))
+	for x := range ct.Cx {
+		attrs = append(attrs, x)
+	}
+	sort.Strings(attrs)
+	return attrs
+}
+
+// KPSetup 为属性全集 U 生成 {Vx} 与云端的 {vx}
+func (pvgss *PVGSS) KPSetup(pp *PublicParameter, attributeUniverse []string) (*KPParameter, *KPSecretKey, error) {
+	kp := &KPParameter{Vs: make(map[string]*bn256.G2, len(attributeUniverse))}
+	ksk := &KPSecretKey{Vs: make(map[string]*big.Int, len(attributeUniverse))}
+	sampler := sample.NewUniformRange(big.NewInt(1), pp.Order)
+	for _, x := range attributeUniverse {
+		if _, ok := kp.Vs[x]; ok {
+			return nil, nil, fmt.Errorf("duplicate attribute %s", x)
+		}
+		v, err := sampler.Sample()
+		if err != nil {
+			return nil, nil, err
+		}
+		ksk.Vs[x] = v
+		kp.Vs[x] = pvgss.Ops.G2Base(v)
+	}
+	return kp, ksk, nil
+}
+
+// KPKeyGen 为访问结构 msp 签发外包密钥，msp 中的属性须在 kp 中
+func (pvgss *PVGSS) KPKeyGen(pp *PublicParameter, kp *KPParameter, msp *abe.MSP) (*KPOSK, error) {
+	if err := LSSS.Validate(msp); err != nil {
+		return nil, err
+	}
+	for _, x := range msp.RowToAttrib {
+		if _, ok := kp.Vs[x]; !ok {
+			return nil, fmt.Errorf("attribute %s not in key-policy parameters", x)
+		}
+	}
+	sampler := sample.NewUniformRange(big.NewInt(1), pp.Order)
+	t, err := sampler.Sample()
+	if err != nil {
+		return nil, err
+	}
+	lambda, err := LSSS.Share(msp, t, pp.Order)
+	if err != nil {
+		return nil, err
+	}
+
+	osk := &KPOSK{
+		Msp:    msp,
+		K:      make(map[int]*bn256.G2, len(lambda)),
+		KPrime: make(map[int]*bn256.G2, len(lambda)),
+		Ht:     pvgss.Ops.G1Mul(pp.H, t),
+	}
+	for i, l := range lambda {
+		r, err := sampler.Sample()
+		if err != nil {
+			return nil, err
+		}
+		//Ki = g2^{λi}·Vρ(i)^{ri}，Ki' = g2^{ri}
+		osk.K[i] = new(bn256.G2).Add(pvgss.Ops.G2Base(l), pvgss.Ops.G2Mul(kp.Vs[msp.RowToAttrib[i]], r))
+		osk.KPrime[i] = pvgss.Ops.G2Base(r)
+	}
+	return osk, nil
+}
+
+// KPShare 由云端执行：对属性集 attrs 中的每个 x 计算 Cx = B^{vx}
+func (pvgss *PVGSS) KPShare(pp *PublicParameter, ksk *KPSecretKey, b *bn256.G1, attrs []string) (*KPCipherText, error) {
+	if b == nil {
+		return nil, fmt.Errorf("nil B")
+	}
+	if len(attrs) == 0 {
+		return nil, fmt.Errorf("empty attribute set")
+	}
+	ct := &KPCipherText{B: b, Cx: make(map[string]*bn256.G1, len(attrs))}
+	for _, x := range attrs {
+		v, ok := ksk.Vs[x]
+		if !ok {
+			return nil, fmt.Errorf("attribute %s not in key-policy parameters", x)
+		}
+		ct.Cx[x] = pvgss.Ops.G1Mul(b, v)
+	}
+	return ct, nil
+}
+
+// KPSVerify 公开验证 KPShare 的输出：e(B, g) = e(pk, C')，且对每个 x，e(Cx, g) = e(B, Vx)
+func (pvgss *PVGSS) KPSVerify(pp *PublicParameter, kp *KPParameter, ct *KPCipherText, cprime *bn256.G2) bool {
+	if ct == nil || ct.B == nil || cprime == nil || len(ct.Cx) == 0 {
+		return false
+	}
+	g2 := new(bn256.G2).ScalarBaseMult(big.NewInt(1))
+	if pvgss.Ops.Pair(ct.B, g2).String() != pvgss.Ops.Pair(pp.Pk, cprime).String() {
+		return false
+	}
+	for x, cx := range ct.Cx {
+		vx, ok := kp.Vs[x]
+		if !ok || cx == nil {
+			return false
+		}
+		if pvgss.Ops.Pair(cx, g2).String() != pvgss.Ops.Pair(ct.B, vx).String() {
+			return false
+		}
+	}
+	return true
+}
+
+// KPRecon 与 Recon 相同，但访问结构取自密钥，属性集取自密文
+func (pvgss *PVGSS) KPRecon(pp *PublicParameter, ct *KPCipherText, osk *KPOSK, sk *SecretKey) (*bn256.GT, *DLEQ.Prfs, error) {
+	return pvgss.KPReconWithContext(pp, ct, osk, sk, nil)
+}
+
+// KPReconWithContext 与 ReconWithContext 相同，ctx 通常为密文属性集的摘要
+func (pvgss *PVGSS) KPReconWithContext(pp *PublicParameter, ct *KPCipherText, osk *KPOSK, sk *SecretKey, ctx []byte) (*bn256.GT, *DLEQ.Prfs, error) {
+	rPrime, err := pvgss.kpReconTilde(pp, ct, osk)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pvgss.proveTilde(pp, rPrime, sk, ctx)
+}
+
+func (pvgss *PVGSS) KPDVerify(pp *PublicParameter, ct *KPCipherText, osk *KPOSK, R *bn256.GT, proof *DLEQ.Prfs) bool {
+	return pvgss.KPDVerifyWithContext(pp, ct, osk, R, proof, nil)
+}
+
+// KPDVerifyWithContext 验证 KPReconWithContext 在同一 ctx 下生成的证明
+func (pvgss *PVGSS) KPDVerifyWithContext(pp *PublicParameter, ct *KPCipherText, osk *KPOSK, R *bn256.GT, proof *DLEQ.Prfs, ctx []byte) bool {
+	rPrime, err := pvgss.kpReconTilde(pp, ct, osk)
+	if err != nil {
+		return false
+	}
+	return pvgss.verifyTilde(pp, rPrime, R, proof, ctx)
+}
+
+// kpReconTilde 计算 ˜R = ∏_{i∈I} (e(B, Ki)·e(Cρ(i), Ki')^{-1})^{wi}，重构计划按 (密钥 MSP, S) 缓存
+func (pvgss *PVGSS) kpReconTilde(pp *PublicParameter, ct *KPCipherText, osk *KPOSK) (*bn256.GT, error) {
+	if ct == nil || ct.B == nil || osk == nil || osk.Msp == nil {
+		return nil, fmt.Errorf("nil input")
+	}
+	plan, err := pvgss.Plans.Plan(nil, osk.Msp, ct.Attrs(), pp.Order)
+	if err != nil {
+		return nil, fmt.Errorf("attribute set does not satisfy the policy: %w", err)
+	}
+
+	riPrime := make(map[int]*bn256.GT, len(plan.Rows))
+	for _, j := range plan.Rows {
+		cx := ct.Cx[osk.Msp.RowToAttrib[j]]
+		k, kPrime := osk.K[j], osk.KPrime[j]
+		if cx == nil || k == nil || kPrime == nil {
+			return nil, fmt.Errorf("missing key or ciphertext component for row %d", j)
+		}
+		left := pvgss.Ops.Pair(ct.B, k)
+		right := pvgss.Ops.Pair(new(bn256.G1).Neg(cx), kPrime)
+		riPrime[j] = new(bn256.GT).Add(left, right)
+	}
+	pvgss.Ops.Add(opcount.GTExp, len(plan.Rows))
+	return LSSS.ReconWithPlan(plan, riPrime)
+}
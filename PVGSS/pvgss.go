@@ -3,7 +3,6 @@ package PVGSS
 import (
 	"crypto/sha256"
 	"fmt"
-	"log"
 	"math/big"
 
 	//"strings"
@@ -12,6 +11,8 @@ import (
 
 	"github.com/AUKUS561/PVOABE/DLEQ"
 	"github.com/AUKUS561/PVOABE/LSSS"
+	"github.com/AUKUS561/PVOABE/opcount"
+	"github.com/AUKUS561/PVOABE/policy"
 	"github.com/fentec-project/bn256"
 	"github.com/fentec-project/gofe/abe"
 	"github.com/fentec-project/gofe/sample"
@@ -27,6 +28,8 @@ type PublicParameter struct {
 	PkXs   map[string]*bn256.G1 //{Pkxs}
 	PkXsG2 map[string]*bn256.G2 //{PkxsG2}
 	Order  *big.Int             //群的阶
+	//属性版本号，RevokeAttribute 每次加一；缺省为 0
+	Versions map[string]int
 }
 
 type SecretKey struct {
@@ -34,11 +37,13 @@ type SecretKey struct {
 }
 
 type PVGSS struct {
-	P *big.Int
+	P     *big.Int
+	Plans *LSSS.PlanCache  //按策略摘要与属性集合缓存的重构计划，Recon/DVerify 共用
+	Ops   *opcount.Counter //运算计数，nil 表示不计数
 }
 
 func NewPVGSS() *PVGSS {
-	return &PVGSS{P: bn256.Order}
+	return &PVGSS{P: bn256.Order, Plans: LSSS.NewPlanCache()}
 }
 
 // (SK, PP) ← PVGSS.Setup(1κ, U)
@@ -53,11 +58,11 @@ func (pvgss *PVGSS) Setup(attributeUniverse []string) (*PublicParameter, *Secret
 	//β←Zp, h = g^β
 	sampler := sample.NewUniformRange(big.NewInt(1), pvgss.P)
 	beta, _ := sampler.Sample()
-	h := new(bn256.G1).ScalarMult(g, beta)
+	h := pvgss.Ops.G1Mul(g, beta)
 	//h2 := new(bn256.G2).ScalarMult(g2, beta)
 	//a←Zp, pk = h^a
 	a, _ := sampler.Sample()
-	pk := new(bn256.G1).ScalarMult(h, a)
+	pk := pvgss.Ops.G1Mul(h, a)
 	//pk2 := new(bn256.G2).ScalarMult(h2, a)
 	//对每一个属性x in U , 生成hx, 计算pkx = hx^a
 	hxs := make(map[string]*bn256.G1)
@@ -70,10 +75,10 @@ func (pvgss *PVGSS) Setup(attributeUniverse []string) (*PublicParameter, *Secret
 	for i := 0; i < len(attributeUniverse); i++ {
 		//hx := HashToG1(attributeUniverse[i])
 		r_i, _ := sampler.Sample()
-		hx := new(bn256.G1).ScalarBaseMult(r_i)
-		hxG2 := new(bn256.G2).ScalarBaseMult(r_i)
-		pkx := new(bn256.G1).ScalarMult(hx, a)
-		pkxG2 := new(bn256.G2).ScalarMult(hxG2, a)
+		hx := pvgss.Ops.G1Base(r_i)
+		hxG2 := pvgss.Ops.G2Base(r_i)
+		pkx := pvgss.Ops.G1Mul(hx, a)
+		pkxG2 := pvgss.Ops.G2Mul(hxG2, a)
 		hxs[attributeUniverse[i]] = hx
 		hxsG2[attributeUniverse[i]] = hxG2
 		pkxs[attributeUniverse[i]] = pkx
@@ -104,143 +109,267 @@ type OSK struct {
 	KXs map[string]*bn256.G2
 	//Lprime *bn256.G1
 	Ht *bn256.G1 //h^t 用于PVOABE
+	ID string    //密钥标识，由 PVOABE 的吊销机制使用，PVGSS 本身不使用
+	//KXs[x] 对应的属性版本，与 PP.Versions[x] 不一致的属性在重构时视为未持有
+	Versions map[string]int
+	//隐藏取值属性 name:value 的 Kx，按属性名分组且不记录取值，见 hidden.go
+	Hidden map[string][]*bn256.G2
 }
 
 // OSK ← PVGSS.KeyGen(Su)
 // 输入用户属性集Su,输入格式为"清华 北大 博士 硕士"，属性之间用空格分开
+// 若属性全集中含有否定属性 not_x（见 policy.WithNegations），则对用户不持有的每个 x 额外签发 not_x
 func (pvgss *PVGSS) KeyGen(pp *PublicParameter, attributeSet []string) (*OSK, error) {
+	osk, _, err := pvgss.keyGen(pp, attributeSet)
+	return osk, err
+}
+
+// keyGen 是 KeyGen 的实现，另外返回 t 供 KeyGenHidden 签发隐藏属性
+func (pvgss *PVGSS) keyGen(pp *PublicParameter, attributeSet []string) (*OSK, *big.Int, error) {
+	attributeSet, err := withNegations(pp, attributeSet)
+	if err != nil {
+		return nil, nil, err
+	}
 	p := pp.Order //群的阶p
 	//t←Zp,L=g^t
 	sampler := sample.NewUniformRange(big.NewInt(1), p)
 	t, _ := sampler.Sample()
-	l := new(bn256.G2).ScalarBaseMult(t) //L=g^t
+	l := pvgss.Ops.G2Base(t) //L=g^t
 	//lprime := new(bn256.G1).ScalarBaseMult(t)
-	ht := new(bn256.G1).ScalarMult(pp.H, t)
+	ht := pvgss.Ops.G1Mul(pp.H, t)
 	//{Kx = pkx^t}x∈Su
 	kxs := make(map[string]*bn256.G2)
+	versions := make(map[string]int)
 	//1.从用户属性集合attributeSet中分割出单个属性
 	//singleAtt := strings.Split(attributeSet, " ")
 	for i := 0; i < len(attributeSet); i++ {
 		//2.找到该属性对应的pkx
 		_, ok := pp.PkXs[attributeSet[i]]
 		if !ok {
-			return nil, fmt.Errorf("attribute %s not in public parameters", attributeSet[i])
+			return nil, nil, fmt.Errorf("attribute %s not in public parameters", attributeSet[i])
 		}
 		//3.计算Kx=pkx^t
-		kxs[attributeSet[i]] = new(bn256.G2).ScalarMult(pp.PkXsG2[attributeSet[i]], t)
+		kxs[attributeSet[i]] = pvgss.Ops.G2Mul(pp.PkXsG2[attributeSet[i]], t)
+		versions[attributeSet[i]] = pp.Versions[attributeSet[i]]
 	}
 
-	return &OSK{L: l, KXs: kxs, Ht: ht}, nil
+	return &OSK{L: l, KXs: kxs, Ht: ht, Versions: versions}, t, nil
+}
+
+// withNegations 把权威机构签发的否定属性补充进用户属性集
+// 用户不能自行申请 not_x：若同时持有 x 与 not_x 则报错
+func withNegations(pp *PublicParameter, attributeSet []string) ([]string, error) {
+	held := make(map[string]bool, len(attributeSet))
+	for _, x := range attributeSet {
+		held[x] = true
+	}
+	out := append([]string(nil), attributeSet...)
+	for x := range pp.PkXs {
+		if !policy.IsNegated(x) {
+			continue
+		}
+		base := x[len(policy.NegationPrefix):]
+		if held[base] {
+			if held[x] {
+				return nil, fmt.Errorf("attribute set holds both %s and %s", base, x)
+			}
+			continue
+		}
+		if !held[x] {
+			out = append(out, x)
+		}
+	}
+	return out, nil
 }
 
 type CipherText struct {
 	Ci      *bn256.G1 //Ci
 	CiPrime *bn256.G1 //Ci'
 	//CiPrime2 *bn256.G2 //
+	Version int //生成或最近一次 UpdateShares 时属性 ρ(i) 的版本
+	//隐藏取值的行没有 Ci'，另有 E = g^{u·ri}、匹配标签 T = pkx^{u·ri} 与 log_U E = log_X T 的证明，普通行为 nil，见 hidden.go
+	E   *bn256.G1
+	T   *bn256.G1
+	Prf *DLEQ.PrfsG1
 }
 
 // Ci, Ci'} ← PVGSS.Share(B, τ)
 func (pvgss *PVGSS) Share(pp *PublicParameter, b *bn256.G1, msp *abe.MSP) (map[int]*CipherText, error) {
+	return pvgss.ShareHidden(pp, b, msp, nil)
+}
+
+// ShareHidden 与 Share 相同，rows 中给出的行为隐藏取值的行，用数据拥有者提供的盲化公钥 pkx^u 代替 pkρ(i)
+func (pvgss *PVGSS) ShareHidden(pp *PublicParameter, b *bn256.G1, msp *abe.MSP, rows map[int]*HiddenRow) (map[int]*CipherText, error) {
 	p := pp.Order
+	if err := LSSS.Validate(msp); err != nil {
+		return nil, fmt.Errorf("invalid access structure: %w", err)
+	}
 	sampler := sample.NewUniformRange(big.NewInt(1), p)
-	// {lambda_i} <- LSSS.Share(s, τ)
-	lambdaI, _ := LSSS.Share(msp, big.NewInt(1), p)
+	// {bi = b^lambda_i} <- LSSS.Share(1, τ) 在指数上执行
+	bis, err := LSSS.ShareInExponent(msp, b, big.NewInt(1), p)
+	if err != nil {
+		return nil, err
+	}
+	pvgss.Ops.Add(opcount.G1Mul, len(bis))
 	shares := make(map[int]*CipherText)
-	for i, lambda := range lambdaI {
-		//bi=b^lambdai
-		bi := new(bn256.G1).ScalarMult(b, lambda)
+	for i, bi := range bis {
 		//ri<-Zp
 		ri, _ := sampler.Sample()
+		if row, ok := rows[i]; ok {
+			if shares[i], err = pvgss.shareHiddenRow(bi, row, ri); err != nil {
+				return nil, err
+			}
+			continue
+		}
 		attri := msp.RowToAttrib[i]
-		pki := pp.PkXs[attri]
+		pki, ok := pp.PkXs[attri]
+		if !ok {
+			return nil, fmt.Errorf("attribute %s not in public parameters", attri)
+		}
 		//-ri
 		negRi := new(big.Int).Neg(ri)
 		negRi = negRi.Mod(negRi, p)
 		//pki^-ri
-		part := new(bn256.G1).ScalarMult(pki, negRi)
+		part := pvgss.Ops.G1Mul(pki, negRi)
 		//ci = bi*pki^-ri
 		ci := new(bn256.G1).Add(bi, part)
 		//ci'=g^ri
 		//ciprime := new(bn256.G1).ScalarBaseMult(ri)
-		ciprime := new(bn256.G1).ScalarBaseMult(ri)
-		shares[i] = &CipherText{Ci: ci, CiPrime: ciprime}
+		ciprime := pvgss.Ops.G1Base(ri)
+		shares[i] = &CipherText{Ci: ci, CiPrime: ciprime, Version: pp.Versions[attri]}
 	}
 	return shares, nil
 }
 
 // 0/1 ← PVGSS.SVerify({Ci, Ci'}, C', τ )
 func (pvgss *PVGSS) SVerify(pp *PublicParameter, ct map[int]*CipherText, cprime *bn256.G2, msp *abe.MSP) bool {
+	return pvgss.SVerifyHidden(pp, ct, cprime, msp, nil)
+}
+
+// SVerifyHidden 与 SVerify 相同，隐藏取值的行先验证 log_U E = log_X T 的证明，
+// 再以 Ai = e(Ci·T, g) 参与重构，保证持有该取值的用户能匹配并重构
+func (pvgss *PVGSS) SVerifyHidden(pp *PublicParameter, ct map[int]*CipherText, cprime *bn256.G2, msp *abe.MSP, rows map[int]*HiddenRow) bool {
 	p := pp.Order
 	//∀i ∈ [1, l] : Ai = e(Ci, g)e(pkρ(i), Ci')
 	g2 := new(bn256.G2).ScalarBaseMult(big.NewInt(1)) //生成一个G2生成元g2专门用于配对
 	Ais := make(map[int]*bn256.GT)
 	for i, v := range ct {
-		part1 := bn256.Pair(v.Ci, g2)
-		part2 := bn256.Pair(v.CiPrime, pp.PkXsG2[msp.RowToAttrib[i]])
+		if i < 0 || i >= len(msp.RowToAttrib) {
+			return false
+		}
+		if row, hidden := rows[i]; hidden {
+			if !pvgss.verifyHiddenRow(v, row) {
+				return false
+			}
+			Ais[i] = pvgss.Ops.Pair(new(bn256.G1).Add(v.Ci, v.T), g2)
+			continue
+		}
+		pkx, ok := pp.PkXsG2[msp.RowToAttrib[i]]
+		if !ok || v.T != nil || v.E != nil || v.Prf != nil {
+			return false
+		}
+		part1 := pvgss.Ops.Pair(v.Ci, g2)
+		part2 := pvgss.Ops.Pair(v.CiPrime, pkx)
 		Ais[i] = new(bn256.GT).Add(part1, part2)
 	}
 	//验证LSSS.Recon({Ai}i∈[1,l], τ ) ?= e(pk, C′)
-	left, _ := LSSS.Recon(msp, Ais, p)
-	right := bn256.Pair(pp.Pk, cprime)
+	left, err := LSSS.Recon(msp, Ais, p)
+	if err != nil {
+		return false
+	}
+	pvgss.Ops.Add(opcount.GTExp, len(Ais))
+	right := pvgss.Ops.Pair(pp.Pk, cprime)
 
 	return left.String() == right.String()
 }
 
 // (R, π) ← PVGSS.Recon({Ci, Ci'}, τ, OSK, sk)
 func (pvgss *PVGSS) Recon(pp *PublicParameter, ct map[int]*CipherText, msp *abe.MSP, osk *OSK, sk *SecretKey) (*bn256.GT, *DLEQ.Prfs, error) {
-	p := pp.Order
-	riPrime := make(map[int]*bn256.GT)
-
-	//I = {i : ρ(i) ∈ Su}
-	//遍历Kx（对应用户属性集）的属性索引i，同时遍历策略矩阵msp每一行对应的属性v，若i=v
-	//则找到这一行的密文ci与ci'，执行∀i ∈ I : Ri~ = e(Ci, L)e(Ci', Kρ(i))
-	for i, _ := range osk.KXs {
-		for j, v := range msp.RowToAttrib {
-			if i == v {
-				left := bn256.Pair(ct[j].Ci, osk.L)
-				right := bn256.Pair(ct[j].CiPrime, osk.KXs[i])
-				riPrime[j] = new(bn256.GT).Add(left, right)
-			}
-		}
-	}
-	//R ← LSSS.Recon({ ˜Ri}i∈I , τ )
-	rPrime, err := LSSS.Recon(msp, riPrime, p)
+	return pvgss.ReconWithContext(pp, ct, msp, osk, sk, nil)
+}
+
+// ReconWithContext 与 Recon 相同，但 DLEQ 证明绑定上下文 ctx（通常为策略摘要），
+// 同一个 π 不能被挪用到另一条策略的密文上
+func (pvgss *PVGSS) ReconWithContext(pp *PublicParameter, ct map[int]*CipherText, msp *abe.MSP, osk *OSK, sk *SecretKey, ctx []byte) (*bn256.GT, *DLEQ.Prfs, error) {
+	//R~ ← LSSS.Recon({ ˜Ri}i∈I , τ )
+	rPrime, err := pvgss.reconTilde(pp, ct, msp, osk, ctx)
 	if err != nil {
-		log.Fatalf("Fail to execute LSSSRecon ,Error: %v", err)
+		return nil, nil, err
 	}
+	return pvgss.proveTilde(pp, rPrime, sk, ctx)
+}
+
+// proveTilde 由 ˜R 计算 R = ˜R^{1/sk} 与证明 π，CP 与 KP 的 Recon 共用
+func (pvgss *PVGSS) proveTilde(pp *PublicParameter, rPrime *bn256.GT, sk *SecretKey, ctx []byte) (*bn256.GT, *DLEQ.Prfs, error) {
 	//R = ˜R^1/sk
-	skInv := new(big.Int).ModInverse(sk.A, p)
-	r := new(bn256.GT).ScalarMult(rPrime, skInv)
+	skInv := new(big.Int).ModInverse(sk.A, pp.Order)
+	r := pvgss.Ops.GTExp(rPrime, skInv)
 	//π ← DLEQ.Proof(sk, R, ˜R, h, pk)
-	pi, err := DLEQ.Proof(sk.A, r, rPrime, pp.H, pp.Pk)
+	pi, err := DLEQ.ProofWithContext(ctx, sk.A, r, rPrime, pp.H, pp.Pk)
 	if err != nil {
-		log.Fatalf("fail to generate proof")
+		return nil, nil, fmt.Errorf("fail to generate proof: %w", err)
 	}
+	pvgss.Ops.AddCounts(DLEQ.ProofCost)
 	return r, pi, nil
 }
 
+// verifyTilde 验证 R 与 π 对应于重新计算的 ˜R，CP 与 KP 的 DVerify 共用
+func (pvgss *PVGSS) verifyTilde(pp *PublicParameter, rPrime, R *bn256.GT, proof *DLEQ.Prfs, ctx []byte) bool {
+	pvgss.Ops.AddCounts(DLEQ.VerifyCost)
+	return DLEQ.VerifyWithContext(ctx, proof, R, rPrime, pp.H, pp.Pk)
+}
+
 func (pvgss *PVGSS) DVerify(pp *PublicParameter, ct map[int]*CipherText, msp *abe.MSP, osk *OSK, R *bn256.GT, proof *DLEQ.Prfs) bool {
-	p := pp.Order
-	riPrime := make(map[int]*bn256.GT)
-
-	//I = {i : ρ(i) ∈ Su}
-	//遍历Kx（对应用户属性集）的属性索引i，同时遍历策略矩阵msp每一行对应的属性v，若i=v
-	//则找到这一行的密文ci与ci'，执行∀i ∈ I : Ri~ = e(Ci, L)e(Ci', Kρ(i))
-	for i, _ := range osk.KXs {
-		for j, v := range msp.RowToAttrib {
-			if i == v {
-				left := bn256.Pair(ct[j].Ci, osk.L)
-				right := bn256.Pair(ct[j].CiPrime, osk.KXs[i])
-				riPrime[j] = new(bn256.GT).Add(left, right)
-			}
+	return pvgss.DVerifyWithContext(pp, ct, msp, osk, R, proof, nil)
+}
+
+// DVerifyWithContext 验证 ReconWithContext 在同一 ctx 下生成的证明
+func (pvgss *PVGSS) DVerifyWithContext(pp *PublicParameter, ct map[int]*CipherText, msp *abe.MSP, osk *OSK, R *bn256.GT, proof *DLEQ.Prfs, ctx []byte) bool {
+	rPrime, err := pvgss.reconTilde(pp, ct, msp, osk, ctx)
+	if err != nil {
+		return false
+	}
+	return pvgss.verifyTilde(pp, rPrime, R, proof, ctx)
+}
+
+// reconTilde 计算 ˜R = ∏_{i∈I} (e(Ci, L)e(Ci', Kρ(i)))^{wi}
+// I 与 {wi} 取自重构计划，计划按 (策略摘要 ctx, Su) 缓存，同一策略的多个密文只做一次高斯消元，
+// 且只对系数非零的行计算配对。ctx 为空时缓存键退回 MSPDigest(msp)；
+// ctx 由调用方提供，不一定对应 msp，PlanCache 命中时会先在 msp 上核对计划。
+// 版本落后于 PP 的属性（已被吊销或尚未应用 KeyUpdate）不参与重构。含隐藏取值的行时改用 reconTildeHidden
+func (pvgss *PVGSS) reconTilde(pp *PublicParameter, ct map[int]*CipherText, msp *abe.MSP, osk *OSK, ctx []byte) (*bn256.GT, error) {
+	for _, c := range ct {
+		if c != nil && c.T != nil {
+			return pvgss.reconTildeHidden(pp, ct, msp, osk)
 		}
 	}
-	//R ← LSSS.Recon({ ˜Ri}i∈I , τ )
-	rPrime, err := LSSS.Recon(msp, riPrime, p)
+	attrs := make([]string, 0, len(osk.KXs))
+	for x := range osk.KXs {
+		if osk.Versions[x] == pp.Versions[x] {
+			attrs = append(attrs, x)
+		}
+	}
+	plan, err := pvgss.Plans.Plan(ctx, msp, attrs, pp.Order)
 	if err != nil {
-		log.Fatalf("Error: %v", err)
+		return nil, fmt.Errorf("attribute set does not satisfy the policy: %w", err)
+	}
+
+	//∀i ∈ I : Ri~ = e(Ci, L)e(Ci', Kρ(i))
+	riPrime := make(map[int]*bn256.GT, len(plan.Rows))
+	for _, j := range plan.Rows {
+		c, ok := ct[j]
+		if !ok || c == nil {
+			return nil, fmt.Errorf("missing share for row %d", j)
+		}
+		if x := msp.RowToAttrib[j]; c.Version != pp.Versions[x] {
+			return nil, fmt.Errorf("share for row %d is at version %d of %s, current version is %d", j, c.Version, x, pp.Versions[x])
+		}
+		left := pvgss.Ops.Pair(c.Ci, osk.L)
+		right := pvgss.Ops.Pair(c.CiPrime, osk.KXs[msp.RowToAttrib[j]])
+		riPrime[j] = new(bn256.GT).Add(left, right)
 	}
-	return DLEQ.Verify(proof, R, rPrime, pp.H, pp.Pk)
+	pvgss.Ops.Add(opcount.GTExp, len(plan.Rows))
+	return LSSS.ReconWithPlan(plan, riPrime)
 }
 
 // HashToG1函数实现将一个属性x映射到G1群上的一个点
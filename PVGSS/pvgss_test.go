@@ -3,8 +3,12 @@ package PVGSS
 import (
 	"math/big"
 	"strconv"
+	"strings"
 	"testing"
 
+	"github.com/AUKUS561/PVOABE/opcount"
+	"github.com/AUKUS561/PVOABE/policy"
+	"github.com/AUKUS561/PVOABE/size"
 	"github.com/fentec-project/bn256"
 	"github.com/fentec-project/gofe/abe"
 	"github.com/fentec-project/gofe/sample"
@@ -98,7 +102,7 @@ func TestShare(t *testing.T) {
 	s, _ := sampler.Sample()
 	B := new(bn256.G1).ScalarMult(pp.Pk, s) //B=pk^s
 	//policy := "教授 AND (海南大学 OR 博士)"
-	policy := "A1 AND (A2 OR A3)"
+	policy := "Attr1 AND (Attr2 OR Attr3)"
 	msp, _ := abe.BooleanToMSP(policy, false)
 	shareResult, err := pvgss.Share(pp, B, msp)
 	if err != nil {
@@ -128,7 +132,7 @@ func TestSVerify(t *testing.T) {
 	B := new(bn256.G1).ScalarMult(pp.Pk, s)   //B=pk^s
 	Cprime := new(bn256.G2).ScalarBaseMult(s) //C'
 	//policy := "教授 AND (海南大学 OR 博士)"
-	policy := "A1 AND (A2 OR A3)"
+	policy := "Attr1 AND (Attr2 OR Attr3)"
 	msp, _ := abe.BooleanToMSP(policy, false)
 	shareResult, err := pvgss.Share(pp, B, msp)
 	if err != nil {
@@ -194,3 +198,529 @@ func TestAll(t *testing.T) {
 	finalResult := pvgss.DVerify(pp, shareResult, msp, osk, R, proof)
 	t.Logf("DVerify Result :%v", finalResult)
 }
+
+func TestNegatedAttributes(t *testing.T) {
+	pvgss := NewPVGSS()
+	pp, sk, err := pvgss.Setup(policy.WithNegations([]string{"Employee", "Contractor", "Intern"}))
+	require.NoError(t, err)
+
+	// 权威机构为用户不持有的属性签发 not_x
+	employee, err := pvgss.KeyGen(pp, []string{"Employee"})
+	require.NoError(t, err)
+	require.Contains(t, employee.KXs, "not_Contractor")
+	require.Contains(t, employee.KXs, "not_Intern")
+	require.NotContains(t, employee.KXs, "not_Employee")
+	contractor, err := pvgss.KeyGen(pp, []string{"Employee", "Contractor"})
+	require.NoError(t, err)
+	require.NotContains(t, contractor.KXs, "not_Contractor")
+	_, err = pvgss.KeyGen(pp, []string{"Contractor", "not_Contractor"})
+	require.Error(t, err, "a user cannot hold both x and not_x")
+
+	msp, err := policy.MustParse("Employee AND NOT Contractor").ToMSP()
+	require.NoError(t, err)
+	s, _ := sample.NewUniformRange(big.NewInt(1), pp.Order).Sample()
+	B := new(bn256.G1).ScalarMult(pp.Pk, s)
+	Cprime := new(bn256.G2).ScalarBaseMult(s)
+	shares, err := pvgss.Share(pp, B, msp)
+	require.NoError(t, err)
+	require.True(t, pvgss.SVerify(pp, shares, Cprime, msp))
+
+	R, proof, err := pvgss.Recon(pp, shares, msp, employee, sk)
+	require.NoError(t, err)
+	require.True(t, pvgss.DVerify(pp, shares, msp, employee, R, proof))
+
+	_, _, err = pvgss.Recon(pp, shares, msp, contractor, sk)
+	require.Error(t, err, "a contractor must not satisfy Employee AND NOT Contractor")
+
+	// 未启用否定属性的全集无法分发含 NOT 的策略
+	plain, _, err := pvgss.Setup([]string{"Employee", "Contractor"})
+	require.NoError(t, err)
+	_, err = pvgss.Share(plain, B, msp)
+	require.Error(t, err)
+}
+
+func TestReconPlanReuse(t *testing.T) {
+	pvgss := NewPVGSS()
+	var attributeUniverse []string
+	for i := 1; i <= 10; i++ {
+		attributeUniverse = append(attributeUniverse, "Attr"+strconv.Itoa(i))
+	}
+	pp, sk, err := pvgss.Setup(attributeUniverse)
+	require.NoError(t, err)
+	osk, err := pvgss.KeyGen(pp, []string{"Attr1", "Attr2", "Attr3"})
+	require.NoError(t, err)
+	msp, err := abe.BooleanToMSP("(Attr1 AND Attr2) OR (Attr3 AND Attr4)", false)
+	require.NoError(t, err)
+
+	sampler := sample.NewUniformRange(big.NewInt(1), pp.Order)
+	for k := 0; k < 3; k++ {
+		s, _ := sampler.Sample()
+		B := new(bn256.G1).ScalarMult(pp.Pk, s)
+		shares, err := pvgss.Share(pp, B, msp)
+		require.NoError(t, err)
+		R, proof, err := pvgss.Recon(pp, shares, msp, osk, sk)
+		require.NoError(t, err)
+		require.True(t, pvgss.DVerify(pp, shares, msp, osk, R, proof))
+	}
+	// 三个密文共享策略和属性集合，只需要一个重构计划
+	require.Equal(t, 1, pvgss.Plans.Len())
+
+	// 带策略摘要时按摘要缓存，同一摘要的后续请求不再重新哈希 MSP
+	digest := []byte("(Attr1 AND Attr2) OR (Attr3 AND Attr4)")
+	for k := 0; k < 2; k++ {
+		s, _ := sampler.Sample()
+		shares, err := pvgss.Share(pp, new(bn256.G1).ScalarMult(pp.Pk, s), msp)
+		require.NoError(t, err)
+		R, proof, err := pvgss.ReconWithContext(pp, shares, msp, osk, sk, digest)
+		require.NoError(t, err)
+		require.True(t, pvgss.DVerifyWithContext(pp, shares, msp, osk, R, proof, digest))
+	}
+	require.Equal(t, 2, pvgss.Plans.Len())
+
+	invalid := &abe.MSP{Mat: msp.Mat, RowToAttrib: []string{"Attr1", "", "Attr3", "Attr4"}}
+	_, err = pvgss.Share(pp, pp.Pk, invalid)
+	require.Error(t, err)
+}
+
+func TestRepeatedAttributes(t *testing.T) {
+	pvgss := NewPVGSS()
+	pp, sk, err := pvgss.Setup([]string{"A", "B", "C", "D", "E", "F"})
+	require.NoError(t, err)
+	// A 出现在五行中
+	msp, err := abe.BooleanToMSP("(A AND B) OR (A AND C) OR (A AND D) OR (A AND E) OR (A AND F)", false)
+	require.NoError(t, err)
+	countA := 0
+	for _, attr := range msp.RowToAttrib {
+		if attr == "A" {
+			countA++
+		}
+	}
+	require.Equal(t, 5, countA)
+
+	sampler := sample.NewUniformRange(big.NewInt(1), pp.Order)
+	s, _ := sampler.Sample()
+	B := new(bn256.G1).ScalarMult(pp.Pk, s)
+	Cprime := new(bn256.G2).ScalarBaseMult(s)
+	shares, err := pvgss.Share(pp, B, msp)
+	require.NoError(t, err)
+	require.True(t, pvgss.SVerify(pp, shares, Cprime, msp))
+	g2 := new(bn256.G2).ScalarBaseMult(big.NewInt(1))
+
+	for _, attrs := range [][]string{
+		{"A", "B", "C", "D", "E", "F"},
+		{"A", "F"},
+		{"A", "C", "E"},
+	} {
+		osk, err := pvgss.KeyGen(pp, attrs)
+		require.NoError(t, err)
+		// 只需两行（某一行 A 与一行 B..F）即可重构，冗余行不参与配对
+		plan, err := pvgss.Plans.Plan(nil, msp, attrs, pp.Order)
+		require.NoError(t, err)
+		require.Len(t, plan.Rows, 2, "attrs %v", attrs)
+
+		R, proof, err := pvgss.Recon(pp, shares, msp, osk, sk)
+		require.NoError(t, err)
+		// B = pk^s，˜R = e(B, g)^t，R = ˜R^{1/a} = e(h^t, g)^s
+		expected := new(bn256.GT).ScalarMult(bn256.Pair(osk.Ht, g2), s)
+		require.Equal(t, expected.String(), R.String())
+		require.True(t, pvgss.DVerify(pp, shares, msp, osk, R, proof))
+	}
+
+	// 只有 A（出现五次）无法满足策略
+	osk, err := pvgss.KeyGen(pp, []string{"A"})
+	require.NoError(t, err)
+	_, _, err = pvgss.Recon(pp, shares, msp, osk, sk)
+	require.Error(t, err)
+}
+
+func TestOpCounts(t *testing.T) {
+	pvgss := NewPVGSS()
+	var universe []string
+	for i := 1; i <= 20; i++ {
+		universe = append(universe, "Attr"+strconv.Itoa(i))
+	}
+	pp, sk, err := pvgss.Setup(universe)
+	require.NoError(t, err)
+	msp, err := abe.BooleanToMSP(strings.Join(universe, " AND "), false)
+	require.NoError(t, err)
+	rows := len(msp.Mat)
+	require.Equal(t, 20, rows)
+
+	sampler := sample.NewUniformRange(big.NewInt(1), pp.Order)
+	s, _ := sampler.Sample()
+	B := new(bn256.G1).ScalarMult(pp.Pk, s)
+	Cprime := new(bn256.G2).ScalarBaseMult(s)
+	osk, err := pvgss.KeyGen(pp, universe)
+	require.NoError(t, err)
+
+	pvgss.Ops = opcount.New()
+	shares, err := pvgss.Share(pp, B, msp)
+	require.NoError(t, err)
+	// 每行 B^{λ_i}、pk_i^{-r_i} 与 g^{r_i}
+	require.Equal(t, int64(3*rows), pvgss.Ops.Snapshot().G1Mul)
+
+	// SVerify：每行两次配对，再加 e(pk, C')
+	pvgss.Ops.Reset()
+	require.True(t, pvgss.SVerify(pp, shares, Cprime, msp))
+	require.Equal(t, int64(2*rows+1), pvgss.Ops.Snapshot().Pairings)
+
+	// Recon：每个参与重构的行两次配对
+	pvgss.Ops.Reset()
+	R, proof, err := pvgss.Recon(pp, shares, msp, osk, sk)
+	require.NoError(t, err)
+	require.Equal(t, int64(2*rows), pvgss.Ops.Snapshot().Pairings)
+
+	// 与 opcount.PVGSS 中的解析公式一致
+	size := opcount.Size{Universe: len(universe), Attrs: len(universe), Rows: rows, Used: rows}
+	pvgss.Ops.Reset()
+	require.True(t, pvgss.DVerify(pp, shares, msp, osk, R, proof))
+	require.Equal(t, opcount.PVGSS["DVerify"](size), pvgss.Ops.Snapshot())
+	pvgss.Ops.Reset()
+	_, _, err = pvgss.Recon(pp, shares, msp, osk, sk)
+	require.NoError(t, err)
+	require.Equal(t, opcount.PVGSS["Recon"](size), pvgss.Ops.Snapshot())
+	pvgss.Ops.Reset()
+	_, err = pvgss.Share(pp, B, msp)
+	require.NoError(t, err)
+	require.Equal(t, opcount.PVGSS["Share"](size), pvgss.Ops.Snapshot())
+	pvgss.Ops.Reset()
+	require.True(t, pvgss.SVerify(pp, shares, Cprime, msp))
+	require.Equal(t, opcount.PVGSS["SVerify"](size), pvgss.Ops.Snapshot())
+	pvgss.Ops.Reset()
+	_, _, err = pvgss.Setup(universe)
+	require.NoError(t, err)
+	require.Equal(t, opcount.PVGSS["Setup"](size), pvgss.Ops.Snapshot())
+	pvgss.Ops.Reset()
+	_, err = pvgss.KeyGen(pp, universe)
+	require.NoError(t, err)
+	require.Equal(t, opcount.PVGSS["KeyGen"](size), pvgss.Ops.Snapshot())
+
+	// 关闭计数后结果不变
+	pvgss.Ops = nil
+	require.True(t, pvgss.SVerify(pp, shares, Cprime, msp))
+}
+
+func TestSizes(t *testing.T) {
+	pvgss := NewPVGSS()
+	pp, _, err := pvgss.Setup([]string{"A", "B", "C"})
+	require.NoError(t, err)
+	msp, err := abe.BooleanToMSP("A AND (B OR C)", false)
+	require.NoError(t, err)
+	shares, err := pvgss.Share(pp, pp.Pk, msp)
+	require.NoError(t, err)
+
+	r := SharesSize(shares)
+	require.Len(t, r.Components, len(msp.Mat))
+	require.Equal(t, "row 0", r.Components[0].Name)
+	require.Equal(t, size.Elements{G1: 2 * len(msp.Mat)}, r.Total())
+	require.Equal(t, len(shares[0].Ci.Marshal())+len(shares[0].CiPrime.Marshal()), shares[0].EncodedLen(size.Uncompressed))
+
+	osk, err := pvgss.KeyGen(pp, []string{"A", "B"})
+	require.NoError(t, err)
+	require.Equal(t, size.Elements{G1: 1, G2: 3}, osk.Size().Total())
+	require.Equal(t, 32+3*64, osk.EncodedLen(size.Compressed))
+}
+
+func TestAttributeRevocation(t *testing.T) {
+	pvgss := NewPVGSS()
+	pp, sk, err := pvgss.Setup([]string{"Doctor", "Cardiology", "Nurse"})
+	require.NoError(t, err)
+	msp, err := abe.BooleanToMSP("Doctor AND (Cardiology OR Nurse)", false)
+	require.NoError(t, err)
+
+	sampler := sample.NewUniformRange(big.NewInt(1), pp.Order)
+	s, _ := sampler.Sample()
+	B := new(bn256.G1).ScalarMult(pp.Pk, s)
+	Cprime := new(bn256.G2).ScalarBaseMult(s)
+	shares, err := pvgss.Share(pp, B, msp)
+	require.NoError(t, err)
+	g2 := new(bn256.G2).ScalarBaseMult(big.NewInt(1))
+	expected := func(osk *OSK) string {
+		return new(bn256.GT).ScalarMult(bn256.Pair(osk.Ht, g2), s).String()
+	}
+
+	alice, err := pvgss.KeyGen(pp, []string{"Doctor", "Cardiology"})
+	require.NoError(t, err)
+	bob, err := pvgss.KeyGen(pp, []string{"Doctor", "Cardiology"})
+	require.NoError(t, err)
+	carol, err := pvgss.KeyGen(pp, []string{"Doctor", "Nurse"})
+	require.NoError(t, err)
+
+	// bob 离开 Cardiology：只升级该属性，alice 应用密钥更新，云端更新对应的行
+	upd, err := pvgss.RevokeAttribute(pp, sk, "Cardiology")
+	require.NoError(t, err)
+	require.Equal(t, 1, pp.Versions["Cardiology"])
+
+	ku, err := pvgss.KeyUpdate(upd, alice)
+	require.NoError(t, err)
+	require.NoError(t, pvgss.ApplyKeyUpdate(alice, ku))
+	require.Error(t, pvgss.ApplyKeyUpdate(alice, ku), "updates apply once")
+	_, err = pvgss.KeyUpdate(upd, carol)
+	require.Error(t, err, "carol does not hold Cardiology")
+
+	_, _, err = pvgss.Recon(pp, shares, msp, alice, sk)
+	require.Error(t, err, "shares must be updated first")
+	n, err := pvgss.UpdateShares(upd, shares, msp)
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	_, err = pvgss.UpdateShares(upd, shares, msp)
+	require.Error(t, err)
+	require.True(t, pvgss.SVerify(pp, shares, Cprime, msp))
+
+	for _, osk := range []*OSK{alice, carol} {
+		R, proof, err := pvgss.Recon(pp, shares, msp, osk, sk)
+		require.NoError(t, err)
+		require.Equal(t, expected(osk), R.String())
+		require.True(t, pvgss.DVerify(pp, shares, msp, osk, R, proof))
+	}
+
+	// bob 的 Cardiology 停留在版本 0，视为未持有，Doctor 单独不满足策略
+	_, _, err = pvgss.Recon(pp, shares, msp, bob, sk)
+	require.Error(t, err)
+	// 即使忽略版本号，旧的 Kx 也无法重构出正确的 R
+	bob.Versions["Cardiology"] = 1
+	R, _, err := pvgss.Recon(pp, shares, msp, bob, sk)
+	require.NoError(t, err)
+	require.NotEqual(t, expected(bob), R.String())
+
+	// 吊销后新签发的密钥与新生成的份额使用新版本
+	dave, err := pvgss.KeyGen(pp, []string{"Doctor", "Cardiology"})
+	require.NoError(t, err)
+	fresh, err := pvgss.Share(pp, B, msp)
+	require.NoError(t, err)
+	R, _, err = pvgss.Recon(pp, fresh, msp, dave, sk)
+	require.NoError(t, err)
+	require.Equal(t, expected(dave), R.String())
+}
+
+func TestDelegate(t *testing.T) {
+	pvgss := NewPVGSS()
+	pp, sk, err := pvgss.Setup(policy.WithNegations([]string{"Cardiology", "Physician", "HeadOfDept", "Contractor"}))
+	require.NoError(t, err)
+	parent, err := pvgss.KeyGen(pp, []string{"Cardiology", "Physician", "HeadOfDept"})
+	require.NoError(t, err)
+	parent.ID = "head"
+
+	sub, err := pvgss.Delegate(pp, parent, []string{"Cardiology", "Physician"})
+	require.NoError(t, err)
+	require.Empty(t, sub.ID, "the sub-key does not inherit the parent's identifier")
+	// not_Contractor 随子密钥保留，HeadOfDept 被去掉
+	require.Len(t, sub.KXs, 3)
+	require.Contains(t, sub.KXs, policy.Negate("Contractor"))
+	require.NotContains(t, sub.KXs, "HeadOfDept")
+	// t 被重新随机化，子密钥的各部分都与父密钥不同
+	require.NotEqual(t, parent.L.String(), sub.L.String())
+	require.NotEqual(t, parent.Ht.String(), sub.Ht.String())
+	require.NotEqual(t, parent.KXs["Cardiology"].String(), sub.KXs["Cardiology"].String())
+
+	_, err = pvgss.Delegate(pp, sub, []string{"HeadOfDept"})
+	require.Error(t, err, "cannot delegate attributes the parent does not hold")
+
+	msp, err := policy.MustParse("Cardiology AND Physician AND NOT Contractor").ToMSP()
+	require.NoError(t, err)
+	s := big.NewInt(12345)
+	B := new(bn256.G1).ScalarMult(pp.Pk, s)
+	shares, err := pvgss.Share(pp, B, msp)
+	require.NoError(t, err)
+	R, proof, err := pvgss.Recon(pp, shares, msp, sub, sk)
+	require.NoError(t, err)
+	g2 := new(bn256.G2).ScalarBaseMult(big.NewInt(1))
+	require.Equal(t, new(bn256.GT).ScalarMult(bn256.Pair(sub.Ht, g2), s).String(), R.String())
+	require.True(t, pvgss.DVerify(pp, shares, msp, sub, R, proof))
+}
+
+func TestHiddenPolicy(t *testing.T) {
+	pvgss := NewPVGSS()
+	pp, sk, err := pvgss.Setup([]string{"Doctor", "Nurse"})
+	require.NoError(t, err)
+	hk, err := pvgss.SetupHidden(pp, sk, []string{"Department:HIV-Clinic", "Department:Cardiology", "Ward:3"})
+	require.NoError(t, err)
+	_, err = pvgss.SetupHidden(pp, sk, []string{"Doctor"})
+	require.Error(t, err, "hidden attributes must be name:value")
+	require.NotContains(t, pp.PkXs, "Department:HIV-Clinic", "hidden keys stay out of the public parameters")
+
+	full, err := policy.MustParse("Doctor AND (Department:HIV-Clinic OR Ward:3)").ToMSP()
+	require.NoError(t, err)
+	msp, rows, err := pvgss.HidePolicy(pp, hk, full)
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	require.ElementsMatch(t, []string{"Doctor", "Department", "Ward"}, msp.RowToAttrib)
+	require.Contains(t, full.RowToAttrib, "Department:HIV-Clinic", "HidePolicy must not modify its input")
+
+	s := big.NewInt(424242)
+	B := new(bn256.G1).ScalarMult(pp.Pk, s)
+	Cprime := new(bn256.G2).ScalarBaseMult(s)
+	shares, err := pvgss.ShareHidden(pp, B, msp, rows)
+	require.NoError(t, err)
+	require.True(t, pvgss.SVerifyHidden(pp, shares, Cprime, msp, rows))
+	require.False(t, pvgss.SVerify(pp, shares, Cprime, msp), "hidden rows need their blinded keys")
+
+	// 云端换用另一个盲化公钥（即另一个取值）时验证失败
+	other, otherRows, err := pvgss.HidePolicy(pp, hk, full)
+	require.NoError(t, err)
+	require.False(t, pvgss.SVerifyHidden(pp, shares, Cprime, other, otherRows))
+	// 篡改 T 或换用另一行的证明都无法通过验证
+	tampered := make(map[int]*CipherText, len(shares))
+	for i, c := range shares {
+		cc := *c
+		tampered[i] = &cc
+	}
+	var hiddenIdx []int
+	for i := range rows {
+		hiddenIdx = append(hiddenIdx, i)
+	}
+	tampered[hiddenIdx[0]].Prf = shares[hiddenIdx[1]].Prf
+	require.False(t, pvgss.SVerifyHidden(pp, tampered, Cprime, msp, rows))
+	tampered[hiddenIdx[0]].Prf = shares[hiddenIdx[0]].Prf
+	tampered[hiddenIdx[0]].T = new(bn256.G1).Add(shares[hiddenIdx[0]].T, pp.G)
+	require.False(t, pvgss.SVerifyHidden(pp, tampered, Cprime, msp, rows))
+
+	// 隐藏行与其份额不含 G2 元素，旧的公开等值测试 e(X1, U2') = e(U1, X2') 无从计算；
+	// 用公开的 G2 元素 g2、C' 代替时，同一取值的两行（来自两条密文）也无法被识别
+	otherShares, err := pvgss.ShareHidden(pp, B, other, otherRows)
+	require.NoError(t, err)
+	g2 := new(bn256.G2).ScalarBaseMult(big.NewInt(1))
+	Cprime2 := new(bn256.G2).ScalarBaseMult(big.NewInt(777))
+	for _, i := range hiddenIdx {
+		require.Zero(t, size.Breakdown("row", rows[i]).Total().G2)
+		require.Zero(t, shares[i].Size().Total().G2)
+		a, b := rows[i], otherRows[i]
+		for _, q := range [][2]*bn256.G2{{g2, g2}, {Cprime, Cprime2}, {g2, Cprime2}} {
+			require.NotEqual(t, bn256.Pair(a.X, q[1]).String(), bn256.Pair(b.X, q[0]).String())
+			require.NotEqual(t, bn256.Pair(shares[i].T, q[1]).String(), bn256.Pair(otherShares[i].T, q[0]).String())
+		}
+	}
+
+	for _, tc := range []struct {
+		attrs, hidden []string
+		ok            bool
+	}{
+		{[]string{"Doctor"}, []string{"Department:HIV-Clinic"}, true},
+		{[]string{"Doctor"}, []string{"Department:Cardiology", "Ward:3"}, true},
+		{[]string{"Doctor"}, []string{"Department:Cardiology"}, false},
+		{[]string{"Nurse"}, []string{"Department:HIV-Clinic", "Ward:3"}, false},
+	} {
+		osk, err := pvgss.KeyGenHidden(pp, hk, tc.attrs, tc.hidden)
+		require.NoError(t, err)
+		R, proof, err := pvgss.Recon(pp, shares, msp, osk, sk)
+		if !tc.ok {
+			require.Error(t, err, "%v %v", tc.attrs, tc.hidden)
+			continue
+		}
+		require.NoError(t, err, "%v %v", tc.attrs, tc.hidden)
+		require.Equal(t, new(bn256.GT).ScalarMult(bn256.Pair(osk.Ht, g2), s).String(), R.String())
+		require.True(t, pvgss.DVerify(pp, shares, msp, osk, R, proof))
+	}
+}
+
+func TestKeyPolicy(t *testing.T) {
+	pvgss := NewPVGSS()
+	universe := []string{"Source:firewall", "Source:ids", "Severity:high", "Severity:low", "Host:db"}
+	pp, sk, err := pvgss.Setup(universe)
+	require.NoError(t, err)
+	kp, ksk, err := pvgss.KPSetup(pp, universe)
+	require.NoError(t, err)
+
+	s := big.NewInt(777001)
+	B := new(bn256.G1).ScalarMult(pp.Pk, s)
+	Cprime := new(bn256.G2).ScalarBaseMult(s)
+	ct, err := pvgss.KPShare(pp, ksk, B, []string{"Source:ids", "Severity:high", "Host:db"})
+	require.NoError(t, err)
+	require.True(t, pvgss.KPSVerify(pp, kp, ct, Cprime))
+	require.Equal(t, []string{"Host:db", "Severity:high", "Source:ids"}, ct.Attrs())
+
+	_, err = pvgss.KPShare(pp, ksk, B, []string{"Unknown"})
+	require.Error(t, err)
+
+	g2 := new(bn256.G2).ScalarBaseMult(big.NewInt(1))
+	for _, tc := range []struct {
+		policy string
+		ok     bool
+	}{
+		{"Severity:high AND (Source:firewall OR Source:ids)", true},
+		{"(Source:ids AND Host:db) OR (Source:firewall AND Host:db)", true},
+		{"Severity:low OR Source:firewall", false},
+		{"Severity:high AND Source:firewall", false},
+	} {
+		msp, err := policy.MustParse(tc.policy).ToMSP()
+		require.NoError(t, err)
+		osk, err := pvgss.KPKeyGen(pp, kp, msp)
+		require.NoError(t, err)
+		R, proof, err := pvgss.KPRecon(pp, ct, osk, sk)
+		if !tc.ok {
+			require.Error(t, err, tc.policy)
+			continue
+		}
+		require.NoError(t, err, tc.policy)
+		// ˜R = e(B, g)^t，R = ˜R^{1/a} = e(h^t, g)^s，与 CP 模式相同
+		require.Equal(t, new(bn256.GT).ScalarMult(bn256.Pair(osk.Ht, g2), s).String(), R.String())
+		require.True(t, pvgss.KPDVerify(pp, ct, osk, R, proof))
+		require.False(t, pvgss.KPDVerifyWithContext(pp, ct, osk, R, proof, []byte("other")))
+		require.False(t, pvgss.KPDVerify(pp, ct, osk, new(bn256.GT).Add(R, R), proof))
+	}
+
+	// 密钥策略中的属性须在 KP 参数中
+	msp, err := policy.MustParse("Source:ids AND Unknown").ToMSP()
+	require.NoError(t, err)
+	_, err = pvgss.KPKeyGen(pp, kp, msp)
+	require.Error(t, err)
+
+	// 篡改 Cx、换用别的 B 或 C' 都无法通过验证
+	tampered := &KPCipherText{B: ct.B, Cx: map[string]*bn256.G1{}}
+	for x, cx := range ct.Cx {
+		tampered.Cx[x] = cx
+	}
+	tampered.Cx["Host:db"] = new(bn256.G1).Add(ct.Cx["Host:db"], pp.G)
+	require.False(t, pvgss.KPSVerify(pp, kp, tampered, Cprime))
+	require.False(t, pvgss.KPSVerify(pp, kp, ct, new(bn256.G2).ScalarBaseMult(big.NewInt(5))))
+	forged, err := pvgss.KPShare(pp, ksk, new(bn256.G1).ScalarMult(pp.Pk, big.NewInt(5)), ct.Attrs())
+	require.NoError(t, err)
+	require.False(t, pvgss.KPSVerify(pp, kp, forged, Cprime))
+	require.True(t, pvgss.KPSVerify(pp, kp, forged, new(bn256.G2).ScalarBaseMult(big.NewInt(5))))
+
+	// 键策略中同一属性出现在多行
+	msp, err = policy.MustParse("(Host:db AND Severity:high) OR (Host:db AND Source:firewall)").ToMSP()
+	require.NoError(t, err)
+	osk, err := pvgss.KPKeyGen(pp, kp, msp)
+	require.NoError(t, err)
+	R, proof, err := pvgss.KPRecon(pp, ct, osk, sk)
+	require.NoError(t, err)
+	require.True(t, pvgss.KPDVerify(pp, ct, osk, R, proof))
+	require.Equal(t, 1+len(ct.Cx), ct.Size().Total().G1)
+	require.Equal(t, 2*len(msp.Mat), osk.Size().Total().G2)
+}
+
+func TestRerandomize(t *testing.T) {
+	pvgss := NewPVGSS()
+	pp, sk, err := pvgss.Setup([]string{"A", "B", "C"})
+	require.NoError(t, err)
+	msp, err := abe.BooleanToMSP("A AND (B OR C)", false)
+	require.NoError(t, err)
+	s, delta := big.NewInt(1234567), big.NewInt(7654321)
+	B := new(bn256.G1).ScalarMult(pp.Pk, s)
+	shares, err := pvgss.Share(pp, B, msp)
+	require.NoError(t, err)
+
+	d := new(bn256.G1).ScalarMult(pp.Pk, delta)
+	fresh, _, err := pvgss.Rerandomize(pp, shares, msp, d, nil)
+	require.NoError(t, err)
+	sum := new(big.Int).Add(s, delta)
+	require.True(t, pvgss.SVerify(pp, fresh, new(bn256.G2).ScalarBaseMult(sum), msp))
+	require.False(t, pvgss.SVerify(pp, fresh, new(bn256.G2).ScalarBaseMult(s), msp))
+	for i := range shares {
+		require.NotEqual(t, shares[i].Ci.String(), fresh[i].Ci.String())
+		require.NotEqual(t, shares[i].CiPrime.String(), fresh[i].CiPrime.String())
+	}
+
+	osk, err := pvgss.KeyGen(pp, []string{"A", "C"})
+	require.NoError(t, err)
+	R, proof, err := pvgss.Recon(pp, fresh, msp, osk, sk)
+	require.NoError(t, err)
+	g2 := new(bn256.G2).ScalarBaseMult(big.NewInt(1))
+	require.Equal(t, new(bn256.GT).ScalarMult(bn256.Pair(osk.Ht, g2), sum).String(), R.String())
+	require.True(t, pvgss.DVerify(pp, fresh, msp, osk, R, proof))
+
+	delete(shares, 0)
+	_, _, err = pvgss.Rerandomize(pp, shares, msp, d, nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,84 @@
+package PVGSS
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/AUKUS561/PVOABE/LSSS"
+	"github.com/AUKUS561/PVOABE/opcount"
+	"github.com/fentec-project/bn256"
+	"github.com/fentec-project/gofe/abe"
+	"github.com/fentec-project/gofe/sample"
+)
+
+// Rerandomize 把 Share(b) 的输出刷新为 Share(b·d) 的一份新输出，不需要知道 b 或原来的 {λi}、{ri}：
+// 在指数上对 d 做一次新的分享 {d^{μi}}，再选取 r'i，
+//
+//	Ci ← Ci · d^{μi} · pkρ(i)^{-r'i}，Ci' ← Ci' · g^{r'i}
+//
+// b^{λi}·d^{μi} 是 b·d 的一个均匀的新分享，ri + r'i 也是均匀的，因此输出与新分享同分布。
+// 隐藏取值的行另选 u'，把盲化公钥刷新为 (X, U)^{u'}（即 u 换成 u·u'）；该行的 b^{λi} = Ci·T 可以直接算出，
+// 因此以 b^{λi}·d^{μi} 和新的 r'i 重新生成该行的份额与证明。
+// 返回新的份额与新的隐藏行，输入不被修改
+func (pvgss *PVGSS) Rerandomize(pp *PublicParameter, ct map[int]*CipherText, msp *abe.MSP, d *bn256.G1, rows map[int]*HiddenRow) (map[int]*CipherText, map[int]*HiddenRow, error) {
+	p := pp.Order
+	if err := LSSS.Validate(msp); err != nil {
+		return nil, nil, fmt.Errorf("invalid access structure: %w", err)
+	}
+	if len(ct) != len(msp.Mat) {
+		return nil, nil, fmt.Errorf("have %d shares for %d rows", len(ct), len(msp.Mat))
+	}
+	dis, err := LSSS.ShareInExponent(msp, d, big.NewInt(1), p)
+	if err != nil {
+		return nil, nil, err
+	}
+	pvgss.Ops.Add(opcount.G1Mul, len(dis))
+	sampler := sample.NewUniformRange(big.NewInt(1), p)
+	out := make(map[int]*CipherText, len(ct))
+	var freshRows map[int]*HiddenRow
+	if rows != nil {
+		freshRows = make(map[int]*HiddenRow, len(rows))
+	}
+	for i, c := range ct {
+		if c == nil || c.Ci == nil {
+			return nil, nil, fmt.Errorf("missing share for row %d", i)
+		}
+		di, ok := dis[i]
+		if !ok {
+			return nil, nil, fmt.Errorf("share for unknown row %d", i)
+		}
+		r, _ := sampler.Sample()
+
+		if row, hidden := rows[i]; hidden {
+			if c.T == nil || row == nil || row.X == nil || row.U == nil {
+				return nil, nil, fmt.Errorf("hidden row %d has no T", i)
+			}
+			u, _ := sampler.Sample()
+			nr := &HiddenRow{X: pvgss.Ops.G1Mul(row.X, u), U: pvgss.Ops.G1Mul(row.U, u)}
+			freshRows[i] = nr
+			//b^{λi}·d^{μi} = Ci·T·d^{μi}
+			bi := new(bn256.G1).Add(c.Ci, c.T)
+			if out[i], err = pvgss.shareHiddenRow(bi.Add(bi, di), nr, r); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+		if c.CiPrime == nil {
+			return nil, nil, fmt.Errorf("missing share for row %d", i)
+		}
+		pkx, ok := pp.PkXs[msp.RowToAttrib[i]]
+		if !ok {
+			return nil, nil, fmt.Errorf("attribute %s not in public parameters", msp.RowToAttrib[i])
+		}
+		negR := new(big.Int).Neg(r)
+		negR.Mod(negR, p)
+		fresh := &CipherText{Version: c.Version, CiPrime: new(bn256.G1).Add(c.CiPrime, pvgss.Ops.G1Base(r))}
+		fresh.Ci = new(bn256.G1).Add(c.Ci, di)
+		fresh.Ci.Add(fresh.Ci, pvgss.Ops.G1Mul(pkx, negR))
+		out[i] = fresh
+	}
+	if len(freshRows) != len(rows) {
+		return nil, nil, fmt.Errorf("hidden rows do not match the shares")
+	}
+	return out, freshRows, nil
+}
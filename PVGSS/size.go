@@ -0,0 +1,63 @@
+package PVGSS
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/AUKUS561/PVOABE/size"
+)
+
+// Size 列出一行份额 (Ci, Ci') 的大小
+func (c *CipherText) Size() size.Report {
+	return size.Breakdown("PVGSS.CipherText", c)
+}
+
+// EncodedLen 返回一行份额在编码 enc 下的字节数
+func (c *CipherText) EncodedLen(enc size.Encoding) int {
+	return c.Size().Len(enc)
+}
+
+// Size 列出 OSK 的 L、{Kx} 与 h^t 的大小
+func (osk *OSK) Size() size.Report {
+	return size.Breakdown("PVGSS.OSK", osk)
+}
+
+// EncodedLen 返回 OSK 在编码 enc 下的字节数
+func (osk *OSK) EncodedLen(enc size.Encoding) int {
+	return osk.Size().Len(enc)
+}
+
+// SharesSize 按行号列出 Share 输出的每一行份额
+func SharesSize(shares map[int]*CipherText) size.Report {
+	rows := make([]int, 0, len(shares))
+	for i := range shares {
+		rows = append(rows, i)
+	}
+	sort.Ints(rows)
+
+	r := size.Report{Name: "PVGSS shares"}
+	for _, i := range rows {
+		r.Add("row "+strconv.Itoa(i), shares[i].Size().Total())
+	}
+	return r
+}
+
+// Size 列出密钥策略模式外包密钥每行 (Ki, Ki') 与 h^t 的大小，访问结构不计入
+func (osk *KPOSK) Size() size.Report {
+	return size.Breakdown("PVGSS.KPOSK", osk)
+}
+
+// EncodedLen 返回密钥策略模式外包密钥在编码 enc 下的字节数
+func (osk *KPOSK) EncodedLen(enc size.Encoding) int {
+	return osk.Size().Len(enc)
+}
+
+// Size 列出密钥策略模式密文分量 B 与 {Cx} 的大小
+func (ct *KPCipherText) Size() size.Report {
+	return size.Breakdown("PVGSS.KPCipherText", ct)
+}
+
+// EncodedLen 返回密钥策略模式密文分量在编码 enc 下的字节数
+func (ct *KPCipherText) EncodedLen(enc size.Encoding) int {
+	return ct.Size().Len(enc)
+}
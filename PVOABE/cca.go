@@ -0,0 +1,136 @@
+package PVOABE
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/AUKUS561/PVOABE/LSSS"
+	"github.com/fentec-project/bn256"
+	"github.com/fentec-project/gofe/abe"
+)
+
+/*
+CCA 安全变体（Fujisaki-Okamoto 变换）
+
+Enc/Dec 只有 CPA 安全：C、C'、B 都可被改造，Dec 也不检查它们是否一致。EncCCA 改为：
+  - 选取随机 σ ∈ GT，由 s = H1(σ, m, 策略摘要, MSP 摘要, W) 派生加密随机数，按 Enc 的方式计算 C = σ·e(g,g)^{αs}、C'、B，
+    W 为加密时公钥的追踪参数，未启用追踪时为空，因此密文是否携带 CW 也受 s 约束；
+    密钥承诺的随机数 z 同样由 H1 在另一个域标签下派生；
+  - 消息用 D = m ⊕ H2(σ) 加密；
+  - DecCCA 照常用 R 恢复 σ 与 m，重新计算 s、z 并重新加密，C、C'、B、Tag、CW（包括是否存在）任一不一致即返回 ErrInvalidCiphertext。
+    启用追踪之前加密的密文按 W 为空派生 s，没有 CW，启用追踪后仍可用 DecCCA 解密，但不能用 DecTraceable 解密；
+    删去启用追踪后加密的密文的 CW，s 随之改变，重新加密的检查不通过。
+  - Updates、Hidden 与 NotBefore 不在重新加密的检查之内，携带它们的 CCA 密文一律拒绝。
+
+外包部分不变：OEnc、ODecWithContext 直接作用于嵌入的 CipherText。
+UpdateCiphertext 后 C 仍等于 σ·Base^s（Base 为新纪元的值），因此吊销后的密文仍可通过检查；PolicyUpdate 会改变 s，不适用于 CCA 密文。
+*/
+
+var ErrInvalidCiphertext = errors.New("PVOABE: ciphertext failed the re-encryption check")
+
+// CCACipherText 是 EncCCA 的输出：密文头与消息的一次一密 D = m ⊕ H2(σ)
+type CCACipherText struct {
+	*CipherText
+	D []byte
+}
+
+// EncCCA 按访问策略加密消息 msg，加密随机数由 σ 与 msg 派生
+func (pvoabe *PVOABE) EncCCA(pk *PublicKey, accessPolicy string, msg []byte) (*CCACipherText, error) {
+	msp, _, err := canonicalMSP(pk, accessPolicy)
+	if err != nil {
+		return nil, err
+	}
+	digest := headerDigest(msp, time.Time{})
+	_, sigma, err := bn256.RandomGT(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	s := foRandomness("PVOABE/fo-s", pk.PP.Order, sigma, msg, digest, msp, pk.W)
+	z := foRandomness("PVOABE/fo-z", pk.PP.Order, sigma, msg, digest, msp, pk.W)
+	return &CCACipherText{
+		CipherText: pvoabe.encWith(pk, msp, digest, s, z, sigma),
+		D:          xorPad(sigma, msg),
+	}, nil
+}
+
+// DecCCA 用 DSK 与云端返回的 R 解密，并通过重新加密检查密文未被篡改
+func (pvoabe *PVOABE) DecCCA(pk *PublicKey, CT *CCACipherText, DSK *bn256.G1, R *bn256.GT) ([]byte, error) {
+	if CT == nil || CT.CipherText == nil || CT.Cprime == nil || CT.B == nil || CT.Msp == nil {
+		return nil, ErrInvalidCiphertext
+	}
+	if len(CT.Updates) > 0 || len(CT.Hidden) > 0 || !CT.NotBefore.IsZero() {
+		return nil, ErrInvalidCiphertext
+	}
+	if CT.Epoch != pk.Epoch {
+		return nil, fmt.Errorf("%w: ciphertext at epoch %d, public key at epoch %d", ErrEpochMismatch, CT.Epoch, pk.Epoch)
+	}
+	sigma, err := pvoabe.Dec(CT.CipherText, DSK, R)
+	if err != nil {
+		return nil, err
+	}
+	msg := xorPad(sigma, CT.D)
+
+	// 密文带 CW 时按公钥的 W 派生，否则按未启用追踪派生；删去或伪造 CW 都会改变 s
+	at := *pk
+	if CT.CW == nil {
+		at.W = nil
+	} else if pk.W == nil {
+		return nil, ErrInvalidCiphertext
+	}
+	s := foRandomness("PVOABE/fo-s", pk.PP.Order, sigma, msg, CT.Digest, CT.Msp, at.W)
+	z := foRandomness("PVOABE/fo-z", pk.PP.Order, sigma, msg, CT.Digest, CT.Msp, at.W)
+	want := pvoabe.encWith(&at, CT.Msp, CT.Digest, s, z, sigma)
+	if want.C.String() != CT.C.String() || want.Cprime.String() != CT.Cprime.String() || want.B.String() != CT.B.String() {
+		return nil, ErrInvalidCiphertext
+	}
+	if want.Tag.Z.String() != CT.Tag.Z.String() || want.Tag.T.String() != CT.Tag.T.String() {
+		return nil, ErrInvalidCiphertext
+	}
+	if (want.CW == nil) != (CT.CW == nil) || (want.CW != nil && want.CW.String() != CT.CW.String()) {
+		return nil, ErrInvalidCiphertext
+	}
+	return msg, nil
+}
+
+// foRandomness 在域标签 domain 下计算 H1(σ, m, digest, MSPDigest(msp), W)，落在 [1, p)，w 为空时写入空串
+func foRandomness(domain string, p *big.Int, sigma *bn256.GT, msg, digest []byte, msp *abe.MSP, w *bn256.G2) *big.Int {
+	var wBytes []byte
+	if w != nil {
+		wBytes = w.Marshal()
+	}
+	h := sha256.New()
+	h.Write([]byte(domain))
+	for _, part := range [][]byte{sigma.Marshal(), msg, digest, LSSS.MSPDigest(msp), wBytes} {
+		var l [8]byte
+		binary.BigEndian.PutUint64(l[:], uint64(len(part)))
+		h.Write(l[:])
+		h.Write(part)
+	}
+	s := new(big.Int).SetBytes(h.Sum(nil))
+	s.Mod(s, new(big.Int).Sub(p, big.NewInt(1)))
+	return s.Add(s, big.NewInt(1))
+}
+
+// xorPad 返回 in ⊕ H2(σ)，H2 为计数器模式的 SHA-256
+func xorPad(sigma *bn256.GT, in []byte) []byte {
+	seed := sigma.Marshal()
+	out := make([]byte, len(in))
+	var ctr [4]byte
+	for off := 0; off < len(in); off += sha256.Size {
+		binary.BigEndian.PutUint32(ctr[:], uint32(off/sha256.Size))
+		h := sha256.New()
+		h.Write([]byte("PVOABE/fo-pad"))
+		h.Write(seed)
+		h.Write(ctr[:])
+		block := h.Sum(nil)
+		end := min(off+sha256.Size, len(in))
+		subtle.XORBytes(out[off:end], in[off:end], block)
+	}
+	return out
+}
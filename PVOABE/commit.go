@@ -0,0 +1,99 @@
+package PVOABE
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/AUKUS561/PVOABE/LSSS"
+	"github.com/fentec-project/bn256"
+	"github.com/fentec-project/gofe/abe"
+)
+
+// 密钥承诺：密文携带 Tag = (Z, T) = (g^z, Z^m)，m = H("PVOABE/key-commit", 加密时的策略摘要, keyGt)。
+// Dec 算出 keyGt 后重新计算 m 并检查 T = Z^m，R 或 DSK 有误时返回 ErrDecryptionFailed，
+// 而不是一个无意义的 GT 元素。检查不依赖 ODecVer：即使用户跳过了对 R 的验证，错误的 R 也会在这里被发现。
+// 密文由云端保存，云端可以删去 Tag，因此缺少 Tag 同样返回 ErrDecryptionFailed；
+// 没有 Tag 的旧密文只能显式地用 DecUnchecked 解密。
+// 承诺可以公开地重新随机化为 (Z^ζ, T^ζ)，Rerandomize 由此刷新 Tag，使其不能作为隐蔽信道。
+// m 绑定加密时的策略摘要。Digest 由 Msp 与 NotBefore 决定（headerDigest），Dec 与 ODecVerWithContext 都重新计算：
+// 云端只凭公开的 B 就能在任意 MSP 下重新执行 OEnc，只改 Msp 时摘要对不上，连 Digest 一起改时密钥承诺对不上，
+// 删去策略更新令牌链同样使密钥承诺对不上
+
+var (
+	ErrDecryptionFailed = errors.New("PVOABE: decryption failed, key commitment does not match")
+	ErrHeaderMismatch   = errors.New("PVOABE: ciphertext digest does not match its policy")
+)
+
+// KeyTag 是 keyGt 的可重新随机化承诺
+type KeyTag struct {
+	Z *bn256.G1 //g^z
+	T *bn256.G1 //Z^m
+}
+
+// keyHash 计算 m = H("PVOABE/key-commit", digest, keyGt) mod p
+func keyHash(keyGt *bn256.GT, digest []byte) *big.Int {
+	h := sha256.New()
+	h.Write([]byte("PVOABE/key-commit"))
+	h.Write(binary.BigEndian.AppendUint64(nil, uint64(len(digest))))
+	h.Write(digest)
+	h.Write(keyGt.Marshal())
+	m := new(big.Int).SetBytes(h.Sum(nil))
+	return m.Mod(m, bn256.Order)
+}
+
+// keyTag 用随机数 z 计算 keyGt 在策略摘要 digest 下的承诺
+func (pvoabe *PVOABE) keyTag(keyGt *bn256.GT, digest []byte, z *big.Int) *KeyTag {
+	zm := new(big.Int).Mul(z, keyHash(keyGt, digest))
+	zm.Mod(zm, bn256.Order)
+	return &KeyTag{Z: pvoabe.Ops.G1Base(z), T: pvoabe.Ops.G1Base(zm)}
+}
+
+// rerandomizeTag 返回 (Z^ζ, T^ζ)，承诺的 keyGt 不变
+func (pvoabe *PVOABE) rerandomizeTag(tag *KeyTag, zeta *big.Int) *KeyTag {
+	return &KeyTag{Z: pvoabe.Ops.G1Mul(tag.Z, zeta), T: pvoabe.Ops.G1Mul(tag.T, zeta)}
+}
+
+// checkKeyTag 检查 keyGt 与 digest 是否与承诺 tag 一致，tag 为空或 Z 为单位元时同样失败
+func (pvoabe *PVOABE) checkKeyTag(tag *KeyTag, keyGt *bn256.GT, digest []byte) error {
+	if tag == nil || tag.Z == nil || tag.T == nil || tag.Z.String() == new(bn256.G1).ScalarBaseMult(big.NewInt(0)).String() {
+		return ErrDecryptionFailed
+	}
+	if pvoabe.Ops.G1Mul(tag.Z, keyHash(keyGt, digest)).String() != tag.T.String() {
+		return ErrDecryptionFailed
+	}
+	return nil
+}
+
+// headerDigest 计算密文头的策略摘要 H(MSPDigest(msp), notBefore)，notBefore 为零值时不写入
+func headerDigest(msp *abe.MSP, notBefore time.Time) []byte {
+	h := sha256.New()
+	h.Write([]byte("PVOABE/header"))
+	h.Write(LSSS.MSPDigest(msp))
+	if !notBefore.IsZero() {
+		h.Write(binary.BigEndian.AppendUint64(nil, uint64(notBefore.UnixNano())))
+	}
+	return h.Sum(nil)
+}
+
+// checkHeader 检查 Digest 与 Msp、NotBefore 一致，更新过策略的密文还须与最后一个令牌的 Digest 一致
+func (CT *CipherText) checkHeader() error {
+	if CT.Msp == nil || !bytes.Equal(CT.Digest, headerDigest(CT.Msp, CT.NotBefore)) {
+		return ErrHeaderMismatch
+	}
+	if n := len(CT.Updates); n > 0 && (CT.Updates[n-1] == nil || !bytes.Equal(CT.Updates[n-1].Digest, CT.Digest)) {
+		return ErrHeaderMismatch
+	}
+	return nil
+}
+
+// originDigest 返回加密时的策略摘要：更新过策略的密文取第一个令牌的 Prev
+func (CT *CipherText) originDigest() []byte {
+	if len(CT.Updates) > 0 && CT.Updates[0] != nil {
+		return CT.Updates[0].Prev
+	}
+	return CT.Digest
+}
@@ -0,0 +1,57 @@
+package PVOABE
+
+import (
+	"math/big"
+	"time"
+
+	"github.com/AUKUS561/PVOABE/PVGSS"
+	"github.com/AUKUS561/PVOABE/policy"
+	"github.com/fentec-project/bn256"
+)
+
+// 部分隐藏策略：策略中的 name:value 属性只公开 name，取值对云端与观察者隐藏，见 PVGSS/hidden.go。
+// 密文头的 Msp 只含属性名，Digest 取自改写后的 MSP，不泄露取值
+
+// SetupHidden 由权威机构执行，生成隐藏取值属性的公钥，交给数据拥有者
+func (pvoabe *PVOABE) SetupHidden(pk *PublicKey, sk *PVGSS.SecretKey, attrs []string) (*PVGSS.HiddenKeys, error) {
+	return pvoabe.pvgss.SetupHidden(pk.PP, sk, attrs)
+}
+
+// KeyGenHidden 与 KeyGen 相同，另签发隐藏取值属性 hidden
+func (pvoabe *PVOABE) KeyGenHidden(pk *PublicKey, mk *big.Int, hk *PVGSS.HiddenKeys, su, hidden []string) (*PVGSS.OSK, *bn256.G1, error) {
+	OSK, err := pvoabe.pvgss.KeyGenHidden(pk.PP, hk, su, hidden)
+	if err != nil {
+		return nil, nil, err
+	}
+	//DSK=g^alpha h^t
+	DSK := new(bn256.G1).Add(pvoabe.Ops.G1Base(mk), OSK.Ht)
+	return OSK, DSK, nil
+}
+
+// EncHidden 由数据拥有者执行：按策略加密，并把其中属于 hk 的 name:value 行改写为隐藏行
+func (pvoabe *PVOABE) EncHidden(pk *PublicKey, hk *PVGSS.HiddenKeys, accessPolicy string) (*CipherText, *bn256.GT, error) {
+	msp, _, err := policy.Canonicalize(accessPolicy)
+	if err != nil {
+		return nil, nil, err
+	}
+	msp, rows, err := pvoabe.pvgss.HidePolicy(pk.PP, hk, msp)
+	if err != nil {
+		return nil, nil, err
+	}
+	CT, keyGt, _, err := pvoabe.encMSP(pk, msp, headerDigest(msp, time.Time{}))
+	if err != nil {
+		return nil, nil, err
+	}
+	CT.Hidden = rows
+	return CT, keyGt, nil
+}
+
+// OEncHidden 是云端对 EncHidden 密文执行的 OEnc
+func (pvoabe *PVOABE) OEncHidden(pk *PublicKey, CT *CipherText) (map[int]*PVGSS.CipherText, error) {
+	return pvoabe.pvgss.ShareHidden(pk.PP, CT.B, CT.Msp, CT.Hidden)
+}
+
+// OEncVerHidden 公开验证 OEncHidden 的份额，不需要知道隐藏的取值
+func (pvoabe *PVOABE) OEncVerHidden(pk *PublicKey, ct map[int]*PVGSS.CipherText, CT *CipherText) bool {
+	return pvoabe.pvgss.SVerifyHidden(pk.PP, ct, CT.Cprime, CT.Msp, CT.Hidden)
+}
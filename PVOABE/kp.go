@@ -0,0 +1,143 @@
+package PVOABE
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/AUKUS561/PVOABE/DLEQ"
+	"github.com/AUKUS561/PVOABE/PVGSS"
+	"github.com/AUKUS561/PVOABE/policy"
+	"github.com/fentec-project/bn256"
+	"github.com/fentec-project/gofe/sample"
+)
+
+// 密钥策略（KP）模式：密文标记属性集，如日志来源与级别，分析人员的密钥携带访问策略，见 PVGSS/kp.go。
+// C、C'、B 与 DSK = g^α h^t 的形式与 CP 模式相同，外包加密、验证与解密的流程一一对应
+
+// KPCipherText 是密钥策略模式的密文头，Digest 为属性集的摘要并绑定到 KPODec 的 DLEQ 证明
+type KPCipherText struct {
+	C      *bn256.GT
+	Cprime *bn256.G2
+	B      *bn256.G1
+	Attrs  []string
+	Digest []byte
+	Tag    *KeyTag //keyGt 的承诺，见 CipherText.Tag
+}
+
+// KPSetup 由权威机构与云端执行，为属性全集生成 {Vx}，{vx} 交给云端
+func (pvoabe *PVOABE) KPSetup(pk *PublicKey, attributeUniverse []string) (*PVGSS.KPParameter, *PVGSS.KPSecretKey, error) {
+	return pvoabe.pvgss.KPSetup(pk.PP, attributeUniverse)
+}
+
+// KPKeyGen 按访问策略签发 (OSK, DSK)，策略按 policy.Canonicalize 规范化
+func (pvoabe *PVOABE) KPKeyGen(pk *PublicKey, mk *big.Int, kp *PVGSS.KPParameter, accessPolicy string) (*PVGSS.KPOSK, *bn256.G1, error) {
+	msp, _, err := policy.Canonicalize(accessPolicy)
+	if err != nil {
+		return nil, nil, err
+	}
+	OSK, err := pvoabe.pvgss.KPKeyGen(pk.PP, kp, msp)
+	if err != nil {
+		return nil, nil, err
+	}
+	//DSK=g^alpha h^t
+	DSK := new(bn256.G1).Add(pvoabe.Ops.G1Base(mk), OSK.Ht)
+	return OSK, DSK, nil
+}
+
+// KPEnc 由数据拥有者执行，以属性集 attrs 标记密文
+func (pvoabe *PVOABE) KPEnc(pk *PublicKey, kp *PVGSS.KPParameter, attrs []string) (*KPCipherText, *bn256.GT, error) {
+	if len(attrs) == 0 {
+		return nil, nil, fmt.Errorf("empty attribute set")
+	}
+	sorted := append([]string(nil), attrs...)
+	sort.Strings(sorted)
+	for i, x := range sorted {
+		if i > 0 && sorted[i-1] == x {
+			return nil, nil, fmt.Errorf("duplicate attribute %s", x)
+		}
+		if _, ok := kp.Vs[x]; !ok {
+			return nil, nil, fmt.Errorf("attribute %s not in key-policy parameters", x)
+		}
+	}
+
+	//s<-Zp,计算B,C'
+	sampler := sample.NewUniformRange(big.NewInt(1), pk.PP.Order)
+	s, _ := sampler.Sample()
+	z, _ := sampler.Sample()
+	B := pvoabe.Ops.G1Mul(pk.PP.Pk, s)      //B=pk^s
+	Cprime := pvoabe.Ops.G2Base(s)          //C'
+	abeTerm := pvoabe.Ops.GTExp(pk.Base, s) //e(g,g)^alpha s
+
+	_, keyGt, err := bn256.RandomGT(rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	digest := attrsDigest(sorted)
+	return &KPCipherText{
+		C:      new(bn256.GT).Add(keyGt, abeTerm),
+		Cprime: Cprime,
+		B:      B,
+		Attrs:  sorted,
+		Digest: digest,
+		Tag:    pvoabe.keyTag(keyGt, digest, z),
+	}, keyGt, nil
+}
+
+// attrsDigest 对排序后的属性集做哈希
+func attrsDigest(sorted []string) []byte {
+	h := sha256.New()
+	h.Write([]byte("PVOABE/kp-attrs"))
+	for _, x := range sorted {
+		h.Write([]byte{0})
+		h.Write([]byte(x))
+	}
+	return h.Sum(nil)
+}
+
+// KPOEnc 由云端执行，为密文的属性集计算 {Cx = B^{vx}}
+func (pvoabe *PVOABE) KPOEnc(pk *PublicKey, ksk *PVGSS.KPSecretKey, CT *KPCipherText) (*PVGSS.KPCipherText, error) {
+	return pvoabe.pvgss.KPShare(pk.PP, ksk, CT.B, CT.Attrs)
+}
+
+// KPOEncVer 公开验证 KPOEnc 的输出与密文头一致：B 相同、属性集相同且各 Cx 正确
+func (pvoabe *PVOABE) KPOEncVer(pk *PublicKey, kp *PVGSS.KPParameter, ct *PVGSS.KPCipherText, CT *KPCipherText) bool {
+	if ct == nil || CT == nil || ct.B == nil || CT.B == nil || ct.B.String() != CT.B.String() {
+		return false
+	}
+	attrs := ct.Attrs()
+	if len(attrs) != len(CT.Attrs) {
+		return false
+	}
+	for i := range attrs {
+		if attrs[i] != CT.Attrs[i] {
+			return false
+		}
+	}
+	return pvoabe.pvgss.KPSVerify(pk.PP, kp, ct, CT.Cprime)
+}
+
+// KPODec 由云端执行，证明 π 绑定密文头中的属性集摘要 CT.Digest
+func (pvoabe *PVOABE) KPODec(pk *PublicKey, ct *PVGSS.KPCipherText, CT *KPCipherText, OSK *PVGSS.KPOSK, sk *PVGSS.SecretKey) (*bn256.GT, *DLEQ.Prfs, error) {
+	return pvoabe.pvgss.KPReconWithContext(pk.PP, ct, OSK, sk, CT.Digest)
+}
+
+// KPODecVer 验证 KPODec 的输出
+func (pvoabe *PVOABE) KPODecVer(pk *PublicKey, ct *PVGSS.KPCipherText, CT *KPCipherText, OSK *PVGSS.KPOSK, R *bn256.GT, Proof *DLEQ.Prfs) bool {
+	return pvoabe.pvgss.KPDVerifyWithContext(pk.PP, ct, OSK, R, Proof, CT.Digest)
+}
+
+// KPDec 与 Dec 相同：keyGt = C / (e(DSK, C') / R)，并检查密钥承诺
+func (pvoabe *PVOABE) KPDec(CT *KPCipherText, DSK *bn256.G1, R *bn256.GT) (*bn256.GT, error) {
+	if CT.C == nil || DSK == nil || R == nil {
+		return nil, fmt.Errorf("nil input")
+	}
+	T := new(bn256.GT).Add(pvoabe.Ops.Pair(DSK, CT.Cprime), new(bn256.GT).Neg(R))
+	keyGt := new(bn256.GT).Add(CT.C, new(bn256.GT).Neg(T))
+	if err := pvoabe.checkKeyTag(CT.Tag, keyGt, CT.Digest); err != nil {
+		return nil, err
+	}
+	return keyGt, nil
+}
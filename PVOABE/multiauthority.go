@@ -0,0 +1,293 @@
+package PVOABE
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/AUKUS561/PVOABE/LSSS"
+	"github.com/AUKUS561/PVOABE/policy"
+	"github.com/fentec-project/bn256"
+	"github.com/fentec-project/gofe/abe"
+	"github.com/fentec-project/gofe/sample"
+)
+
+/*
+多权威机构模式
+
+Setup 假设一个权威机构持有主密钥 α 与整个属性全集。多权威机构模式下每个机构独立运行
+AuthoritySetup，只管理以自己名字为前缀的属性（如 Hospital.Doctor），机构之间无需协调，
+也没有中心机构。构造采用去中心化 ABE（Lewko–Waters）：
+  - 机构为属性 x 选取 αx, yx，公开 e(g,g)^{αx} 与 g2^{yx}；
+  - 用户以全局标识 GID 向各机构申请属性密钥 Kx = g^{αx}·H(GID)^{yx}，H 映射到 G1 且离散对数未知；
+  - 加密时 s 的份额 λi 与 0 的份额 ωi 按同一 MSP 生成，第 i 行 x = ρ(i)、ri←Zp：
+      C1_i = e(g,g)^{λi}·e(g,g)^{αx·ri}，C2_i = g2^{ri}，C3_i = g2^{yx·ri}·g2^{ωi}；
+  - 每行 C1_i·e(H(GID), C3_i)/e(Kx, C2_i) = e(g,g)^{λi}·e(H(GID),g)^{ωi}，
+    只有同一 GID 的密钥才能让 ωi 部分在重构时抵消，因此不同用户合谋无效。
+
+与单机构模式一样，配对由云端完成：用户选取 z，把 Kx^{1/z} 与 H(GID)^{1/z} 作为转换密钥交给云端，
+云端返回 Q1 = ∏C1_i^{wi} 与 Q2 = ∏(e(H^{1/z}, C3_i)/e(Kx^{1/z}, C2_i))^{wi}，
+用户只需计算 Q1·Q2^z = e(g,g)^s。该模式的份额与重构不经过 PVGSS，因此没有 SVerify/DVerify 证明；
+云端返回的 (Q1, Q2) 由密钥承诺验证：密文携带 keyGt 的 Tag（见 commit.go），Q1 或 Q2 有误时
+MADec 算出的 K 与 Tag 不符，返回 ErrDecryptionFailed。
+*/
+
+var ErrGIDMismatch = errors.New("PVOABE: keys issued to different global identifiers")
+
+// fieldP 是 BN256 的基域特征，hashGID 用它把哈希值映射为 G1 上的点
+var fieldP, _ = new(big.Int).SetString("65000549695646603732796438742359905742825358107623003571877145026864184071783", 10)
+
+// AttrAuthority 是一个属性权威机构的私有状态
+type AttrAuthority struct {
+	Name  string
+	PK    *AuthorityPublicKey
+	alpha map[string]*big.Int
+	y     map[string]*big.Int
+}
+
+// AuthorityPublicKey 是机构公开的属性公钥：e(g,g)^{αx} 与 g2^{yx}
+type AuthorityPublicKey struct {
+	Name   string
+	EAlpha map[string]*bn256.GT
+	GY     map[string]*bn256.G2
+}
+
+// MAKey 是用户在多个机构处取得的属性密钥，所有 Kx 都绑定同一个 GID
+type MAKey struct {
+	GID string
+	K   map[string]*bn256.G1
+}
+
+// MATransformKey 是交给云端的转换密钥：Kx^{1/z} 与 H(GID)^{1/z}
+type MATransformKey struct {
+	GID string
+	HG  *bn256.G1
+	K   map[string]*bn256.G1
+}
+
+// MACipherText 是多权威机构模式的密文，每行对应一个属性
+type MACipherText struct {
+	C0     *bn256.GT //K·e(g,g)^s
+	Msp    *abe.MSP
+	Digest []byte
+	C1     map[int]*bn256.GT
+	C2     map[int]*bn256.G2
+	C3     map[int]*bn256.G2
+	Tag    *KeyTag //K 的承诺，MADec 用它验证云端的 (Q1, Q2)
+}
+
+// MAPartial 是云端 MAODec 的输出
+type MAPartial struct {
+	Q1 *bn256.GT
+	Q2 *bn256.GT
+}
+
+// AuthoritySetup 由机构 name 独立执行，attrs 必须以 "name." 为前缀，保证各机构的属性名互不冲突
+func (pvoabe *PVOABE) AuthoritySetup(name string, attrs []string) (*AttrAuthority, error) {
+	if name == "" || strings.ContainsAny(name, " (),") {
+		return nil, fmt.Errorf("invalid authority name %q", name)
+	}
+	auth := &AttrAuthority{
+		Name:  name,
+		PK:    &AuthorityPublicKey{Name: name, EAlpha: make(map[string]*bn256.GT), GY: make(map[string]*bn256.G2)},
+		alpha: make(map[string]*big.Int),
+		y:     make(map[string]*big.Int),
+	}
+	g1 := new(bn256.G1).ScalarBaseMult(big.NewInt(1))
+	g2 := new(bn256.G2).ScalarBaseMult(big.NewInt(1))
+	egg := pvoabe.Ops.Pair(g1, g2)
+	sampler := sample.NewUniformRange(big.NewInt(1), pvoabe.P)
+	for _, x := range attrs {
+		if !strings.HasPrefix(x, name+".") || len(x) == len(name)+1 {
+			return nil, fmt.Errorf("attribute %s is not qualified by authority %s", x, name)
+		}
+		if _, ok := auth.alpha[x]; ok {
+			return nil, fmt.Errorf("duplicate attribute %s", x)
+		}
+		alpha, _ := sampler.Sample()
+		y, _ := sampler.Sample()
+		auth.alpha[x], auth.y[x] = alpha, y
+		auth.PK.EAlpha[x] = pvoabe.Ops.GTExp(egg, alpha)
+		auth.PK.GY[x] = pvoabe.Ops.G2Base(y)
+	}
+	return auth, nil
+}
+
+// MAKeyGen 由机构执行，为 gid 签发 attrs 中各属性的密钥 Kx = g^{αx}·H(gid)^{yx}
+func (pvoabe *PVOABE) MAKeyGen(auth *AttrAuthority, gid string, attrs []string) (*MAKey, error) {
+	if gid == "" {
+		return nil, fmt.Errorf("empty global identifier")
+	}
+	hg := pvoabe.Ops.HashG1(hashGID, gid)
+	key := &MAKey{GID: gid, K: make(map[string]*bn256.G1, len(attrs))}
+	for _, x := range attrs {
+		alpha, ok := auth.alpha[x]
+		if !ok {
+			return nil, fmt.Errorf("attribute %s not managed by authority %s", x, auth.Name)
+		}
+		key.K[x] = new(bn256.G1).Add(pvoabe.Ops.G1Base(alpha), pvoabe.Ops.G1Mul(hg, auth.y[x]))
+	}
+	return key, nil
+}
+
+// Merge 把另一机构签发的密钥并入 key，两者的 GID 必须相同
+func (key *MAKey) Merge(other *MAKey) error {
+	if key.GID != other.GID {
+		return fmt.Errorf("%w: %s and %s", ErrGIDMismatch, key.GID, other.GID)
+	}
+	for x, k := range other.K {
+		key.K[x] = k
+	}
+	return nil
+}
+
+// MAEnc 按可混合多个机构属性的策略加密，pks 为策略中涉及的机构公钥。
+// 每个机构公钥只能发布以自己名字为前缀的属性，否则一个机构可以冒充另一个机构的属性
+func (pvoabe *PVOABE) MAEnc(pks []*AuthorityPublicKey, accessPolicy string) (*MACipherText, *bn256.GT, error) {
+	msp, digest, err := policy.Canonicalize(accessPolicy)
+	if err != nil {
+		return nil, nil, err
+	}
+	eAlpha := make(map[string]*bn256.GT)
+	gy := make(map[string]*bn256.G2)
+	for _, pk := range pks {
+		for x := range pk.EAlpha {
+			if !strings.HasPrefix(x, pk.Name+".") || len(x) == len(pk.Name)+1 {
+				return nil, nil, fmt.Errorf("attribute %s is not qualified by authority %s", x, pk.Name)
+			}
+			if pk.GY[x] == nil {
+				return nil, nil, fmt.Errorf("attribute %s has no g2^y in authority %s", x, pk.Name)
+			}
+			if _, ok := eAlpha[x]; ok {
+				return nil, nil, fmt.Errorf("attribute %s published by more than one authority", x)
+			}
+			eAlpha[x], gy[x] = pk.EAlpha[x], pk.GY[x]
+		}
+	}
+	for _, x := range msp.RowToAttrib {
+		if _, ok := eAlpha[x]; !ok {
+			return nil, nil, fmt.Errorf("attribute %s not published by any authority", x)
+		}
+	}
+
+	sampler := sample.NewUniformRange(big.NewInt(1), pvoabe.P)
+	s, _ := sampler.Sample()
+	z, _ := sampler.Sample()
+	lambda, err := LSSS.Share(msp, s, pvoabe.P)
+	if err != nil {
+		return nil, nil, err
+	}
+	omega, err := LSSS.Share(msp, big.NewInt(0), pvoabe.P)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	_, keyGt, err := bn256.RandomGT(rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	g1 := new(bn256.G1).ScalarBaseMult(big.NewInt(1))
+	g2 := new(bn256.G2).ScalarBaseMult(big.NewInt(1))
+	egg := pvoabe.Ops.Pair(g1, g2)
+	CT := &MACipherText{
+		C0:     new(bn256.GT).Add(keyGt, pvoabe.Ops.GTExp(egg, s)),
+		Msp:    msp,
+		Digest: digest,
+		C1:     make(map[int]*bn256.GT, len(msp.Mat)),
+		C2:     make(map[int]*bn256.G2, len(msp.Mat)),
+		C3:     make(map[int]*bn256.G2, len(msp.Mat)),
+		Tag:    pvoabe.keyTag(keyGt, digest, z),
+	}
+	for i, x := range msp.RowToAttrib {
+		r, _ := sampler.Sample()
+		CT.C1[i] = new(bn256.GT).Add(pvoabe.Ops.GTExp(egg, lambda[i]), pvoabe.Ops.GTExp(eAlpha[x], r))
+		CT.C2[i] = pvoabe.Ops.G2Base(r)
+		CT.C3[i] = new(bn256.G2).Add(pvoabe.Ops.G2Mul(gy[x], r), pvoabe.Ops.G2Base(omega[i]))
+	}
+	return CT, keyGt, nil
+}
+
+// MATransformKey 由用户执行，返回交给云端的转换密钥与自己保留的 z
+func (pvoabe *PVOABE) MATransformKey(key *MAKey) (*MATransformKey, *big.Int, error) {
+	sampler := sample.NewUniformRange(big.NewInt(1), pvoabe.P)
+	z, _ := sampler.Sample()
+	zInv := new(big.Int).ModInverse(z, pvoabe.P)
+	if zInv == nil {
+		return nil, nil, fmt.Errorf("z is not invertible")
+	}
+	tk := &MATransformKey{
+		GID: key.GID,
+		HG:  pvoabe.Ops.G1Mul(pvoabe.Ops.HashG1(hashGID, key.GID), zInv),
+		K:   make(map[string]*bn256.G1, len(key.K)),
+	}
+	for x, k := range key.K {
+		tk.K[x] = pvoabe.Ops.G1Mul(k, zInv)
+	}
+	return tk, z, nil
+}
+
+// MAODec 由云端执行：按转换密钥中的属性求重构系数 wi，计算 Q1 与 Q2
+func (pvoabe *PVOABE) MAODec(tk *MATransformKey, CT *MACipherText) (*MAPartial, error) {
+	attrs := make([]string, 0, len(tk.K))
+	for x := range tk.K {
+		attrs = append(attrs, x)
+	}
+	plan, err := LSSS.NewReconPlan(CT.Msp, attrs, pvoabe.P)
+	if err != nil {
+		return nil, err
+	}
+	part := &MAPartial{Q1: new(bn256.GT).ScalarBaseMult(big.NewInt(0)), Q2: new(bn256.GT).ScalarBaseMult(big.NewInt(0))}
+	for _, i := range plan.Rows {
+		w := plan.Coeffs[i]
+		d := new(bn256.GT).Add(pvoabe.Ops.Pair(tk.HG, CT.C3[i]), new(bn256.GT).Neg(pvoabe.Ops.Pair(tk.K[CT.Msp.RowToAttrib[i]], CT.C2[i])))
+		part.Q1.Add(part.Q1, pvoabe.Ops.GTExp(CT.C1[i], w))
+		part.Q2.Add(part.Q2, pvoabe.Ops.GTExp(d, w))
+	}
+	return part, nil
+}
+
+// MADec 由用户执行：e(g,g)^s = Q1·Q2^z，K = C0 / e(g,g)^s，并用 Tag 检查云端的 (Q1, Q2)
+func (pvoabe *PVOABE) MADec(CT *MACipherText, z *big.Int, part *MAPartial) (*bn256.GT, error) {
+	if CT.C0 == nil || z == nil || part == nil || part.Q1 == nil || part.Q2 == nil {
+		return nil, fmt.Errorf("nil input")
+	}
+	mask := new(bn256.GT).Add(part.Q1, pvoabe.Ops.GTExp(part.Q2, z))
+	keyGt := new(bn256.GT).Add(CT.C0, new(bn256.GT).Neg(mask))
+	if err := pvoabe.checkKeyTag(CT.Tag, keyGt, CT.Digest); err != nil {
+		return nil, err
+	}
+	return keyGt, nil
+}
+
+// hashGID 把 gid 映射到 G1（try-and-increment）：x = H(gid, ctr) mod p，
+// 直到 x^3+3 是平方剩余；p ≡ 3 (mod 4)，平方根为 (x^3+3)^{(p+1)/4}。得到的点离散对数未知
+func hashGID(gid string) *bn256.G1 {
+	exp := new(big.Int).Add(fieldP, big.NewInt(1))
+	exp.Rsh(exp, 2)
+	buf := make([]byte, 64)
+	for ctr := uint32(0); ; ctr++ {
+		h := sha256.New()
+		h.Write([]byte("PVOABE-GID"))
+		h.Write(binary.BigEndian.AppendUint32(nil, ctr))
+		h.Write([]byte(gid))
+		x := new(big.Int).SetBytes(h.Sum(nil))
+		x.Mod(x, fieldP)
+		rhs := new(big.Int).Exp(x, big.NewInt(3), fieldP)
+		rhs.Add(rhs, big.NewInt(3))
+		rhs.Mod(rhs, fieldP)
+		y := new(big.Int).Exp(rhs, exp, fieldP)
+		if new(big.Int).Exp(y, big.NewInt(2), fieldP).Cmp(rhs) != 0 {
+			continue
+		}
+		x.FillBytes(buf[:32])
+		y.FillBytes(buf[32:])
+		p := new(bn256.G1)
+		if _, err := p.Unmarshal(buf); err == nil {
+			return p
+		}
+	}
+}
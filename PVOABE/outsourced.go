@@ -0,0 +1,127 @@
+package PVOABE
+
+import (
+	"math/big"
+
+	"github.com/AUKUS561/PVOABE/DLEQ"
+	"github.com/AUKUS561/PVOABE/PVGSS"
+	"github.com/AUKUS561/PVOABE/oabe"
+	"github.com/AUKUS561/PVOABE/size"
+	"github.com/fentec-project/bn256"
+)
+
+// Name 是 PVOABE 在 oabe 接口中的方案名
+const Name = "PVOABE"
+
+// Outsourced 把 PVOABE 适配为 oabe.OutsourcedABE：
+// OEnc/OEncVer 对应 ProxyEncrypt/VerifyEncryption，ODec/ODecVer 对应 ProxyDecrypt/VerifyDecryption
+type Outsourced struct {
+	*PVOABE
+}
+
+func NewOutsourced() *Outsourced {
+	return &Outsourced{PVOABE: NewPVOABE()}
+}
+
+// outsourcedCT 是 Enc 的输出加上代理服务器的 PVGSS 份额
+type outsourcedCT struct {
+	CT     *CipherText
+	Shares map[int]*PVGSS.CipherText
+}
+
+// Size 列出密文各部分以及代理服务器保存的每一行 PVGSS 份额
+func (v *outsourcedCT) Size() size.Report {
+	r := v.CT.Size()
+	r.Components = append(r.Components, PVGSS.SharesSize(v.Shares).Components...)
+	return r
+}
+
+func (o *Outsourced) Name() string { return Name }
+
+func (o *Outsourced) Supports() oabe.Support {
+	return oabe.Support{ProxyEncrypt: true, VerifyEncryption: true, VerifyDecryption: true}
+}
+
+func (o *Outsourced) Setup(universe []string) (*oabe.Authority, error) {
+	alpha, pk, sk, err := o.SetupUniverse(universe)
+	if err != nil {
+		return nil, err
+	}
+	return &oabe.Authority{Scheme: Name, Universe: universe, Public: pk, Master: alpha, Proxy: sk}, nil
+}
+
+func (o *Outsourced) KeyGen(auth *oabe.Authority, id string, attrs []string) (*oabe.UserKey, error) {
+	if err := oabe.Check(Name, auth.Scheme); err != nil {
+		return nil, err
+	}
+	osk, dsk, err := o.PVOABE.KeyGen(auth.Public.(*PublicKey), auth.Master.(*big.Int), attrs)
+	if err != nil {
+		return nil, err
+	}
+	return &oabe.UserKey{Scheme: Name, ID: id, Attrs: attrs, Proxy: osk, User: dsk}, nil
+}
+
+func (o *Outsourced) Encrypt(auth *oabe.Authority, owner *oabe.UserKey, policy string) (*oabe.Ciphertext, *bn256.GT, error) {
+	if err := oabe.Check(Name, auth.Scheme); err != nil {
+		return nil, nil, err
+	}
+	ct, key, err := o.EncPolicy(auth.Public.(*PublicKey), policy)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &oabe.Ciphertext{Scheme: Name, Policy: policy, Value: &outsourcedCT{CT: ct}}, key, nil
+}
+
+func (o *Outsourced) ProxyEncrypt(auth *oabe.Authority, owner *oabe.UserKey, ct *oabe.Ciphertext) error {
+	if err := oabe.Check(Name, ct.Scheme); err != nil {
+		return err
+	}
+	v := ct.Value.(*outsourcedCT)
+	shares, err := o.OEnc(auth.Public.(*PublicKey), v.CT.B, v.CT.Msp)
+	if err != nil {
+		return err
+	}
+	v.Shares = shares
+	return nil
+}
+
+func (o *Outsourced) VerifyEncryption(auth *oabe.Authority, owner *oabe.UserKey, ct *oabe.Ciphertext) error {
+	if err := oabe.Check(Name, ct.Scheme); err != nil {
+		return err
+	}
+	v := ct.Value.(*outsourcedCT)
+	if !o.OEncVer(auth.Public.(*PublicKey), v.Shares, v.CT.Cprime, v.CT.Msp) {
+		return oabe.ErrVerificationFailed
+	}
+	return nil
+}
+
+func (o *Outsourced) ProxyDecrypt(auth *oabe.Authority, user *oabe.UserKey, ct *oabe.Ciphertext) (*oabe.Transformed, error) {
+	if err := oabe.Check(Name, ct.Scheme); err != nil {
+		return nil, err
+	}
+	v := ct.Value.(*outsourcedCT)
+	R, proof, err := o.ODecWithContext(auth.Public.(*PublicKey), v.Shares, v.CT, user.Proxy.(*PVGSS.OSK), auth.Proxy.(*PVGSS.SecretKey))
+	if err != nil {
+		return nil, err
+	}
+	return &oabe.Transformed{Scheme: Name, Value: R, Proof: proof}, nil
+}
+
+func (o *Outsourced) VerifyDecryption(auth *oabe.Authority, user *oabe.UserKey, ct *oabe.Ciphertext, tr *oabe.Transformed) error {
+	if err := oabe.Check(Name, tr.Scheme); err != nil {
+		return err
+	}
+	v := ct.Value.(*outsourcedCT)
+	if !o.ODecVerWithContext(auth.Public.(*PublicKey), v.Shares, v.CT, user.Proxy.(*PVGSS.OSK), tr.Value.(*bn256.GT), tr.Proof.(*DLEQ.Prfs)) {
+		return oabe.ErrVerificationFailed
+	}
+	return nil
+}
+
+func (o *Outsourced) Decrypt(auth *oabe.Authority, user *oabe.UserKey, ct *oabe.Ciphertext, tr *oabe.Transformed) (*bn256.GT, error) {
+	if err := oabe.Check(Name, tr.Scheme); err != nil {
+		return nil, err
+	}
+	return o.Dec(ct.Value.(*outsourcedCT).CT, user.User.(*bn256.G1), tr.Value.(*bn256.GT))
+}
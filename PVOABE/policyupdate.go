@@ -0,0 +1,160 @@
+package PVOABE
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/AUKUS561/PVOABE/LSSS"
+	"github.com/AUKUS561/PVOABE/PVGSS"
+	"github.com/fentec-project/bn256"
+	"github.com/fentec-project/gofe/abe"
+)
+
+/*
+策略更新
+
+PVGSS 份额只依赖公开的 B 与 MSP，云端可以直接在新策略下重新生成份额，C、C'、B 都不变，
+因此数据拥有者不必下载、解密并重新执行 Enc 与 OEnc。需要保证的是只有数据拥有者能改变策略：
+更新令牌携带新策略及其摘要，并附带对 C' = g2^s 中 s 的 Schnorr 知识证明，
+挑战绑定 (B, C', 版本号, 旧摘要, 新摘要)。版本号是密文已应用的令牌数加一，
+因此令牌不能被挪用到其他密文，也不能在 A→B→A 之后被重放为第三次更新。
+更新后的份额仍可用 SVerify 对同一个 C' 公开验证。
+
+令牌只由云端检查是不够的：UpdatePolicy 把令牌追加到 CT.Updates，ODecVerWithContext 逐个验证这条令牌链，
+并检查最后一个令牌的策略就是密文当前的 Msp 与 Digest，用户与审计者由此区分授权的更新与云端擅自对 B 重新执行 OEnc。
+没有更新过的密文同样检查 Digest = headerDigest(Msp, NotBefore)，云端不能只替换 Msp 而保留原来的 Digest。
+令牌绑定 B 与 C'，因此 Rerandomize 拒绝已更新过策略的密文。
+
+注意 EncPolicyUpdatable 返回的 s 单独就能解密：keyGt = C / Base^s，数据拥有者须像保管 keyGt 一样保管 s。
+*/
+
+var ErrInvalidUpdateToken = errors.New("PVOABE: invalid policy update token")
+
+// UpdateToken 是数据拥有者授权把密文策略从 Prev 改为 Policy 的令牌
+type UpdateToken struct {
+	Policy  string
+	Version int       //本次更新后的版本号，即应用后 len(CT.Updates)
+	Digest  []byte    //新策略的摘要 headerDigest(新 MSP, CT.NotBefore)
+	Prev    []byte    //旧策略的摘要
+	T       *bn256.G2 //承诺 g2^k
+	Z       *big.Int  //响应 z = k + c·s
+}
+
+// PolicyUpdateToken 由数据拥有者执行，s 为 EncPolicyUpdatable 返回的加密随机数
+func (pvoabe *PVOABE) PolicyUpdateToken(pk *PublicKey, CT *CipherText, s *big.Int, newPolicy string) (*UpdateToken, error) {
+	msp, _, err := canonicalMSP(pk, newPolicy)
+	if err != nil {
+		return nil, err
+	}
+	digest := headerDigest(msp, CT.NotBefore)
+	if new(bn256.G2).ScalarBaseMult(s).String() != CT.Cprime.String() {
+		return nil, fmt.Errorf("s does not match the ciphertext")
+	}
+	k, err := rand.Int(rand.Reader, pvoabe.P)
+	if err != nil {
+		return nil, err
+	}
+	tok := &UpdateToken{Policy: newPolicy, Version: len(CT.Updates) + 1, Digest: digest, Prev: CT.Digest, T: pvoabe.Ops.G2Base(k)}
+	c := updateChallenge(CT, tok)
+	tok.Z = new(big.Int).Mul(c, s)
+	tok.Z.Add(tok.Z, k)
+	tok.Z.Mod(tok.Z, pvoabe.P)
+	return tok, nil
+}
+
+// VerifyUpdateToken 公开验证令牌：作用于 CT 当前的版本与策略，新摘要与 Policy 一致，且 g2^z = T·C'^c
+func (pvoabe *PVOABE) VerifyUpdateToken(pk *PublicKey, CT *CipherText, tok *UpdateToken) error {
+	if tok == nil || tok.Version != len(CT.Updates)+1 || !bytes.Equal(tok.Prev, CT.Digest) {
+		return fmt.Errorf("%w: token is for a different policy version", ErrInvalidUpdateToken)
+	}
+	_, err := pvoabe.checkToken(pk, CT, tok)
+	return err
+}
+
+// VerifyUpdateHistory 验证 CT.Updates 中的令牌链：版本号依次为 1, 2, …，每个令牌的 Prev 是前一个的 Digest，
+// 各令牌的证明都成立，且最后一个令牌的策略就是密文当前的 Msp 与 Digest。
+// 没有更新过的密文只检查 Digest 与 Msp 一致，不一致时返回 ErrHeaderMismatch
+func (pvoabe *PVOABE) VerifyUpdateHistory(pk *PublicKey, CT *CipherText) error {
+	if len(CT.Updates) == 0 {
+		return CT.checkHeader()
+	}
+	var msp *abe.MSP
+	for k, tok := range CT.Updates {
+		if tok == nil || tok.Version != k+1 || (k > 0 && !bytes.Equal(tok.Prev, CT.Updates[k-1].Digest)) {
+			return fmt.Errorf("%w: broken update chain at version %d", ErrInvalidUpdateToken, k+1)
+		}
+		var err error
+		if msp, err = pvoabe.checkToken(pk, CT, tok); err != nil {
+			return err
+		}
+	}
+	last := CT.Updates[len(CT.Updates)-1]
+	if !bytes.Equal(last.Digest, CT.Digest) || CT.Msp == nil || !bytes.Equal(LSSS.MSPDigest(msp), LSSS.MSPDigest(CT.Msp)) {
+		return fmt.Errorf("%w: ciphertext policy was not set by the last token", ErrInvalidUpdateToken)
+	}
+	return nil
+}
+
+// checkToken 检查令牌的新摘要与 Policy 一致且 g2^z = T·C'^c，返回新策略的 MSP
+func (pvoabe *PVOABE) checkToken(pk *PublicKey, CT *CipherText, tok *UpdateToken) (*abe.MSP, error) {
+	if tok.T == nil || tok.Z == nil {
+		return nil, ErrInvalidUpdateToken
+	}
+	msp, _, err := canonicalMSP(pk, tok.Policy)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(headerDigest(msp, CT.NotBefore), tok.Digest) {
+		return nil, fmt.Errorf("%w: digest does not match policy", ErrInvalidUpdateToken)
+	}
+	c := updateChallenge(CT, tok)
+	left := pvoabe.Ops.G2Base(tok.Z)
+	right := new(bn256.G2).Add(tok.T, pvoabe.Ops.G2Mul(CT.Cprime, c))
+	if left.String() != right.String() {
+		return nil, fmt.Errorf("%w: proof does not verify", ErrInvalidUpdateToken)
+	}
+	return msp, nil
+}
+
+// UpdatePolicy 由云端执行：验证令牌，在新策略下由 B 重新生成份额，更新密文头中的 Msp 与 Digest，
+// 并把令牌追加到 CT.Updates。
+// 旧份额须先通过 SVerify，保证更新前后对应同一个 C'
+func (pvoabe *PVOABE) UpdatePolicy(pk *PublicKey, CT *CipherText, ct map[int]*PVGSS.CipherText, tok *UpdateToken) (map[int]*PVGSS.CipherText, error) {
+	if err := pvoabe.VerifyUpdateToken(pk, CT, tok); err != nil {
+		return nil, err
+	}
+	if !pvoabe.OEncVer(pk, ct, CT.Cprime, CT.Msp) {
+		return nil, fmt.Errorf("current shares do not verify against C'")
+	}
+	msp, _, err := canonicalMSP(pk, tok.Policy)
+	if err != nil {
+		return nil, err
+	}
+	shares, err := pvoabe.OEnc(pk, CT.B, msp)
+	if err != nil {
+		return nil, err
+	}
+	CT.Msp = msp
+	CT.Digest = tok.Digest
+	CT.Updates = append(append([]*UpdateToken(nil), CT.Updates...), tok)
+	return shares, nil
+}
+
+// updateChallenge 计算 c = H("PVOABE-policy-update", B, C', Version, Prev, Digest, T)
+func updateChallenge(CT *CipherText, tok *UpdateToken) *big.Int {
+	h := sha256.New()
+	h.Write([]byte("PVOABE-policy-update"))
+	h.Write(CT.B.Marshal())
+	h.Write(CT.Cprime.Marshal())
+	h.Write(binary.BigEndian.AppendUint64(nil, uint64(tok.Version)))
+	h.Write(tok.Prev)
+	h.Write(tok.Digest)
+	h.Write(tok.T.Marshal())
+	c := new(big.Int).SetBytes(h.Sum(nil))
+	return c.Mod(c, bn256.Order)
+}
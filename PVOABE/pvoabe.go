@@ -0,0 +1,286 @@
+package PVOABE
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/AUKUS561/PVOABE/DLEQ"
+	"github.com/AUKUS561/PVOABE/PVGSS"
+	"github.com/AUKUS561/PVOABE/opcount"
+	"github.com/AUKUS561/PVOABE/policy"
+	"github.com/AUKUS561/PVOABE/policygen"
+	"github.com/fentec-project/bn256"
+	"github.com/fentec-project/gofe/abe"
+	"github.com/fentec-project/gofe/sample"
+)
+
+type PVOABE struct {
+	pvgss *PVGSS.PVGSS
+	P     *big.Int
+	Ops   *opcount.Counter //运算计数，nil 表示不计数；用 SetCounter 同时设置内部的 PVGSS
+}
+
+func NewPVOABE() *PVOABE {
+	return &PVOABE{
+		pvgss: PVGSS.NewPVGSS(),
+		P:     bn256.Order,
+	}
+}
+
+type PublicKey struct {
+	PP    *PVGSS.PublicParameter
+	Base  *bn256.GT //e(g,g)^alpha，吊销后为当前纪元的 e(g,g)^{alpha+Δ}
+	Epoch int       //当前纪元，每次吊销加一
+	W     *bn256.G2 //g2^b，NewTracer 设置后密文携带 W^s，用于可追踪密钥；nil 表示不追踪
+}
+
+// SetCounter 让 PVOABE 及其内部的 PVGSS 使用同一个计数器，c 为 nil 时关闭计数
+func (pvoabe *PVOABE) SetCounter(c *opcount.Counter) {
+	pvoabe.Ops = c
+	pvoabe.pvgss.Ops = c
+}
+
+// Generate an access structure
+func GeneratePolicy(attrCount int) string {
+	return policygen.GeneratePolicy(attrCount)
+}
+
+func (pvoabe *PVOABE) Setup() (*big.Int, *PublicKey, *PVGSS.SecretKey, error) {
+	var attributeUniverse []string
+	for i := 1; i <= 100; i++ {
+		attributeUniverse = append(attributeUniverse, "Attr"+strconv.Itoa(i)) // Attr1, Attr2, ..., Attr100
+	}
+	return pvoabe.SetupUniverse(attributeUniverse)
+}
+
+// SetupUniverse 与 Setup 相同，但使用调用方给定的属性全集U
+// 需要 NOT 门时传入 policy.WithNegations(U)，KeyGen 会为用户不持有的属性签发 not_x
+func (pvoabe *PVOABE) SetupUniverse(attributeUniverse []string) (*big.Int, *PublicKey, *PVGSS.SecretKey, error) {
+	PP, sk, err := pvoabe.pvgss.Setup(attributeUniverse)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	sampler := sample.NewUniformRange(big.NewInt(1), pvoabe.P)
+	alpha, _ := sampler.Sample()
+	g1 := new(bn256.G1).ScalarBaseMult(big.NewInt(1))
+	g2 := new(bn256.G2).ScalarBaseMult(big.NewInt(1))
+	res := pvoabe.Ops.Pair(g1, g2)       //e(g,g)
+	base := pvoabe.Ops.GTExp(res, alpha) //e(g,g)^alpha
+	return alpha, &PublicKey{PP: PP, Base: base}, sk, nil
+}
+
+func (pvoabe *PVOABE) KeyGen(pk *PublicKey, mk *big.Int, su []string) (*PVGSS.OSK, *bn256.G1, error) {
+	OSK, err := pvoabe.pvgss.KeyGen(pk.PP, su)
+	if err != nil {
+		return nil, nil, err
+	}
+	//DSK=g^alpha h^t
+	g1 := new(bn256.G1).ScalarBaseMult(big.NewInt(1))
+	part1 := pvoabe.Ops.G1Mul(g1, mk)
+	DSK := new(bn256.G1).Add(part1, OSK.Ht)
+	return OSK, DSK, nil
+}
+
+type CipherText struct {
+	C      *bn256.GT
+	Cprime *bn256.G2
+	B      *bn256.G1
+	Msp    *abe.MSP
+	Digest []byte    //策略摘要 headerDigest(Msp, NotBefore)，作为密文头并绑定到 ODec 的 DLEQ 证明
+	Epoch  int       //加密或最近一次 UpdateCiphertext 时的纪元
+	CW     *bn256.G2 //W^s，仅在公钥启用追踪时设置
+	//keyGt 的可重新随机化承诺，Dec 用它发现错误的 R 或 DSK；为空时 Dec 失败，见 commit.go
+	Tag *KeyTag
+	//EncHidden 生成的隐藏取值行的盲化公钥，OEncHidden 与 OEncVerHidden 使用
+	Hidden map[int]*PVGSS.HiddenRow
+	//UpdatePolicy 应用过的策略更新令牌链，ODecVerWithContext 逐个验证
+	Updates []*UpdateToken
+	//EncTimed 设置的可读时间，零值表示不受时间限制
+	NotBefore time.Time
+	//symEnc []byte
+	//iv     []byte
+}
+
+func (pvoabe *PVOABE) Enc(pk *PublicKey, attrNum int) (*CipherText, *bn256.GT, error) {
+	//生成访问控制策略
+	//policy := "Attr1 OR (Attr2 AND Attr3)"
+	return pvoabe.EncPolicy(pk, GeneratePolicy(attrNum))
+}
+
+// EncPolicy 按给定的访问控制策略加密，策略可含 NOT 门，如 "Employee AND NOT Contractor"
+func (pvoabe *PVOABE) EncPolicy(pk *PublicKey, accessPolicy string) (*CipherText, *bn256.GT, error) {
+	CT, keyGt, _, err := pvoabe.encPolicy(pk, accessPolicy)
+	return CT, keyGt, err
+}
+
+// EncPolicyUpdatable 与 EncPolicy 相同，但同时返回加密随机数 s。
+// 数据拥有者保存 s，之后可用 PolicyUpdateToken 授权云端修改密文策略。
+// s 单独就能解密这条记录（keyGt = C / Base^s），须像 keyGt 一样保密
+func (pvoabe *PVOABE) EncPolicyUpdatable(pk *PublicKey, accessPolicy string) (*CipherText, *bn256.GT, *big.Int, error) {
+	return pvoabe.encPolicy(pk, accessPolicy)
+}
+
+func (pvoabe *PVOABE) encPolicy(pk *PublicKey, accessPolicy string) (*CipherText, *bn256.GT, *big.Int, error) {
+	//先规范化策略（扁平化、去重、吸收冗余子句、NOT x 编译为 not_x），再构建msp矩阵
+	msp, _, err := canonicalMSP(pk, accessPolicy)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return pvoabe.encMSP(pk, msp, headerDigest(msp, time.Time{}))
+}
+
+// encMSP 在给定的 msp 下加密，digest 作为密文头中的策略摘要
+func (pvoabe *PVOABE) encMSP(pk *PublicKey, msp *abe.MSP, digest []byte) (*CipherText, *bn256.GT, *big.Int, error) {
+	//s<-Zp
+	sampler := sample.NewUniformRange(big.NewInt(1), pk.PP.Order)
+	s, _ := sampler.Sample()
+	z, _ := sampler.Sample()
+
+	//生成一个随机的GT元素作为对称密钥
+	_, keyGt, err := bn256.RandomGT(rand.Reader)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return pvoabe.encWith(pk, msp, digest, s, z, keyGt), keyGt, s, nil
+}
+
+// encWith 用给定的 s、承诺随机数 z 与 keyGt 计算密文头，EncCCA 由此使用派生的 s 与 z
+func (pvoabe *PVOABE) encWith(pk *PublicKey, msp *abe.MSP, digest []byte, s, z *big.Int, keyGt *bn256.GT) *CipherText {
+	//计算B,C'
+	B := pvoabe.Ops.G1Mul(pk.PP.Pk, s)      //B=pk^s
+	Cprime := pvoabe.Ops.G2Base(s)          //C'
+	abeTerm := pvoabe.Ops.GTExp(pk.Base, s) //e(g,g)^alpha s
+	C := new(bn256.GT).Add(keyGt, abeTerm)  //C = keyGt · e(h, g)αs
+	var CW *bn256.G2
+	if pk.W != nil {
+		CW = pvoabe.Ops.G2Mul(pk.W, s)
+	}
+
+	return &CipherText{
+		C:      C,
+		Cprime: Cprime,
+		B:      B,
+		Msp:    msp,
+		Digest: digest,
+		Epoch:  pk.Epoch,
+		CW:     CW,
+		Tag:    pvoabe.keyTag(keyGt, digest, z),
+		//symEnc: symEnc,
+		//iv:     iv,
+	}
+}
+
+// canonicalMSP 规范化策略并检查其中的属性都在公共参数中
+func canonicalMSP(pk *PublicKey, accessPolicy string) (*abe.MSP, []byte, error) {
+	msp, digest, err := policy.Canonicalize(accessPolicy)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, attr := range msp.RowToAttrib {
+		if _, ok := pk.PP.PkXs[attr]; !ok {
+			return nil, nil, fmt.Errorf("attribute %s not in public parameters", attr)
+		}
+	}
+	return msp, digest, nil
+}
+
+func (pvoabe *PVOABE) OEnc(pk *PublicKey, B *bn256.G1, msp *abe.MSP) (map[int]*PVGSS.CipherText, error) {
+	ct := make(map[int]*PVGSS.CipherText)
+	ct, err := pvoabe.pvgss.Share(pk.PP, B, msp)
+	if err != nil {
+		return nil, err
+	}
+	return ct, nil
+}
+
+func (pvoabe *PVOABE) OEncVer(pk *PublicKey, ct map[int]*PVGSS.CipherText, Cprime *bn256.G2, msp *abe.MSP) bool {
+	return pvoabe.pvgss.SVerify(pk.PP, ct, Cprime, msp)
+}
+
+func (pvoabe *PVOABE) ODec(pk *PublicKey, ct map[int]*PVGSS.CipherText, msp *abe.MSP, OSK *PVGSS.OSK, sk *PVGSS.SecretKey) (*bn256.GT, *DLEQ.Prfs, error) {
+	R, Proof, err := pvoabe.pvgss.Recon(pk.PP, ct, msp, OSK, sk)
+	if err != nil {
+		return nil, nil, err
+	}
+	return R, Proof, nil
+}
+
+func (pvoabe *PVOABE) ODecVer(pk *PublicKey, ct map[int]*PVGSS.CipherText, msp *abe.MSP, OSK *PVGSS.OSK, R *bn256.GT, Proof *DLEQ.Prfs) bool {
+	return pvoabe.pvgss.DVerify(pk.PP, ct, msp, OSK, R, Proof)
+}
+
+// ODecWithContext 与 ODec 相同，但证明 π 绑定密文头中的策略摘要 CT.Digest
+func (pvoabe *PVOABE) ODecWithContext(pk *PublicKey, ct map[int]*PVGSS.CipherText, CT *CipherText, OSK *PVGSS.OSK, sk *PVGSS.SecretKey) (*bn256.GT, *DLEQ.Prfs, error) {
+	return pvoabe.pvgss.ReconWithContext(pk.PP, ct, CT.Msp, OSK, sk, CT.Digest)
+}
+
+// ODecVerWithContext 验证 ODecWithContext 的输出，并验证密文的策略摘要与策略更新令牌链，见 VerifyUpdateHistory
+func (pvoabe *PVOABE) ODecVerWithContext(pk *PublicKey, ct map[int]*PVGSS.CipherText, CT *CipherText, OSK *PVGSS.OSK, R *bn256.GT, Proof *DLEQ.Prfs) bool {
+	if pvoabe.VerifyUpdateHistory(pk, CT) != nil {
+		return false
+	}
+	return pvoabe.pvgss.DVerifyWithContext(pk.PP, ct, CT.Msp, OSK, R, Proof, CT.Digest)
+}
+
+// Dec 计算 keyGt 并检查密文头的策略摘要与密钥承诺
+func (pvoabe *PVOABE) Dec(CT *CipherText, DSK *bn256.G1, R *bn256.GT) (*bn256.GT, error) {
+	if err := CT.checkHeader(); err != nil {
+		return nil, err
+	}
+	keyGt, err := pvoabe.DecUnchecked(CT, DSK, R)
+	if err != nil {
+		return nil, err
+	}
+	if err := pvoabe.checkKeyTag(CT.Tag, keyGt, CT.originDigest()); err != nil {
+		return nil, err
+	}
+	return keyGt, nil
+}
+
+// DecUnchecked 只计算 keyGt = C / (e(DSK, C') / R)，不检查密钥承诺，
+// 仅用于没有 Tag 的旧密文；调用方须另行用 ODecVer 验证 R
+func (pvoabe *PVOABE) DecUnchecked(CT *CipherText, DSK *bn256.G1, R *bn256.GT) (*bn256.GT, error) {
+	if CT.C == nil || DSK == nil || R == nil {
+		return nil, fmt.Errorf("nil input")
+	}
+
+	// 计算 e(DSK, C')
+	pairDSKCprime := pvoabe.Ops.Pair(DSK, CT.Cprime)
+	T := new(bn256.GT).Set(pairDSKCprime)
+
+	// 计算 R 的逆元
+	RInv := new(bn256.GT).Neg(R)
+
+	// T = e(DSK, C') * R^(-1) = e(g,g)^(αs)
+	T = new(bn256.GT).Add(T, RInv)
+
+	// 现在计算 keyGT = C / T = C * T^(-1)
+	TInv := new(bn256.GT).Neg(T) // T 的逆元
+	keyGt := new(bn256.GT).Add(CT.C, TInv)
+	return keyGt, nil
+}
+
+// Delegate 由持有 (OSK, DSK) 的用户执行，派生只含属性子集 subset 的子密钥，见 PVGSS.Delegate。
+// DSK = g^α h^t 随 t 一起重新随机化：DSK' = DSK · (h^t)^{-1} · h^{t+t'}。
+// 带标识的密钥须给出 Authority.DelegationSeed 返回的 seed，子密钥获得由 seed 派生的新标识，
+// 与父密钥不可链接，吊销父密钥时一并被吊销；不带标识的密钥 seed 可为空
+func (pvoabe *PVOABE) Delegate(pk *PublicKey, OSK *PVGSS.OSK, DSK *bn256.G1, seed []byte, subset []string) (*PVGSS.OSK, *bn256.G1, error) {
+	if OSK.ID != "" && len(seed) == 0 {
+		return nil, nil, fmt.Errorf("cannot delegate key %s without its delegation seed", OSK.ID)
+	}
+	sub, err := pvoabe.pvgss.Delegate(pk.PP, OSK, subset)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(seed) > 0 {
+		if sub.ID, err = delegatedID(seed); err != nil {
+			return nil, nil, err
+		}
+	}
+	subDSK := new(bn256.G1).Add(DSK, new(bn256.G1).Neg(OSK.Ht))
+	subDSK.Add(subDSK, sub.Ht)
+	return sub, subDSK, nil
+}
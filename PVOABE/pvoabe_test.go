@@ -0,0 +1,1274 @@
+package PVOABE
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/AUKUS561/PVOABE/DLEQ"
+	"github.com/AUKUS561/PVOABE/LSSS"
+	"github.com/AUKUS561/PVOABE/PVGSS"
+	"github.com/AUKUS561/PVOABE/opcount"
+	"github.com/AUKUS561/PVOABE/policy"
+	"github.com/fentec-project/bn256"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMainFlow(t *testing.T) {
+	n := 1000
+	attrNum := 3
+
+	// 初始化 PVOABE
+	pvoabe := NewPVOABE()
+
+	// 测试 Setup
+	alpha, pk, sk, err := pvoabe.Setup()
+	require.NoError(t, err, "Setup should not return an error")
+	require.NotNil(t, alpha, "Alpha should not be nil")
+	require.NotNil(t, pk, "PublicKey should not be nil")
+	require.NotNil(t, sk, "SecretKey should not be nil")
+
+	// 测试 KeyGen
+	//userAttrs := "海南大学 博士"
+	var userAttrs []string
+	for i := 1; i <= attrNum; i++ {
+		userAttrs = append(userAttrs, "Attr"+strconv.Itoa(i)) // A1, A2, ..., A100
+	}
+	var osk *PVGSS.OSK
+	var dsk *bn256.G1
+	starttime := time.Now().UnixMilli()
+	for i := 0; i < int(n); i++ {
+		osk, dsk, err = pvoabe.KeyGen(pk, alpha, userAttrs)
+	}
+	endtime := time.Now().UnixMilli()
+	fmt.Printf("KeyGen algorithm is %.4f ms\n", float64(endtime-starttime)/float64(n))
+	require.NoError(t, err, "KeyGen should not return an error")
+	require.NotNil(t, osk, "OSK should not be nil")
+	require.NotNil(t, dsk, "DSK should not be nil")
+
+	// 测试 Encrypt
+	//message := "Hello, PVOABE!" //生成要加密的msg
+	var ct *CipherText
+	var keyGT *bn256.GT
+	starttime = time.Now().UnixMilli()
+	for i := 0; i < int(n); i++ {
+		ct, keyGT, err = pvoabe.Enc(pk, attrNum)
+	}
+	endtime = time.Now().UnixMilli()
+	fmt.Printf("Enc algorithm is %.4f ms\n", float64(endtime-starttime)/float64(n))
+	require.NoError(t, err, "Enc should not return an error")
+	require.NotNil(t, ct, "Ciphertext should not be nil")
+
+	//测试OEnc
+	var shares map[int]*PVGSS.CipherText
+	starttime = time.Now().UnixMilli()
+	for i := 0; i < int(n); i++ {
+		shares, err = pvoabe.OEnc(pk, ct.B, ct.Msp)
+	}
+	endtime = time.Now().UnixMilli()
+	fmt.Printf("OEnc algorithm is %.4f ms\n", float64(endtime-starttime)/float64(n))
+	require.NoError(t, err, "OEnc should not return an error")
+
+	//测试OEncVer
+	var resultOEnc bool
+	starttime = time.Now().UnixMilli()
+	for i := 0; i < int(n); i++ {
+		resultOEnc = pvoabe.OEncVer(pk, shares, ct.Cprime, ct.Msp)
+	}
+	endtime = time.Now().UnixMilli()
+	fmt.Printf("OEncVer algorithm is %.4f ms\n", float64(endtime-starttime)/float64(n))
+	t.Logf("OEncVer Result : %v", resultOEnc)
+
+	//测试ODec
+	var R *bn256.GT
+	var Proof *DLEQ.Prfs
+	starttime = time.Now().UnixMilli()
+	for i := 0; i < int(n); i++ {
+		R, Proof, err = pvoabe.ODec(pk, shares, ct.Msp, osk, sk)
+	}
+	endtime = time.Now().UnixMilli()
+	fmt.Printf("ODec algorithm is %.4f ms\n", float64(endtime-starttime)/float64(n))
+	require.NoError(t, err, "ODec should not return an error")
+
+	//测试ODecVer
+	var resultODec bool
+	starttime = time.Now().UnixMilli()
+	for i := 0; i < int(n); i++ {
+		resultODec = pvoabe.ODecVer(pk, shares, ct.Msp, osk, R, Proof)
+	}
+	endtime = time.Now().UnixMilli()
+	fmt.Printf("ODecVer algorithm is %.4f ms\n", float64(endtime-starttime)/float64(n))
+	t.Logf("ODecVer Result : %v", resultODec)
+
+	//测试解密
+	var decryptedMessage *bn256.GT
+	starttime = time.Now().UnixMilli()
+	for i := 0; i < int(n); i++ {
+		decryptedMessage, err = pvoabe.Dec(ct, dsk, R)
+	}
+	endtime = time.Now().UnixMilli()
+	fmt.Printf("Dec algorithm is %.4f ms\n", float64(endtime-starttime)/float64(n))
+
+	require.NoError(t, err, "Decrypt should not return an error")
+	require.Equal(t, keyGT, decryptedMessage, "Decrypted message should match the original message")
+	t.Logf("Decrypted Message: %s", decryptedMessage)
+}
+
+func TestPolicyDigestBinding(t *testing.T) {
+	pvoabe := NewPVOABE()
+	alpha, pk, sk, err := pvoabe.Setup()
+	require.NoError(t, err)
+	osk, dsk, err := pvoabe.KeyGen(pk, alpha, []string{"Attr1", "Attr2", "Attr3"})
+	require.NoError(t, err)
+
+	ct, keyGT, err := pvoabe.Enc(pk, 3)
+	require.NoError(t, err)
+	require.Len(t, ct.Digest, 32, "ciphertext header should carry the policy digest")
+	shares, err := pvoabe.OEnc(pk, ct.B, ct.Msp)
+	require.NoError(t, err)
+
+	R, proof, err := pvoabe.ODecWithContext(pk, shares, ct, osk, sk)
+	require.NoError(t, err)
+	require.True(t, pvoabe.ODecVerWithContext(pk, shares, ct, osk, R, proof))
+
+	// 证明不能被挪用到另一个策略摘要下
+	other := *ct
+	other.Digest = make([]byte, len(ct.Digest))
+	require.False(t, pvoabe.ODecVerWithContext(pk, shares, &other, osk, R, proof))
+	require.False(t, pvoabe.ODecVer(pk, shares, ct.Msp, osk, R, proof))
+
+	decrypted, err := pvoabe.Dec(ct, dsk, R)
+	require.NoError(t, err)
+	require.Equal(t, keyGT.String(), decrypted.String())
+
+	// 云端只凭 B 在另一个 MSP 下重新执行 OEnc，只替换 Msp，保留 Digest 与 Tag：摘要可由 Msp 重新计算，各路径都拒绝
+	swapped := *ct
+	swapped.Msp, _, err = canonicalMSP(pk, "Attr1 OR Attr2")
+	require.NoError(t, err)
+	swappedShares, err := pvoabe.OEnc(pk, swapped.B, swapped.Msp)
+	require.NoError(t, err)
+	require.True(t, pvoabe.OEncVer(pk, swappedShares, swapped.Cprime, swapped.Msp))
+	R, proof, err = pvoabe.ODecWithContext(pk, swappedShares, &swapped, osk, sk)
+	require.NoError(t, err)
+	require.ErrorIs(t, pvoabe.VerifyUpdateHistory(pk, &swapped), ErrHeaderMismatch)
+	require.False(t, pvoabe.ODecVerWithContext(pk, swappedShares, &swapped, osk, R, proof))
+	_, err = pvoabe.Dec(&swapped, dsk, R)
+	require.ErrorIs(t, err, ErrHeaderMismatch)
+	// 连 Digest 一起替换时，密钥承诺不符
+	swapped.Digest = headerDigest(swapped.Msp, swapped.NotBefore)
+	R, _, err = pvoabe.ODecWithContext(pk, swappedShares, &swapped, osk, sk)
+	require.NoError(t, err)
+	_, err = pvoabe.Dec(&swapped, dsk, R)
+	require.ErrorIs(t, err, ErrDecryptionFailed)
+}
+
+func TestNotGatePolicy(t *testing.T) {
+	pvoabe := NewPVOABE()
+	alpha, pk, sk, err := pvoabe.SetupUniverse(policy.WithNegations([]string{"Employee", "Contractor"}))
+	require.NoError(t, err)
+
+	ct, keyGT, err := pvoabe.EncPolicy(pk, "Employee AND NOT Contractor")
+	require.NoError(t, err)
+	shares, err := pvoabe.OEnc(pk, ct.B, ct.Msp)
+	require.NoError(t, err)
+	require.True(t, pvoabe.OEncVer(pk, shares, ct.Cprime, ct.Msp))
+
+	osk, dsk, err := pvoabe.KeyGen(pk, alpha, []string{"Employee"})
+	require.NoError(t, err)
+	R, proof, err := pvoabe.ODec(pk, shares, ct.Msp, osk, sk)
+	require.NoError(t, err)
+	require.True(t, pvoabe.ODecVer(pk, shares, ct.Msp, osk, R, proof))
+	decrypted, err := pvoabe.Dec(ct, dsk, R)
+	require.NoError(t, err)
+	require.Equal(t, keyGT.String(), decrypted.String())
+
+	oskC, _, err := pvoabe.KeyGen(pk, alpha, []string{"Employee", "Contractor"})
+	require.NoError(t, err)
+	_, _, err = pvoabe.ODec(pk, shares, ct.Msp, oskC, sk)
+	require.Error(t, err, "contractor must be rejected")
+}
+
+func TestRevocation(t *testing.T) {
+	pvoabe := NewPVOABE()
+	alpha, pk, sk, err := pvoabe.SetupUniverse([]string{"Doctor", "Cardiology", "Nurse"})
+	require.NoError(t, err)
+	auth, err := NewAuthority(pk, alpha)
+	require.NoError(t, err)
+
+	aliceOSK, aliceDSK, err := pvoabe.KeyGenID(pk, auth, "alice", []string{"Doctor", "Cardiology"})
+	require.NoError(t, err)
+	bobOSK, bobDSK, err := pvoabe.KeyGenID(pk, auth, "bob", []string{"Doctor", "Cardiology"})
+	require.NoError(t, err)
+	_, _, err = pvoabe.KeyGenID(pk, auth, "bob", []string{"Nurse"})
+	require.Error(t, err, "identifiers are unique")
+
+	decrypt := func(CT *CipherText, osk *PVGSS.OSK, dsk *bn256.G1) string {
+		shares, err := pvoabe.OEnc(pk, CT.B, CT.Msp)
+		require.NoError(t, err)
+		R, _, err := pvoabe.ODecWithContext(pk, shares, CT, osk, sk)
+		require.NoError(t, err)
+		key, err := pvoabe.Dec(CT, dsk, R)
+		require.NoError(t, err)
+		return key.String()
+	}
+
+	old, oldKey, err := pvoabe.EncPolicy(pk, "Doctor AND Cardiology")
+	require.NoError(t, err)
+	require.Equal(t, 0, old.Epoch)
+	require.Equal(t, oldKey.String(), decrypt(old, bobOSK, bobDSK))
+
+	// 吊销 bob，进入纪元 1
+	rl, ek, err := pvoabe.Revoke(pk, auth, "bob")
+	require.NoError(t, err)
+	require.Equal(t, 1, rl.Epoch)
+	require.Equal(t, 1, pk.Epoch)
+	require.NoError(t, rl.Verify(auth.VerifyKey))
+	require.True(t, rl.Contains("bob"))
+	require.False(t, rl.Contains("alice"))
+	_, _, err = pvoabe.Revoke(pk, auth, "carol")
+	require.Error(t, err, "unknown identifiers cannot be revoked")
+
+	// 篡改后的吊销列表无法通过验证
+	forged := &RevocationList{Epoch: rl.Epoch, Sig: rl.Sig}
+	require.ErrorIs(t, forged.Verify(auth.VerifyKey), ErrInvalidSignature)
+
+	// 云端更新旧密文，未更新前拒绝 ODec
+	shares, err := pvoabe.OEnc(pk, old.B, old.Msp)
+	require.NoError(t, err)
+	_, _, err = pvoabe.ODecWithRevocation(pk, rl, auth.VerifyKey, shares, old, aliceOSK, sk)
+	require.ErrorIs(t, err, ErrEpochMismatch)
+	require.NoError(t, pvoabe.UpdateCiphertext(old, ek))
+	require.ErrorIs(t, pvoabe.UpdateCiphertext(old, ek), ErrEpochMismatch)
+
+	// 云端拒绝 bob
+	_, _, err = pvoabe.ODecWithRevocation(pk, rl, auth.VerifyKey, shares, old, bobOSK, sk)
+	require.ErrorIs(t, err, ErrRevoked)
+	_, _, err = pvoabe.ODecWithRevocation(pk, forged, auth.VerifyKey, shares, old, bobOSK, sk)
+	require.ErrorIs(t, err, ErrInvalidSignature)
+
+	// alice 更新 DSK 后仍可解密更新后的旧密文与新纪元的密文
+	aliceDSK = pvoabe.UpdateDSK(aliceDSK, ek)
+	R, proof, err := pvoabe.ODecWithRevocation(pk, rl, auth.VerifyKey, shares, old, aliceOSK, sk)
+	require.NoError(t, err)
+	require.True(t, pvoabe.ODecVerWithContext(pk, shares, old, aliceOSK, R, proof))
+	key, err := pvoabe.Dec(old, aliceDSK, R)
+	require.NoError(t, err)
+	require.Equal(t, oldKey.String(), key.String())
+
+	fresh, freshKey, err := pvoabe.EncPolicy(pk, "Doctor AND Cardiology")
+	require.NoError(t, err)
+	require.Equal(t, 1, fresh.Epoch)
+	require.Equal(t, freshKey.String(), decrypt(fresh, aliceOSK, aliceDSK))
+
+	// 即使绕过云端检查拿到 R，bob 的旧 DSK 也解不开更新后的密文与新密文
+	for _, CT := range []*CipherText{old, fresh} {
+		shares, err := pvoabe.OEnc(pk, CT.B, CT.Msp)
+		require.NoError(t, err)
+		R, _, err := pvoabe.ODecWithContext(pk, shares, CT, bobOSK, sk)
+		require.NoError(t, err)
+		_, err = pvoabe.Dec(CT, bobDSK, R)
+		require.ErrorIs(t, err, ErrDecryptionFailed)
+	}
+
+	// 新纪元签发的密钥直接使用 α_1
+	carolOSK, carolDSK, err := pvoabe.KeyGenID(pk, auth, "carol", []string{"Doctor", "Cardiology"})
+	require.NoError(t, err)
+	require.Equal(t, freshKey.String(), decrypt(fresh, carolOSK, carolDSK))
+}
+
+func TestAttributeRevocation(t *testing.T) {
+	pvoabe := NewPVOABE()
+	alpha, pk, sk, err := pvoabe.SetupUniverse([]string{"Doctor", "Cardiology", "Oncology"})
+	require.NoError(t, err)
+	aliceOSK, aliceDSK, err := pvoabe.KeyGen(pk, alpha, []string{"Doctor", "Cardiology"})
+	require.NoError(t, err)
+	bobOSK, bobDSK, err := pvoabe.KeyGen(pk, alpha, []string{"Doctor", "Cardiology", "Oncology"})
+	require.NoError(t, err)
+
+	card, cardKey, err := pvoabe.EncPolicy(pk, "Doctor AND Cardiology")
+	require.NoError(t, err)
+	onco, oncoKey, err := pvoabe.EncPolicy(pk, "Doctor AND Oncology")
+	require.NoError(t, err)
+	cardShares, err := pvoabe.OEnc(pk, card.B, card.Msp)
+	require.NoError(t, err)
+	oncoShares, err := pvoabe.OEnc(pk, onco.B, onco.Msp)
+	require.NoError(t, err)
+
+	// bob 离开 Cardiology，但保留 Oncology
+	upd, err := pvoabe.RevokeAttribute(pk, sk, "Cardiology")
+	require.NoError(t, err)
+	ku, err := pvoabe.AttrKeyUpdate(upd, aliceOSK)
+	require.NoError(t, err)
+	require.NoError(t, pvoabe.ApplyAttrKeyUpdate(aliceOSK, ku))
+	n, err := pvoabe.UpdateShares(upd, cardShares, card)
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	n, err = pvoabe.UpdateShares(upd, oncoShares, onco)
+	require.NoError(t, err)
+	require.Zero(t, n, "ciphertexts without the attribute are untouched")
+	require.True(t, pvoabe.OEncVer(pk, cardShares, card.Cprime, card.Msp))
+
+	R, _, err := pvoabe.ODecWithContext(pk, cardShares, card, aliceOSK, sk)
+	require.NoError(t, err)
+	key, err := pvoabe.Dec(card, aliceDSK, R)
+	require.NoError(t, err)
+	require.Equal(t, cardKey.String(), key.String())
+
+	_, _, err = pvoabe.ODecWithContext(pk, cardShares, card, bobOSK, sk)
+	require.Error(t, err)
+	R, _, err = pvoabe.ODecWithContext(pk, oncoShares, onco, bobOSK, sk)
+	require.NoError(t, err)
+	key, err = pvoabe.Dec(onco, bobDSK, R)
+	require.NoError(t, err)
+	require.Equal(t, oncoKey.String(), key.String())
+}
+
+func TestTimeBound(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	day := func(d int) time.Time { return start.Add(time.Duration(d)*24*time.Hour + time.Hour) }
+	tl := NewTimeline(start, 24*time.Hour, 4)
+	now := day(5)
+	tl.Clock = func() time.Time { return now }
+
+	// 覆盖集合恰好覆盖区间；[0, to] 的覆盖集合与路径相交当且仅当纪元不晚于 to
+	for from := 0; from < 16; from++ {
+		for to := from; to < 16; to++ {
+			cover := tl.Cover(from, to)
+			require.LessOrEqual(t, len(cover), 2*tl.Depth)
+			covered := map[int]int{}
+			for _, a := range cover {
+				var level, index int
+				_, err := fmt.Sscanf(a, "epoch_%d_%d", &level, &index)
+				require.NoError(t, err)
+				lo, hi := tl.leaves(level, index)
+				for e := lo; e <= hi; e++ {
+					covered[e]++
+				}
+			}
+			for e := 0; e < 16; e++ {
+				want := 0
+				if from <= e && e <= to {
+					want = 1
+				}
+				require.Equal(t, want, covered[e], "[%d,%d] epoch %d", from, to, e)
+			}
+		}
+	}
+	for to := 0; to < 16; to++ {
+		cover := tl.Cover(0, to)
+		require.LessOrEqual(t, len(cover), tl.Depth+1)
+		for e := 0; e < 16; e++ {
+			hit := false
+			for _, a := range tl.Path(e) {
+				for _, b := range cover {
+					hit = hit || a == b
+				}
+			}
+			require.Equal(t, e <= to, hit, "until %d, epoch %d", to, e)
+		}
+	}
+	_, err := tl.Epoch(start.Add(-time.Hour))
+	require.ErrorIs(t, err, ErrOutsideTimeline)
+	_, err = tl.Epoch(day(16))
+	require.ErrorIs(t, err, ErrOutsideTimeline)
+
+	pvoabe := NewPVOABE()
+	alpha, pk, sk, err := pvoabe.SetupUniverse(append([]string{"Doctor"}, tl.Attributes()...))
+	require.NoError(t, err)
+	auth, err := NewAuthority(pk, alpha)
+	require.NoError(t, err)
+	aliceOSK, aliceDSK, aliceKV, err := pvoabe.KeyGenTimed(pk, auth, tl, []string{"Doctor"}, day(2), day(9))
+	require.NoError(t, err)
+	bobOSK, bobDSK, bobKV, err := pvoabe.KeyGenTimed(pk, auth, tl, []string{"Doctor"}, day(10), day(15))
+	require.NoError(t, err)
+
+	decrypt := func(CT *CipherText, osk *PVGSS.OSK, dsk *bn256.G1, kv *KeyValidity) (string, error) {
+		shares, err := pvoabe.OEnc(pk, CT.B, CT.Msp)
+		require.NoError(t, err)
+		R, proof, err := pvoabe.ODecTimed(pk, tl, auth.VerifyKey, shares, CT, osk, kv, sk)
+		if err != nil {
+			return "", err
+		}
+		require.True(t, pvoabe.ODecVerWithContext(pk, shares, CT, osk, R, proof))
+		key, err := pvoabe.Dec(CT, dsk, R)
+		require.NoError(t, err)
+		return key.String(), nil
+	}
+
+	// 第 5 天加密：alice 可解密，bob 的密钥尚未生效
+	ct, key, err := pvoabe.EncTimed(pk, tl, "Doctor", time.Time{})
+	require.NoError(t, err)
+	got, err := decrypt(ct, aliceOSK, aliceDSK, aliceKV)
+	require.NoError(t, err)
+	require.Equal(t, key.String(), got)
+	_, err = decrypt(ct, bobOSK, bobDSK, bobKV)
+	require.ErrorIs(t, err, ErrKeyNotYetValid)
+
+	// 第 11 天起可读的记录
+	later, laterKey, err := pvoabe.EncTimed(pk, tl, "Doctor", day(11))
+	require.NoError(t, err)
+	_, err = decrypt(later, aliceOSK, aliceDSK, aliceKV)
+	require.ErrorIs(t, err, ErrNotYetReadable)
+
+	// 时钟走到第 12 天：alice 的密钥过期；bob 之后签发的密钥既能读取第 11 天的记录，也能读取第 5 天的记录
+	now = day(12)
+	_, err = decrypt(ct, aliceOSK, aliceDSK, aliceKV)
+	require.ErrorIs(t, err, ErrKeyExpired)
+	got, err = decrypt(later, bobOSK, bobDSK, bobKV)
+	require.NoError(t, err)
+	require.Equal(t, laterKey.String(), got)
+	got, err = decrypt(ct, bobOSK, bobDSK, bobKV)
+	require.NoError(t, err)
+	require.Equal(t, key.String(), got)
+
+	// 有效期来自权威机构的签名：没有签名、挪用他人的签名或删去 epoch_* 属性都无法绕过过期检查
+	_, err = decrypt(ct, aliceOSK, aliceDSK, nil)
+	require.ErrorIs(t, err, ErrInvalidValidity)
+	_, err = decrypt(ct, aliceOSK, aliceDSK, bobKV)
+	require.ErrorIs(t, err, ErrInvalidValidity)
+	extended := *aliceKV
+	extended.To = 15
+	_, err = decrypt(ct, aliceOSK, aliceDSK, &extended)
+	require.ErrorIs(t, err, ErrInvalidValidity)
+	stripped := *aliceOSK
+	stripped.KXs = map[string]*bn256.G2{"Doctor": aliceOSK.KXs["Doctor"]}
+	untimed, _, err := pvoabe.EncPolicy(pk, "Doctor")
+	require.NoError(t, err)
+	_, err = decrypt(untimed, &stripped, aliceDSK, aliceKV)
+	require.ErrorIs(t, err, ErrKeyExpired)
+
+	// NotBefore 绑定在策略与 Digest 中：改写到其他纪元或同一纪元内更早的时刻，云端都拒绝
+	rewritten := *later
+	rewritten.NotBefore = day(3)
+	_, err = decrypt(&rewritten, bobOSK, bobDSK, bobKV)
+	require.Error(t, err)
+	rewritten.NotBefore = later.NotBefore.Add(-time.Minute)
+	_, err = decrypt(&rewritten, bobOSK, bobDSK, bobKV)
+	require.Error(t, err)
+	// 连 Digest 一起改写时，用户的密钥承诺不通过
+	rewritten.Digest = headerDigest(rewritten.Msp, rewritten.NotBefore)
+	shares, err := pvoabe.OEnc(pk, later.B, later.Msp)
+	require.NoError(t, err)
+	R, _, err := pvoabe.ODecTimed(pk, tl, auth.VerifyKey, shares, &rewritten, bobOSK, bobKV, sk)
+	require.NoError(t, err)
+	_, err = pvoabe.Dec(&rewritten, bobDSK, R)
+	require.ErrorIs(t, err, ErrDecryptionFailed)
+	other, _, err := pvoabe.EncPolicy(pk, "(Doctor) AND "+tl.Constraint(11))
+	require.NoError(t, err)
+	require.NotEqual(t, other.Digest, later.Digest, "the digest binds NotBefore, not only the policy")
+
+	// 时间约束由密码学保证：即使云端不检查时钟，在第 11 天之前就已过期的 alice 也无法满足该记录的策略
+	shares, err = pvoabe.OEnc(pk, later.B, later.Msp)
+	require.NoError(t, err)
+	_, _, err = pvoabe.ODecWithContext(pk, shares, later, aliceOSK, sk)
+	require.Error(t, err)
+}
+
+func TestPolicyUpdate(t *testing.T) {
+	pvoabe := NewPVOABE()
+	alpha, pk, sk, err := pvoabe.SetupUniverse([]string{"Doctor", "Cardiology", "Oncology", "Nurse"})
+	require.NoError(t, err)
+	cardOSK, cardDSK, err := pvoabe.KeyGen(pk, alpha, []string{"Doctor", "Cardiology"})
+	require.NoError(t, err)
+	oncoOSK, oncoDSK, err := pvoabe.KeyGen(pk, alpha, []string{"Doctor", "Oncology"})
+	require.NoError(t, err)
+
+	CT, key, s, err := pvoabe.EncPolicyUpdatable(pk, "Doctor AND Cardiology")
+	require.NoError(t, err)
+	shares, err := pvoabe.OEnc(pk, CT.B, CT.Msp)
+	require.NoError(t, err)
+	Cprime := CT.Cprime
+	oldDigest := CT.Digest
+
+	decrypt := func(osk *PVGSS.OSK, dsk *bn256.G1) error {
+		R, proof, err := pvoabe.ODecWithContext(pk, shares, CT, osk, sk)
+		if err != nil {
+			return err
+		}
+		require.True(t, pvoabe.ODecVerWithContext(pk, shares, CT, osk, R, proof))
+		got, err := pvoabe.Dec(CT, dsk, R)
+		require.NoError(t, err)
+		require.Equal(t, key.String(), got.String())
+		return nil
+	}
+	require.NoError(t, decrypt(cardOSK, cardDSK))
+	require.Error(t, decrypt(oncoOSK, oncoDSK))
+
+	// 只有知道 s 的数据拥有者能生成令牌
+	_, err = pvoabe.PolicyUpdateToken(pk, CT, big.NewInt(42), "Doctor AND Oncology")
+	require.Error(t, err)
+	tok, err := pvoabe.PolicyUpdateToken(pk, CT, s, "Doctor AND Oncology")
+	require.NoError(t, err)
+	require.NoError(t, pvoabe.VerifyUpdateToken(pk, CT, tok))
+
+	// 篡改令牌中的策略或响应都会被拒绝
+	forged := *tok
+	forged.Policy = "Nurse"
+	require.ErrorIs(t, pvoabe.VerifyUpdateToken(pk, CT, &forged), ErrInvalidUpdateToken)
+	forged = *tok
+	forged.Z = new(big.Int).Add(tok.Z, big.NewInt(1))
+	require.ErrorIs(t, pvoabe.VerifyUpdateToken(pk, CT, &forged), ErrInvalidUpdateToken)
+
+	shares, err = pvoabe.UpdatePolicy(pk, CT, shares, tok)
+	require.NoError(t, err)
+	require.NotEqual(t, oldDigest, CT.Digest)
+	require.Same(t, Cprime, CT.Cprime)
+	require.True(t, pvoabe.OEncVer(pk, shares, Cprime, CT.Msp), "updated shares verify against the same C'")
+
+	require.NoError(t, decrypt(oncoOSK, oncoDSK))
+	require.Error(t, decrypt(cardOSK, cardDSK))
+
+	// 令牌链随密文保存，用户与审计者可以验证
+	require.Len(t, CT.Updates, 1)
+	require.NoError(t, pvoabe.VerifyUpdateHistory(pk, CT))
+
+	// 令牌绑定版本号，不能重放，A→B→A 之后也不能再次应用第一个令牌
+	_, err = pvoabe.UpdatePolicy(pk, CT, shares, tok)
+	require.ErrorIs(t, err, ErrInvalidUpdateToken)
+	back, err := pvoabe.PolicyUpdateToken(pk, CT, s, "Doctor AND Cardiology")
+	require.NoError(t, err)
+	require.Equal(t, 2, back.Version)
+	backShares, err := pvoabe.UpdatePolicy(pk, CT, shares, back)
+	require.NoError(t, err)
+	require.Equal(t, oldDigest, CT.Digest)
+	_, err = pvoabe.UpdatePolicy(pk, CT, backShares, tok)
+	require.ErrorIs(t, err, ErrInvalidUpdateToken)
+	require.NoError(t, pvoabe.VerifyUpdateHistory(pk, CT))
+	shares = backShares
+	require.NoError(t, decrypt(cardOSK, cardDSK))
+
+	// 云端不经令牌直接对 B 重新执行 OEnc：令牌链对不上，ODecVer 拒绝；删去整条令牌链后 Dec 的密钥承诺不符
+	rogue := *CT
+	rogueMsp, _, err := policy.Canonicalize("Doctor AND Oncology")
+	require.NoError(t, err)
+	rogue.Msp, rogue.Digest = rogueMsp, headerDigest(rogueMsp, CT.NotBefore)
+	rogueShares, err := pvoabe.OEnc(pk, rogue.B, rogue.Msp)
+	require.NoError(t, err)
+	require.ErrorIs(t, pvoabe.VerifyUpdateHistory(pk, &rogue), ErrInvalidUpdateToken)
+	R, proof, err := pvoabe.ODecWithContext(pk, rogueShares, &rogue, oncoOSK, sk)
+	require.NoError(t, err)
+	require.False(t, pvoabe.ODecVerWithContext(pk, rogueShares, &rogue, oncoOSK, R, proof))
+	rogue.Updates = nil
+	require.NoError(t, pvoabe.VerifyUpdateHistory(pk, &rogue))
+	R, _, err = pvoabe.ODecWithContext(pk, rogueShares, &rogue, oncoOSK, sk)
+	require.NoError(t, err)
+	_, err = pvoabe.Dec(&rogue, oncoDSK, R)
+	require.ErrorIs(t, err, ErrDecryptionFailed)
+
+	// 已更新过策略的密文不能再刷新
+	_, _, err = pvoabe.Rerandomize(pk, CT, shares)
+	require.Error(t, err)
+
+	// 令牌绑定密文，不能挪用到另一条相同策略的密文
+	other, _, err := pvoabe.EncPolicy(pk, "Doctor AND Oncology")
+	require.NoError(t, err)
+	tok2, err := pvoabe.PolicyUpdateToken(pk, CT, s, "Nurse")
+	require.NoError(t, err)
+	require.ErrorIs(t, pvoabe.VerifyUpdateToken(pk, other, tok2), ErrInvalidUpdateToken)
+}
+
+func TestDelegate(t *testing.T) {
+	pvoabe := NewPVOABE()
+	alpha, pk, sk, err := pvoabe.SetupUniverse([]string{"Cardiology", "Physician", "HeadOfDept", "Oncology"})
+	require.NoError(t, err)
+	auth, err := NewAuthority(pk, alpha)
+	require.NoError(t, err)
+	headAttrs := []string{"Cardiology", "Physician", "HeadOfDept"}
+	headOSK, headDSK, err := pvoabe.KeyGenID(pk, auth, "head", headAttrs)
+	require.NoError(t, err)
+	seed, err := auth.DelegationSeed("head")
+	require.NoError(t, err)
+	residentAttrs := []string{"Cardiology", "Physician"}
+	_, _, err = pvoabe.Delegate(pk, headOSK, headDSK, nil, residentAttrs)
+	require.Error(t, err, "a key with an identifier needs its delegation seed")
+	residentOSK, residentDSK, err := pvoabe.Delegate(pk, headOSK, headDSK, seed, residentAttrs)
+	require.NoError(t, err)
+	require.NotEqual(t, headDSK.String(), residentDSK.String())
+
+	// 子密钥对云端可见的部分与父密钥没有共同之处
+	require.NotEmpty(t, residentOSK.ID)
+	require.NotEqual(t, headOSK.ID, residentOSK.ID)
+	require.NotContains(t, residentOSK.ID, headOSK.ID)
+	require.NotEqual(t, headOSK.L.String(), residentOSK.L.String())
+	require.NotEqual(t, headOSK.Ht.String(), residentOSK.Ht.String())
+	for x, kx := range residentOSK.KXs {
+		require.NotEqual(t, headOSK.KXs[x].String(), kx.String(), x)
+	}
+	// 同一父密钥派生的两个子密钥之间也不共享标识
+	sibling, _, err := pvoabe.Delegate(pk, headOSK, headDSK, seed, residentAttrs)
+	require.NoError(t, err)
+	require.NotEqual(t, residentOSK.ID, sibling.ID)
+
+	// 子密钥恰好能解密其属性子集满足的密文
+	for _, p := range []string{
+		"Cardiology AND Physician",
+		"Cardiology AND HeadOfDept",
+		"Physician OR HeadOfDept",
+		"HeadOfDept",
+		"2 OF (Cardiology, HeadOfDept, Oncology)",
+		"Oncology OR (Physician AND Cardiology)",
+	} {
+		CT, key, err := pvoabe.EncPolicy(pk, p)
+		require.NoError(t, err)
+		shares, err := pvoabe.OEnc(pk, CT.B, CT.Msp)
+		require.NoError(t, err)
+		for _, holder := range []struct {
+			attrs []string
+			osk   *PVGSS.OSK
+			dsk   *bn256.G1
+		}{{headAttrs, headOSK, headDSK}, {residentAttrs, residentOSK, residentDSK}} {
+			R, proof, err := pvoabe.ODecWithContext(pk, shares, CT, holder.osk, sk)
+			if !policy.MustParse(p).Satisfied(holder.attrs) {
+				require.Error(t, err, "%v must not satisfy %s", holder.attrs, p)
+				continue
+			}
+			require.NoError(t, err, "%v should satisfy %s", holder.attrs, p)
+			require.True(t, pvoabe.ODecVerWithContext(pk, shares, CT, holder.osk, R, proof))
+			got, err := pvoabe.Dec(CT, holder.dsk, R)
+			require.NoError(t, err)
+			require.Equal(t, key.String(), got.String(), "%v on %s", holder.attrs, p)
+		}
+	}
+
+	// 吊销父密钥后，由它派生的子密钥（包括再次委托得到的）一并被吊销，其他用户派生的子密钥不受影响
+	nested, _, err := pvoabe.Delegate(pk, residentOSK, residentDSK, seed, []string{"Cardiology"})
+	require.NoError(t, err)
+	otherOSK, otherDSK, err := pvoabe.KeyGenID(pk, auth, "other", residentAttrs)
+	require.NoError(t, err)
+	otherSeed, err := auth.DelegationSeed("other")
+	require.NoError(t, err)
+	otherSub, _, err := pvoabe.Delegate(pk, otherOSK, otherDSK, otherSeed, residentAttrs)
+	require.NoError(t, err)
+	_, _, err = pvoabe.KeyGenID(pk, auth, residentOSK.ID, residentAttrs)
+	require.Error(t, err, "delegated identifiers cannot be issued")
+
+	rl, ek, err := pvoabe.Revoke(pk, auth, "head")
+	require.NoError(t, err)
+	require.NoError(t, rl.Verify(auth.VerifyKey))
+	for _, id := range []string{headOSK.ID, residentOSK.ID, sibling.ID, nested.ID} {
+		require.True(t, rl.Contains(id), id)
+	}
+	require.False(t, rl.Contains(otherOSK.ID))
+	require.False(t, rl.Contains(otherSub.ID))
+	forged := &RevocationList{Epoch: rl.Epoch, Revoked: rl.Revoked, Sig: rl.Sig}
+	require.ErrorIs(t, forged.Verify(auth.VerifyKey), ErrInvalidSignature, "the seeds are signed")
+
+	CT, _, err := pvoabe.EncPolicy(pk, "Cardiology AND Physician")
+	require.NoError(t, err)
+	require.Equal(t, ek.Epoch, CT.Epoch)
+	shares, err := pvoabe.OEnc(pk, CT.B, CT.Msp)
+	require.NoError(t, err)
+	_, _, err = pvoabe.ODecWithRevocation(pk, rl, auth.VerifyKey, shares, CT, residentOSK, sk)
+	require.ErrorIs(t, err, ErrRevoked)
+	_, _, err = pvoabe.ODecWithRevocation(pk, rl, auth.VerifyKey, shares, CT, otherSub, sk)
+	require.NoError(t, err)
+}
+
+func TestMultiAuthority(t *testing.T) {
+	pvoabe := NewPVOABE()
+	hospital, err := pvoabe.AuthoritySetup("Hospital", []string{"Hospital.Doctor", "Hospital.Nurse"})
+	require.NoError(t, err)
+	university, err := pvoabe.AuthoritySetup("University", []string{"University.Researcher"})
+	require.NoError(t, err)
+	insurer, err := pvoabe.AuthoritySetup("Insurer", []string{"Insurer.Auditor"})
+	require.NoError(t, err)
+	_, err = pvoabe.AuthoritySetup("Insurer", []string{"Hospital.Doctor"})
+	require.Error(t, err, "authorities may only define their own attributes")
+	pks := []*AuthorityPublicKey{hospital.PK, university.PK, insurer.PK}
+
+	p := "(Hospital.Doctor AND University.Researcher) OR Insurer.Auditor"
+	CT, key, err := pvoabe.MAEnc(pks, p)
+	require.NoError(t, err)
+	_, _, err = pvoabe.MAEnc(pks[:2], p)
+	require.Error(t, err, "Insurer.Auditor is not published without the insurer's key")
+	rogue := &AuthorityPublicKey{
+		Name:   "Insurer",
+		EAlpha: map[string]*bn256.GT{"Hospital.Doctor": insurer.PK.EAlpha["Insurer.Auditor"]},
+		GY:     map[string]*bn256.G2{"Hospital.Doctor": insurer.PK.GY["Insurer.Auditor"]},
+	}
+	_, _, err = pvoabe.MAEnc([]*AuthorityPublicKey{rogue, university.PK}, "Hospital.Doctor AND University.Researcher")
+	require.Error(t, err, "an authority may not publish another authority's attribute")
+
+	// 每个机构独立签发密钥，同一 GID 的密钥可以合并
+	alice, err := pvoabe.MAKeyGen(hospital, "alice", []string{"Hospital.Doctor"})
+	require.NoError(t, err)
+	aliceUni, err := pvoabe.MAKeyGen(university, "alice", []string{"University.Researcher"})
+	require.NoError(t, err)
+	require.NoError(t, alice.Merge(aliceUni))
+	_, err = pvoabe.MAKeyGen(hospital, "alice", []string{"University.Researcher"})
+	require.Error(t, err)
+
+	decrypt := func(k *MAKey) (*bn256.GT, error) {
+		tk, z, err := pvoabe.MATransformKey(k)
+		require.NoError(t, err)
+		part, err := pvoabe.MAODec(tk, CT)
+		if err != nil {
+			return nil, err
+		}
+		return pvoabe.MADec(CT, z, part)
+	}
+	got, err := decrypt(alice)
+	require.NoError(t, err)
+	require.Equal(t, key.String(), got.String())
+
+	dave, err := pvoabe.MAKeyGen(insurer, "dave", []string{"Insurer.Auditor"})
+	require.NoError(t, err)
+	got, err = decrypt(dave)
+	require.NoError(t, err)
+	require.Equal(t, key.String(), got.String())
+
+	bob, err := pvoabe.MAKeyGen(hospital, "bob", []string{"Hospital.Doctor", "Hospital.Nurse"})
+	require.NoError(t, err)
+	_, err = decrypt(bob)
+	require.Error(t, err, "bob alone does not satisfy the policy")
+
+	// bob 与 carol 合谋：Merge 拒绝不同的 GID，强行拼接的密钥也解密不出 K
+	carol, err := pvoabe.MAKeyGen(university, "carol", []string{"University.Researcher"})
+	require.NoError(t, err)
+	require.ErrorIs(t, bob.Merge(carol), ErrGIDMismatch)
+	colluded := &MAKey{GID: "bob", K: map[string]*bn256.G1{
+		"Hospital.Doctor":       bob.K["Hospital.Doctor"],
+		"University.Researcher": carol.K["University.Researcher"],
+	}}
+	_, err = decrypt(colluded)
+	require.ErrorIs(t, err, ErrDecryptionFailed)
+
+	// 云端返回错误的 Q1 或 Q2 时 MADec 通过 Tag 发现
+	tk, z, err := pvoabe.MATransformKey(alice)
+	require.NoError(t, err)
+	part, err := pvoabe.MAODec(tk, CT)
+	require.NoError(t, err)
+	_, err = pvoabe.MADec(CT, z, &MAPartial{Q1: new(bn256.GT).Add(part.Q1, part.Q1), Q2: part.Q2})
+	require.ErrorIs(t, err, ErrDecryptionFailed)
+	_, rnd, err := bn256.RandomGT(rand.Reader)
+	require.NoError(t, err)
+	_, err = pvoabe.MADec(CT, z, &MAPartial{Q1: part.Q1, Q2: rnd})
+	require.ErrorIs(t, err, ErrDecryptionFailed)
+	stripped := *CT
+	stripped.Tag = nil
+	_, err = pvoabe.MADec(&stripped, z, part)
+	require.ErrorIs(t, err, ErrDecryptionFailed)
+}
+
+func TestTraitorTracing(t *testing.T) {
+	pvoabe := NewPVOABE()
+	alpha, pk, sk, err := pvoabe.SetupUniverse([]string{"Doctor", "Nurse", "Cardiology"})
+	require.NoError(t, err)
+	plain, _, err := pvoabe.EncPolicy(pk, "Doctor")
+	require.NoError(t, err)
+	tr, err := pvoabe.NewTracer(pk)
+	require.NoError(t, err)
+	_, err = pvoabe.NewTracer(pk)
+	require.Error(t, err)
+
+	alice, err := pvoabe.KeyGenTraceable(pk, alpha, tr, "alice", []string{"Doctor", "Cardiology"})
+	require.NoError(t, err)
+	bob, err := pvoabe.KeyGenTraceable(pk, alpha, tr, "bob", []string{"Nurse"})
+	require.NoError(t, err)
+	require.Len(t, tr.Table, 2)
+
+	CT, key, err := pvoabe.EncPolicy(pk, "Doctor AND Cardiology")
+	require.NoError(t, err)
+	require.NotNil(t, CT.CW)
+	shares, err := pvoabe.OEnc(pk, CT.B, CT.Msp)
+	require.NoError(t, err)
+	R, proof, err := pvoabe.ODecWithContext(pk, shares, CT, alice.OSK, sk)
+	require.NoError(t, err)
+	require.True(t, pvoabe.ODecVerWithContext(pk, shares, CT, alice.OSK, R, proof))
+	got, err := pvoabe.DecTraceable(CT, alice, R)
+	require.NoError(t, err)
+	require.Equal(t, key.String(), got.String())
+	_, err = pvoabe.DecTraceable(plain, alice, R)
+	require.Error(t, err, "ciphertexts from before NewTracer carry no CW")
+
+	// 泄露的密钥追踪到其持有者
+	id, err := tr.Trace(pk, alice)
+	require.NoError(t, err)
+	require.Equal(t, "alice", id)
+	id, err = tr.Trace(pk, bob)
+	require.NoError(t, err)
+	require.Equal(t, "bob", id)
+
+	// 换用他人的 c 或拼接他人的 OSK 都不再是结构良好的密钥，也无法解密
+	forged := &TraceableKey{OSK: alice.OSK, K: alice.K, C: bob.C}
+	_, err = tr.Trace(pk, forged)
+	require.ErrorIs(t, err, ErrMalformedKey)
+	_, err = pvoabe.DecTraceable(CT, forged, R)
+	require.ErrorIs(t, err, ErrDecryptionFailed)
+	_, err = tr.Trace(pk, &TraceableKey{OSK: bob.OSK, K: alice.K, C: alice.C})
+	require.ErrorIs(t, err, ErrMalformedKey)
+
+	// 吊销 bob 后：alice 的密钥由权威机构更新到新纪元，新旧纪元的密钥都能追踪
+	auth, err := NewAuthority(pk, alpha)
+	require.NoError(t, err)
+	auth.issued["alice"], auth.issued["bob"] = true, true
+	oldAlice := *alice
+	rl, ek, err := pvoabe.Revoke(pk, auth, "bob")
+	require.NoError(t, err)
+	_, err = pvoabe.KeyGenTraceable(pk, alpha, tr, "carol", []string{"Doctor"})
+	require.ErrorIs(t, err, ErrEpochMismatch, "the tracer must learn the new epoch first")
+	updates, err := pvoabe.UpdateTraceable(pk, tr, rl, ek)
+	require.NoError(t, err)
+	require.Contains(t, updates, "alice")
+	require.NotContains(t, updates, "bob")
+	require.NoError(t, alice.ApplyUpdate(updates["alice"], ek.Epoch))
+	require.Error(t, alice.ApplyUpdate(updates["alice"], ek.Epoch), "an update applies once")
+
+	require.NoError(t, pvoabe.UpdateCiphertext(CT, ek))
+	R, _, err = pvoabe.ODecWithContext(pk, shares, CT, alice.OSK, sk)
+	require.NoError(t, err)
+	got, err = pvoabe.DecTraceable(CT, alice, R)
+	require.NoError(t, err)
+	require.Equal(t, key.String(), got.String())
+	_, err = pvoabe.DecTraceable(CT, &oldAlice, R)
+	require.ErrorIs(t, err, ErrEpochMismatch)
+
+	id, err = tr.Trace(pk, alice)
+	require.NoError(t, err)
+	require.Equal(t, "alice", id)
+	id, err = tr.Trace(pk, &oldAlice)
+	require.NoError(t, err)
+	require.Equal(t, "alice", id)
+	id, err = tr.Trace(pk, bob)
+	require.NoError(t, err)
+	require.Equal(t, "bob", id)
+	claimed := oldAlice
+	claimed.Epoch = ek.Epoch
+	_, err = tr.Trace(pk, &claimed)
+	require.ErrorIs(t, err, ErrMalformedKey)
+
+	delete(tr.Table, alice.C.String())
+	_, err = tr.Trace(pk, alice)
+	require.ErrorIs(t, err, ErrUnknownIdentity)
+
+	// 未启用追踪的公钥不会让 Trace panic
+	_, plainPK, _, err := pvoabe.SetupUniverse([]string{"Doctor"})
+	require.NoError(t, err)
+	_, err = tr.Trace(plainPK, alice)
+	require.Error(t, err)
+}
+
+func TestHiddenPolicy(t *testing.T) {
+	pvoabe := NewPVOABE()
+	alpha, pk, sk, err := pvoabe.SetupUniverse([]string{"Doctor", "Nurse"})
+	require.NoError(t, err)
+	hk, err := pvoabe.SetupHidden(pk, sk, []string{"Department:HIV-Clinic", "Department:Oncology"})
+	require.NoError(t, err)
+
+	CT, key, err := pvoabe.EncHidden(pk, hk, "Doctor AND Department:HIV-Clinic")
+	require.NoError(t, err)
+	// 密文头只出现属性名
+	require.ElementsMatch(t, []string{"Doctor", "Department"}, CT.Msp.RowToAttrib)
+	other, _, err := pvoabe.EncHidden(pk, hk, "Doctor AND Department:Oncology")
+	require.NoError(t, err)
+	require.Equal(t, CT.Digest, other.Digest, "the digest must not depend on hidden values")
+
+	shares, err := pvoabe.OEncHidden(pk, CT)
+	require.NoError(t, err)
+	require.True(t, pvoabe.OEncVerHidden(pk, shares, CT))
+
+	OSK, DSK, err := pvoabe.KeyGenHidden(pk, alpha, hk, []string{"Doctor"}, []string{"Department:HIV-Clinic"})
+	require.NoError(t, err)
+	R, proof, err := pvoabe.ODecWithContext(pk, shares, CT, OSK, sk)
+	require.NoError(t, err)
+	require.True(t, pvoabe.ODecVerWithContext(pk, shares, CT, OSK, R, proof))
+	got, err := pvoabe.Dec(CT, DSK, R)
+	require.NoError(t, err)
+	require.Equal(t, key.String(), got.String())
+
+	oncologist, _, err := pvoabe.KeyGenHidden(pk, alpha, hk, []string{"Doctor"}, []string{"Department:Oncology"})
+	require.NoError(t, err)
+	_, _, err = pvoabe.ODecWithContext(pk, shares, CT, oncologist, sk)
+	require.Error(t, err)
+}
+
+func TestKeyPolicy(t *testing.T) {
+	pvoabe := NewPVOABE()
+	universe := []string{"Source:firewall", "Source:ids", "Severity:high", "Severity:low"}
+	alpha, pk, sk, err := pvoabe.SetupUniverse(universe)
+	require.NoError(t, err)
+	kp, ksk, err := pvoabe.KPSetup(pk, universe)
+	require.NoError(t, err)
+
+	CT, key, err := pvoabe.KPEnc(pk, kp, []string{"Severity:high", "Source:ids"})
+	require.NoError(t, err)
+	require.Len(t, CT.Digest, 32)
+	_, _, err = pvoabe.KPEnc(pk, kp, []string{"Severity:high", "Severity:high"})
+	require.Error(t, err)
+	_, _, err = pvoabe.KPEnc(pk, kp, []string{"Unknown"})
+	require.Error(t, err)
+
+	ct, err := pvoabe.KPOEnc(pk, ksk, CT)
+	require.NoError(t, err)
+	require.True(t, pvoabe.KPOEncVer(pk, kp, ct, CT))
+
+	OSK, DSK, err := pvoabe.KPKeyGen(pk, alpha, kp, "Severity:high AND (Source:firewall OR Source:ids)")
+	require.NoError(t, err)
+	R, proof, err := pvoabe.KPODec(pk, ct, CT, OSK, sk)
+	require.NoError(t, err)
+	require.True(t, pvoabe.KPODecVer(pk, ct, CT, OSK, R, proof))
+	got, err := pvoabe.KPDec(CT, DSK, R)
+	require.NoError(t, err)
+	require.Equal(t, key.String(), got.String())
+
+	// 证明绑定属性集摘要，不能挪用到另一个密文头下
+	other, _, err := pvoabe.KPEnc(pk, kp, []string{"Severity:high", "Source:firewall"})
+	require.NoError(t, err)
+	require.False(t, pvoabe.KPODecVer(pk, ct, other, OSK, R, proof))
+	// 云端为另一个密文头计算的分量不能通过验证
+	otherCt, err := pvoabe.KPOEnc(pk, ksk, other)
+	require.NoError(t, err)
+	require.False(t, pvoabe.KPOEncVer(pk, kp, otherCt, CT))
+
+	lowOnly, _, err := pvoabe.KPKeyGen(pk, alpha, kp, "Severity:low AND Source:ids")
+	require.NoError(t, err)
+	_, _, err = pvoabe.KPODec(pk, ct, CT, lowOnly, sk)
+	require.Error(t, err)
+}
+
+func TestCCA(t *testing.T) {
+	pvoabe := NewPVOABE()
+	alpha, pk, sk, err := pvoabe.Setup()
+	require.NoError(t, err)
+	OSK, DSK, err := pvoabe.KeyGen(pk, alpha, []string{"Attr1", "Attr2"})
+	require.NoError(t, err)
+	msg := []byte("severity=high source=ids host=db-01: 3 failed logins followed by a privilege change")
+
+	CT, err := pvoabe.EncCCA(pk, "Attr1 AND (Attr2 OR Attr3)", msg)
+	require.NoError(t, err)
+	require.NotEqual(t, msg, CT.D)
+	shares, err := pvoabe.OEnc(pk, CT.B, CT.Msp)
+	require.NoError(t, err)
+	require.True(t, pvoabe.OEncVer(pk, shares, CT.Cprime, CT.Msp))
+	R, proof, err := pvoabe.ODecWithContext(pk, shares, CT.CipherText, OSK, sk)
+	require.NoError(t, err)
+	require.True(t, pvoabe.ODecVerWithContext(pk, shares, CT.CipherText, OSK, R, proof))
+	got, err := pvoabe.DecCCA(pk, CT, DSK, R)
+	require.NoError(t, err)
+	require.Equal(t, msg, got)
+
+	// flip 翻转 buf 中的一位，返回新切片
+	flip := func(buf []byte, bit int) []byte {
+		out := append([]byte(nil), buf...)
+		out[bit/8%len(out)] ^= 1 << (bit % 8)
+		return out
+	}
+	// 篡改后的密文必须被 DecCCA 拒绝
+	rejected := 0
+	reject := func(name string, tampered *CCACipherText) {
+		_, err := pvoabe.DecCCA(pk, tampered, DSK, R)
+		require.Error(t, err, name)
+		rejected++
+	}
+	copyCT := func() *CCACipherText {
+		inner := *CT.CipherText
+		return &CCACipherText{CipherText: &inner, D: CT.D}
+	}
+	// 群元素的编码翻转一位后通常不在曲线上，无法解码；改为翻转指数的一位：X·g^{2^bit} 仍是合法的群元素
+	bits := []int{0, 7, 100, 1000, 2000}
+	for _, bit := range bits {
+		delta := new(big.Int).Lsh(big.NewInt(1), uint(bit))
+		tampered := copyCT()
+		tampered.D = flip(CT.D, bit)
+		reject("D", tampered)
+
+		tampered = copyCT()
+		tampered.Digest = flip(CT.Digest, bit)
+		reject("Digest", tampered)
+
+		tampered = copyCT()
+		tampered.C = new(bn256.GT).Add(CT.C, new(bn256.GT).ScalarBaseMult(delta))
+		reject("C", tampered)
+
+		tampered = copyCT()
+		tampered.Cprime = new(bn256.G2).Add(CT.Cprime, new(bn256.G2).ScalarBaseMult(delta))
+		reject("Cprime", tampered)
+
+		tampered = copyCT()
+		tampered.B = new(bn256.G1).Add(CT.B, new(bn256.G1).ScalarBaseMult(delta))
+		reject("B", tampered)
+	}
+	require.Equal(t, 5*len(bits), rejected, "every tampered variant is constructed and checked")
+
+	// 对 CPA 的 Dec 有效的代数改造：C·e(g,g)、C'·g2 与 B·pk 都被拒绝
+	g1 := new(bn256.G1).ScalarBaseMult(big.NewInt(1))
+	g2 := new(bn256.G2).ScalarBaseMult(big.NewInt(1))
+	tampered := copyCT()
+	tampered.C = new(bn256.GT).Add(CT.C, bn256.Pair(g1, g2))
+	_, err = pvoabe.Dec(tampered.CipherText, DSK, R)
+	require.ErrorIs(t, err, ErrDecryptionFailed, "the key commitment catches a mauled C")
+	reject("C·e(g,g)", tampered)
+	tampered = copyCT()
+	tampered.Cprime = new(bn256.G2).Add(CT.Cprime, g2)
+	reject("C'·g2", tampered)
+	tampered = copyCT()
+	tampered.B = new(bn256.G1).Add(CT.B, pk.PP.Pk)
+	reject("B·pk", tampered)
+	tampered = copyCT()
+	tampered.Msp, _, err = canonicalMSP(pk, "Attr1 OR Attr3")
+	require.NoError(t, err)
+	reject("Msp", tampered)
+
+	// 错误的 R 也导致拒绝
+	_, err = pvoabe.DecCCA(pk, CT, DSK, new(bn256.GT).Add(R, R))
+	require.ErrorIs(t, err, ErrDecryptionFailed)
+
+	// Updates、Hidden 与 NotBefore 不受重新加密的检查，携带它们即拒绝
+	tampered = copyCT()
+	tampered.Updates = []*UpdateToken{{}}
+	reject("Updates", tampered)
+	tampered = copyCT()
+	tampered.Hidden = map[int]*PVGSS.HiddenRow{0: {}}
+	reject("Hidden", tampered)
+	tampered = copyCT()
+	tampered.NotBefore = time.Now()
+	reject("NotBefore", tampered)
+
+	// 启用追踪之前加密的密文没有 CW，启用追踪后仍可解密；之后加密的密文的 CW 受检查
+	_, err = pvoabe.NewTracer(pk)
+	require.NoError(t, err)
+	require.Nil(t, CT.CW)
+	got, err = pvoabe.DecCCA(pk, CT, DSK, R)
+	require.NoError(t, err)
+	require.Equal(t, msg, got)
+	traced, err := pvoabe.EncCCA(pk, "Attr1 AND (Attr2 OR Attr3)", msg)
+	require.NoError(t, err)
+	require.NotNil(t, traced.CW)
+	tracedShares, err := pvoabe.OEnc(pk, traced.B, traced.Msp)
+	require.NoError(t, err)
+	R, _, err = pvoabe.ODecWithContext(pk, tracedShares, traced.CipherText, OSK, sk)
+	require.NoError(t, err)
+	got, err = pvoabe.DecCCA(pk, traced, DSK, R)
+	require.NoError(t, err)
+	require.Equal(t, msg, got)
+	inner := *traced.CipherText
+	inner.CW = new(bn256.G2).Add(traced.CW, g2)
+	_, err = pvoabe.DecCCA(pk, &CCACipherText{CipherText: &inner, D: traced.D}, DSK, R)
+	require.ErrorIs(t, err, ErrInvalidCiphertext)
+
+	// 删去 CW 使密文看似在启用追踪之前加密，但 s 由 W 派生，重新加密不一致
+	stripped := *traced.CipherText
+	stripped.CW = nil
+	_, err = pvoabe.DecCCA(pk, &CCACipherText{CipherText: &stripped, D: traced.D}, DSK, R)
+	require.ErrorIs(t, err, ErrInvalidCiphertext)
+}
+
+func TestKeyCommitment(t *testing.T) {
+	pvoabe := NewPVOABE()
+	alpha, pk, sk, err := pvoabe.Setup()
+	require.NoError(t, err)
+	OSK, DSK, err := pvoabe.KeyGen(pk, alpha, []string{"Attr1", "Attr2"})
+	require.NoError(t, err)
+	_, otherDSK, err := pvoabe.KeyGen(pk, alpha, []string{"Attr1", "Attr2"})
+	require.NoError(t, err)
+
+	CT, key, err := pvoabe.EncPolicy(pk, "Attr1 AND Attr2")
+	require.NoError(t, err)
+	require.NotNil(t, CT.Tag)
+	shares, err := pvoabe.OEnc(pk, CT.B, CT.Msp)
+	require.NoError(t, err)
+	R, proof, err := pvoabe.ODecWithContext(pk, shares, CT, OSK, sk)
+	require.NoError(t, err)
+
+	// 不运行 ODecVer 时，错误的 R 或 DSK 同样被发现
+	got, err := pvoabe.Dec(CT, DSK, R)
+	require.NoError(t, err)
+	require.Equal(t, key.String(), got.String())
+	badR := new(bn256.GT).Add(R, R)
+	_, err = pvoabe.Dec(CT, DSK, badR)
+	require.ErrorIs(t, err, ErrDecryptionFailed)
+	_, err = pvoabe.Dec(CT, otherDSK, R)
+	require.ErrorIs(t, err, ErrDecryptionFailed)
+
+	// 运行 ODecVer 后结论一致
+	require.True(t, pvoabe.ODecVerWithContext(pk, shares, CT, OSK, R, proof))
+	require.False(t, pvoabe.ODecVerWithContext(pk, shares, CT, OSK, badR, proof))
+
+	// 云端删去 Tag 后 Dec 仍然失败，旧密文只能显式地走 DecUnchecked
+	stripped := *CT
+	stripped.Tag = nil
+	_, err = pvoabe.Dec(&stripped, DSK, R)
+	require.ErrorIs(t, err, ErrDecryptionFailed)
+	got, err = pvoabe.DecUnchecked(&stripped, DSK, R)
+	require.NoError(t, err)
+	require.Equal(t, key.String(), got.String())
+
+	// 密钥策略模式同样携带承诺
+	kp, ksk, err := pvoabe.KPSetup(pk, []string{"Attr1", "Attr2"})
+	require.NoError(t, err)
+	kpCT, kpKey, err := pvoabe.KPEnc(pk, kp, []string{"Attr1"})
+	require.NoError(t, err)
+	kpOSK, kpDSK, err := pvoabe.KPKeyGen(pk, alpha, kp, "Attr1 OR Attr2")
+	require.NoError(t, err)
+	ct, err := pvoabe.KPOEnc(pk, ksk, kpCT)
+	require.NoError(t, err)
+	kpR, _, err := pvoabe.KPODec(pk, ct, kpCT, kpOSK, sk)
+	require.NoError(t, err)
+	got, err = pvoabe.KPDec(kpCT, kpDSK, kpR)
+	require.NoError(t, err)
+	require.Equal(t, kpKey.String(), got.String())
+	_, err = pvoabe.KPDec(kpCT, DSK, kpR)
+	require.ErrorIs(t, err, ErrDecryptionFailed)
+}
+
+func TestRerandomize(t *testing.T) {
+	pvoabe := NewPVOABE()
+	alpha, pk, sk, err := pvoabe.SetupUniverse([]string{"Doctor", "Nurse", "Cardiology"})
+	require.NoError(t, err)
+	OSK, DSK, err := pvoabe.KeyGen(pk, alpha, []string{"Doctor", "Cardiology"})
+	require.NoError(t, err)
+
+	CT, key, err := pvoabe.EncPolicy(pk, "Cardiology AND (Doctor OR Nurse)")
+	require.NoError(t, err)
+	shares, err := pvoabe.OEnc(pk, CT.B, CT.Msp)
+	require.NoError(t, err)
+	before := CT.B.String()
+
+	CT2, shares2, err := pvoabe.Rerandomize(pk, CT, shares)
+	require.NoError(t, err)
+	require.Equal(t, before, CT.B.String(), "the input must not be modified")
+	require.True(t, pvoabe.OEncVer(pk, shares2, CT2.Cprime, CT2.Msp))
+	require.False(t, pvoabe.OEncVer(pk, shares, CT2.Cprime, CT2.Msp), "old shares do not match the new C'")
+
+	R, proof, err := pvoabe.ODecWithContext(pk, shares2, CT2, OSK, sk)
+	require.NoError(t, err)
+	require.True(t, pvoabe.ODecVerWithContext(pk, shares2, CT2, OSK, R, proof))
+	got, err := pvoabe.Dec(CT2, DSK, R)
+	require.NoError(t, err)
+	require.Equal(t, key.String(), got.String())
+
+	// 不可关联：输入与输出没有相同的群元素，且各行份额的差值互不相同（每行独立刷新）
+	seen := map[string]bool{CT.C.String(): true, CT.Cprime.String(): true, CT.B.String(): true}
+	for _, c := range shares {
+		seen[c.Ci.String()] = true
+		seen[c.CiPrime.String()] = true
+	}
+	require.False(t, seen[CT2.C.String()] || seen[CT2.Cprime.String()] || seen[CT2.B.String()])
+	diffs := map[string]bool{}
+	for i, c := range shares2 {
+		require.False(t, seen[c.Ci.String()] || seen[c.CiPrime.String()], "row %d", i)
+		diffs[new(bn256.G1).Add(c.CiPrime, new(bn256.G1).Neg(shares[i].CiPrime)).String()] = true
+	}
+	require.Len(t, diffs, len(shares2))
+	// 密钥承诺同样刷新，Digest 只取决于策略，与同一策略下新加密的密文相同
+	require.NotEqual(t, CT.Tag.Z.String(), CT2.Tag.Z.String())
+	require.NotEqual(t, CT.Tag.T.String(), CT2.Tag.T.String())
+	fresh, _, err := pvoabe.EncPolicy(pk, "Cardiology AND (Doctor OR Nurse)")
+	require.NoError(t, err)
+	require.Equal(t, fresh.Digest, CT2.Digest)
+	// 两次刷新的结果也互不相同
+	CT3, _, err := pvoabe.Rerandomize(pk, CT, shares)
+	require.NoError(t, err)
+	require.NotEqual(t, CT2.B.String(), CT3.B.String())
+
+	// 启用追踪后 CW 一起刷新，可追踪密钥仍能解密
+	tr, err := pvoabe.NewTracer(pk)
+	require.NoError(t, err)
+	_, _, err = pvoabe.Rerandomize(pk, CT, shares)
+	require.Error(t, err, "CT carries no CW")
+	alice, err := pvoabe.KeyGenTraceable(pk, alpha, tr, "alice", []string{"Doctor"})
+	require.NoError(t, err)
+	traced, tracedKey, err := pvoabe.EncPolicy(pk, "Doctor")
+	require.NoError(t, err)
+	tracedShares, err := pvoabe.OEnc(pk, traced.B, traced.Msp)
+	require.NoError(t, err)
+	traced2, tracedShares2, err := pvoabe.Rerandomize(pk, traced, tracedShares)
+	require.NoError(t, err)
+	require.NotEqual(t, traced.CW.String(), traced2.CW.String())
+	R, _, err = pvoabe.ODecWithContext(pk, tracedShares2, traced2, alice.OSK, sk)
+	require.NoError(t, err)
+	got, err = pvoabe.DecTraceable(traced2, alice, R)
+	require.NoError(t, err)
+	require.Equal(t, tracedKey.String(), got.String())
+
+	// 隐藏策略的行同样刷新 E 与 T
+	hk, err := pvoabe.SetupHidden(pk, sk, []string{"Ward:3"})
+	require.NoError(t, err)
+	hidden, hiddenKey, err := pvoabe.EncHidden(pk, hk, "Doctor AND Ward:3")
+	require.NoError(t, err)
+	hiddenShares, err := pvoabe.OEncHidden(pk, hidden)
+	require.NoError(t, err)
+	hidden2, hiddenShares2, err := pvoabe.Rerandomize(pk, hidden, hiddenShares)
+	require.NoError(t, err)
+	require.True(t, pvoabe.OEncVerHidden(pk, hiddenShares2, hidden2))
+	require.False(t, pvoabe.OEncVerHidden(pk, hiddenShares, hidden2))
+	// 隐藏行的盲化公钥换用新的 u，E 与 T 也不再相同
+	require.Len(t, hidden2.Hidden, len(hidden.Hidden))
+	for i, row := range hidden.Hidden {
+		row2 := hidden2.Hidden[i]
+		require.NotNil(t, row2)
+		require.NotEqual(t, row.X.String(), row2.X.String())
+		require.NotEqual(t, row.U.String(), row2.U.String())
+		require.NotEqual(t, hiddenShares[i].E.String(), hiddenShares2[i].E.String())
+		require.NotEqual(t, hiddenShares[i].T.String(), hiddenShares2[i].T.String())
+		require.NotEqual(t, hiddenShares[i].Prf.C.String(), hiddenShares2[i].Prf.C.String())
+	}
+	require.NotEqual(t, hidden.Tag.Z.String(), hidden2.Tag.Z.String())
+	hOSK, hDSK, err := pvoabe.KeyGenHidden(pk, alpha, hk, []string{"Doctor"}, []string{"Ward:3"})
+	require.NoError(t, err)
+	R, _, err = pvoabe.ODecWithContext(pk, hiddenShares2, hidden2, hOSK, sk)
+	require.NoError(t, err)
+	got, err = pvoabe.Dec(hidden2, hDSK, R)
+	require.NoError(t, err)
+	require.Equal(t, hiddenKey.String(), got.String())
+}
+
+func TestCountFormulas(t *testing.T) {
+	universe := []string{"A", "B", "C", "D", "E", "F"}
+	for _, tc := range []struct {
+		policy string
+		su     []string
+	}{
+		{"A OR B OR C", []string{"B"}},
+		{"A AND B AND C AND D", []string{"A", "B", "C", "D", "E"}},
+		{"(A AND B) OR (C AND D AND E) OR F", []string{"C", "D", "E"}},
+		{"(A AND (B OR C)) AND (D OR (E AND F))", universe},
+	} {
+		pvoabe := NewPVOABE()
+		counter := opcount.New()
+		pvoabe.SetCounter(counter)
+		// measure 返回 f 执行期间的运算次数
+		measure := func(f func()) opcount.Counts {
+			counter.Reset()
+			f()
+			return counter.Snapshot()
+		}
+
+		var (
+			alpha  *big.Int
+			pk     *PublicKey
+			sk     *PVGSS.SecretKey
+			OSK    *PVGSS.OSK
+			DSK    *bn256.G1
+			CT     *CipherText
+			shares map[int]*PVGSS.CipherText
+			R      *bn256.GT
+			proof  *DLEQ.Prfs
+			err    error
+		)
+		counts := map[string]opcount.Counts{
+			"Setup":  measure(func() { alpha, pk, sk, err = pvoabe.SetupUniverse(universe) }),
+			"KeyGen": measure(func() { OSK, DSK, err = pvoabe.KeyGen(pk, alpha, tc.su) }),
+			"Enc":    measure(func() { CT, _, err = pvoabe.EncPolicy(pk, tc.policy) }),
+		}
+		require.NoError(t, err)
+		counts["OEnc"] = measure(func() { shares, err = pvoabe.OEnc(pk, CT.B, CT.Msp) })
+		counts["OEncVer"] = measure(func() { require.True(t, pvoabe.OEncVer(pk, shares, CT.Cprime, CT.Msp)) })
+		counts["ODec"] = measure(func() { R, proof, err = pvoabe.ODecWithContext(pk, shares, CT, OSK, sk) })
+		require.NoError(t, err)
+		counts["ODecVer"] = measure(func() { require.True(t, pvoabe.ODecVerWithContext(pk, shares, CT, OSK, R, proof)) })
+		counts["Dec"] = measure(func() { _, err = pvoabe.Dec(CT, DSK, R) })
+		require.NoError(t, err)
+
+		plan, err := LSSS.NewReconPlan(CT.Msp, tc.su, pk.PP.Order)
+		require.NoError(t, err)
+		size := opcount.Size{Universe: len(universe), Attrs: len(tc.su), Rows: len(CT.Msp.Mat), Used: len(plan.Rows)}
+		require.Len(t, counts, len(opcount.PVOABE))
+		for name, got := range counts {
+			require.Equal(t, opcount.PVOABE[name](size), got, "%s on %q", name, tc.policy)
+		}
+	}
+}
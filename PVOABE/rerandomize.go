@@ -0,0 +1,63 @@
+package PVOABE
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/AUKUS561/PVOABE/PVGSS"
+	"github.com/fentec-project/bn256"
+	"github.com/fentec-project/gofe/sample"
+)
+
+// Rerandomize 由任何持有公钥的一方执行，类似 VOABE 的 Sanitize：选取 δ←Zp 把 s 刷新为 s+δ，
+//
+//	C ← C·Base^δ，C' ← C'·g2^δ，B ← B·pk^δ，CW ← CW·W^δ
+//
+// 并用 PVGSS.Rerandomize 以 pk^δ 刷新各行份额及其 ri，隐藏行的盲化公钥换用新的 u。
+// 密钥承诺 Tag 刷新为 (Z^ζ, T^ζ)，承诺的 keyGt 不变。
+// 输出仍能通过 OEncVer（隐藏策略时为 OEncVerHidden），数据拥有者无法借 s、ri、u 或 Tag 嵌入隐蔽信道。
+// 输出中不随机化的只有 Msp、Digest、Epoch 与 NotBefore，它们由公开的策略与时间决定，同一策略下的密文都相同。
+// 输入不被修改。刷新后数据拥有者保存的 s 失效；EncCCA 密文的 D 与重新加密检查都绑定 s，
+// 不能刷新，只应刷新 Enc 的密文；策略更新令牌同样绑定 C' 与 B，已更新过策略的密文也不能刷新
+func (pvoabe *PVOABE) Rerandomize(pk *PublicKey, CT *CipherText, shares map[int]*PVGSS.CipherText) (*CipherText, map[int]*PVGSS.CipherText, error) {
+	if CT == nil || CT.C == nil || CT.Cprime == nil || CT.B == nil || CT.Msp == nil {
+		return nil, nil, fmt.Errorf("nil input")
+	}
+	if CT.Epoch != pk.Epoch {
+		return nil, nil, fmt.Errorf("%w: ciphertext at epoch %d, public key at epoch %d", ErrEpochMismatch, CT.Epoch, pk.Epoch)
+	}
+	if len(CT.Updates) > 0 {
+		return nil, nil, fmt.Errorf("ciphertext has a policy update history bound to its C' and B")
+	}
+	if (CT.CW != nil) != (pk.W != nil) {
+		return nil, nil, fmt.Errorf("ciphertext and public key disagree on tracing")
+	}
+	sampler := sample.NewUniformRange(big.NewInt(1), pk.PP.Order)
+	delta, err := sampler.Sample()
+	if err != nil {
+		return nil, nil, err
+	}
+	d := pvoabe.Ops.G1Mul(pk.PP.Pk, delta) //pk^δ
+	fresh, rows, err := pvoabe.pvgss.Rerandomize(pk.PP, shares, CT.Msp, d, CT.Hidden)
+	if err != nil {
+		return nil, nil, err
+	}
+	zeta, err := sampler.Sample()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	out := *CT
+	out.C = new(bn256.GT).Add(CT.C, pvoabe.Ops.GTExp(pk.Base, delta))
+	out.Cprime = new(bn256.G2).Add(CT.Cprime, pvoabe.Ops.G2Base(delta))
+	out.B = new(bn256.G1).Add(CT.B, d)
+	if CT.CW != nil {
+		out.CW = new(bn256.G2).Add(CT.CW, pvoabe.Ops.G2Mul(pk.W, delta))
+	}
+	out.Tag = nil
+	if CT.Tag != nil && CT.Tag.Z != nil && CT.Tag.T != nil {
+		out.Tag = pvoabe.rerandomizeTag(CT.Tag, zeta)
+	}
+	out.Hidden = rows
+	return &out, fresh, nil
+}
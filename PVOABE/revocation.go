@@ -0,0 +1,279 @@
+package PVOABE
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/AUKUS561/PVOABE/DLEQ"
+	"github.com/AUKUS561/PVOABE/PVGSS"
+	"github.com/fentec-project/bn256"
+	"github.com/fentec-project/gofe/sample"
+)
+
+/*
+用户吊销
+
+每个用户密钥带有标识 OSK.ID。权威机构吊销用户时：
+ 1. 发布签名的吊销列表 RL，云端在 ODec 前检查 OSK.ID 是否在 RL 中；
+ 2. 进入新纪元 e+1：选取 δ←Zp，α_{e+1} = α_e + δ，公钥的 Base 更新为 e(g,g)^{α_{e+1}}，
+    并生成纪元密钥 U = g^δ。云端用 U 把旧密文更新为 C·e(U, C') = K·e(g,g)^{α_{e+1}s}，
+    未被吊销的用户收到 U 后把 DSK 更新为 DSK·U = g^{α_{e+1}} h^t。
+
+被吊销用户的 DSK 停留在旧纪元，即使绕过云端的检查拿到 R，也无法解密更新后的密文与新纪元的密文。
+U 对所有未吊销用户相同，须经安全信道分发；被吊销用户与未吊销用户或云端合谋可以得到 U，该情形不在保护范围内。
+
+委托派生的子密钥不沿用父密钥的标识。权威机构为每个标识生成委托种子 seed，随密钥交给持有者；
+Delegate 为子密钥生成新标识 delegated:n.HMAC(seed, n)，不知道 seed 的云端无法把子密钥与父密钥或彼此关联。
+吊销父密钥时 RL 同时公开其 seed，云端据此识别并拒绝它派生的所有子密钥，此后这些子密钥才可被关联。
+*/
+
+var (
+	ErrRevoked          = errors.New("PVOABE: key has been revoked")
+	ErrInvalidSignature = errors.New("PVOABE: invalid revocation list signature")
+	ErrEpochMismatch    = errors.New("PVOABE: epoch mismatch")
+)
+
+// Authority 是权威机构吊销所需的状态：主密钥、当前纪元的 α_e、签名密钥与已签发/已吊销的密钥标识
+type Authority struct {
+	Epoch     int
+	VerifyKey ed25519.PublicKey //吊销列表的验证公钥，公开给云端
+
+	alpha   *big.Int //α_e
+	signKey ed25519.PrivateKey
+	issued  map[string]bool
+	revoked map[string]bool
+	seeds   map[string][]byte //标识 → 委托种子
+}
+
+// RevocationList 是某一纪元的吊销列表，Revoked 按字典序排列，
+// Seeds 是被吊销标识的委托种子，用于识别它们派生的子密钥
+type RevocationList struct {
+	Epoch   int
+	Revoked []string
+	Seeds   [][]byte
+	Sig     []byte
+}
+
+// delegatedPrefix 是 Delegate 派生的子密钥标识的前缀，KeyGenID 不接受以它开头的标识
+const delegatedPrefix = "delegated:"
+
+// EpochKey 是进入纪元 Epoch 的更新密钥 U = g^δ，云端用它更新密文，未吊销用户用它更新 DSK
+type EpochKey struct {
+	Epoch int
+	U     *bn256.G1
+}
+
+// NewAuthority 用 Setup 输出的主密钥 mk 创建吊销状态，纪元从 pk.Epoch 开始
+func NewAuthority(pk *PublicKey, mk *big.Int) (*Authority, error) {
+	vk, sk, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return &Authority{
+		Epoch:     pk.Epoch,
+		VerifyKey: vk,
+		alpha:     new(big.Int).Set(mk),
+		signKey:   sk,
+		issued:    make(map[string]bool),
+		revoked:   make(map[string]bool),
+		seeds:     make(map[string][]byte),
+	}, nil
+}
+
+// KeyGenID 与 KeyGen 相同，但为密钥分配标识 id，并按当前纪元的 α_e 生成 DSK
+func (pvoabe *PVOABE) KeyGenID(pk *PublicKey, auth *Authority, id string, su []string) (*PVGSS.OSK, *bn256.G1, error) {
+	if id == "" {
+		return nil, nil, fmt.Errorf("empty key identifier")
+	}
+	if strings.HasPrefix(id, delegatedPrefix) {
+		return nil, nil, fmt.Errorf("key identifier %s is reserved for delegated keys", id)
+	}
+	if auth.issued[id] {
+		return nil, nil, fmt.Errorf("key identifier %s already issued", id)
+	}
+	seed := make([]byte, 32)
+	if _, err := rand.Read(seed); err != nil {
+		return nil, nil, err
+	}
+	OSK, DSK, err := pvoabe.KeyGen(pk, auth.alpha, su)
+	if err != nil {
+		return nil, nil, err
+	}
+	OSK.ID = id
+	auth.issued[id] = true
+	auth.seeds[id] = seed
+	return OSK, DSK, nil
+}
+
+// DelegationSeed 返回标识 id 的委托种子，由权威机构随密钥经安全信道交给持有者，供 Delegate 使用，不能交给云端
+func (auth *Authority) DelegationSeed(id string) ([]byte, error) {
+	seed, ok := auth.seeds[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown key identifier %s", id)
+	}
+	return append([]byte(nil), seed...), nil
+}
+
+// delegatedID 生成子密钥的新标识 delegated:hex(n).hex(HMAC(seed, n))，n 为 16 字节随机数
+func delegatedID(seed []byte) (string, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return delegatedPrefix + hex.EncodeToString(nonce) + "." + hex.EncodeToString(delegationTag(seed, nonce)), nil
+}
+
+func delegationTag(seed, nonce []byte) []byte {
+	mac := hmac.New(sha256.New, seed)
+	mac.Write([]byte("PVOABE/delegate"))
+	mac.Write(nonce)
+	return mac.Sum(nil)
+}
+
+// Revoke 吊销 ids 并进入下一纪元：更新 pk.Base 与 pk.Epoch，返回签名的吊销列表与纪元密钥
+func (pvoabe *PVOABE) Revoke(pk *PublicKey, auth *Authority, ids ...string) (*RevocationList, *EpochKey, error) {
+	if pk.Epoch != auth.Epoch {
+		return nil, nil, ErrEpochMismatch
+	}
+	for _, id := range ids {
+		if !auth.issued[id] {
+			return nil, nil, fmt.Errorf("unknown key identifier %s", id)
+		}
+	}
+	for _, id := range ids {
+		auth.revoked[id] = true
+	}
+
+	//δ←Zp，α_{e+1} = α_e + δ
+	sampler := sample.NewUniformRange(big.NewInt(1), pvoabe.P)
+	delta, _ := sampler.Sample()
+	auth.alpha.Add(auth.alpha, delta)
+	auth.alpha.Mod(auth.alpha, pvoabe.P)
+	auth.Epoch++
+
+	g1 := new(bn256.G1).ScalarBaseMult(big.NewInt(1))
+	g2 := new(bn256.G2).ScalarBaseMult(big.NewInt(1))
+	pk.Base = pvoabe.Ops.GTExp(pvoabe.Ops.Pair(g1, g2), auth.alpha)
+	pk.Epoch = auth.Epoch
+
+	rl := &RevocationList{Epoch: auth.Epoch}
+	for id := range auth.revoked {
+		rl.Revoked = append(rl.Revoked, id)
+		rl.Seeds = append(rl.Seeds, auth.seeds[id])
+	}
+	sort.Strings(rl.Revoked)
+	sort.Slice(rl.Seeds, func(i, j int) bool { return bytes.Compare(rl.Seeds[i], rl.Seeds[j]) < 0 })
+	rl.Sig = ed25519.Sign(auth.signKey, rl.message())
+
+	return rl, &EpochKey{Epoch: auth.Epoch, U: pvoabe.Ops.G1Base(delta)}, nil
+}
+
+// message 是签名覆盖的内容：纪元，以及带个数前缀、逐个以长度前缀编码的标识与委托种子
+func (rl *RevocationList) message() []byte {
+	msg := []byte("PVOABE-RL")
+	msg = binary.BigEndian.AppendUint64(msg, uint64(rl.Epoch))
+	msg = binary.BigEndian.AppendUint32(msg, uint32(len(rl.Revoked)))
+	for _, id := range rl.Revoked {
+		msg = binary.BigEndian.AppendUint32(msg, uint32(len(id)))
+		msg = append(msg, id...)
+	}
+	msg = binary.BigEndian.AppendUint32(msg, uint32(len(rl.Seeds)))
+	for _, seed := range rl.Seeds {
+		msg = binary.BigEndian.AppendUint32(msg, uint32(len(seed)))
+		msg = append(msg, seed...)
+	}
+	return msg
+}
+
+// Verify 用权威机构的公钥 vk 验证吊销列表的签名
+func (rl *RevocationList) Verify(vk ed25519.PublicKey) error {
+	if !sort.StringsAreSorted(rl.Revoked) || !ed25519.Verify(vk, rl.message(), rl.Sig) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// Contains 判断 id 是否被吊销，Delegate 派生的标识在其某个祖先被吊销时也视为被吊销
+func (rl *RevocationList) Contains(id string) bool {
+	i := sort.SearchStrings(rl.Revoked, id)
+	if i < len(rl.Revoked) && rl.Revoked[i] == id {
+		return true
+	}
+	rest, ok := strings.CutPrefix(id, delegatedPrefix)
+	if !ok {
+		return false
+	}
+	nonceHex, tagHex, _ := strings.Cut(rest, ".")
+	nonce, err1 := hex.DecodeString(nonceHex)
+	tag, err2 := hex.DecodeString(tagHex)
+	if err1 != nil || err2 != nil {
+		return false
+	}
+	for _, seed := range rl.Seeds {
+		if hmac.Equal(delegationTag(seed, nonce), tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// ODecWithRevocation 是云端带吊销检查的 ODec：验证吊销列表签名，拒绝被吊销的密钥，
+// 并要求密文已更新到吊销列表的纪元
+func (pvoabe *PVOABE) ODecWithRevocation(pk *PublicKey, rl *RevocationList, vk ed25519.PublicKey, ct map[int]*PVGSS.CipherText, CT *CipherText, OSK *PVGSS.OSK, sk *PVGSS.SecretKey) (*bn256.GT, *DLEQ.Prfs, error) {
+	if err := rl.Verify(vk); err != nil {
+		return nil, nil, err
+	}
+	if rl.Contains(OSK.ID) {
+		return nil, nil, fmt.Errorf("%w: %s", ErrRevoked, OSK.ID)
+	}
+	if CT.Epoch != rl.Epoch {
+		return nil, nil, fmt.Errorf("%w: ciphertext at epoch %d, revocation list at epoch %d", ErrEpochMismatch, CT.Epoch, rl.Epoch)
+	}
+	return pvoabe.ODecWithContext(pk, ct, CT, OSK, sk)
+}
+
+// UpdateCiphertext 由云端执行，把纪元 ek.Epoch-1 的密文更新到 ek.Epoch：C ← C·e(U, C')
+// PVGSS 份额与 α 无关，不需要更新
+func (pvoabe *PVOABE) UpdateCiphertext(CT *CipherText, ek *EpochKey) error {
+	if CT.Epoch != ek.Epoch-1 {
+		return fmt.Errorf("%w: ciphertext at epoch %d, update key for epoch %d", ErrEpochMismatch, CT.Epoch, ek.Epoch)
+	}
+	CT.C = new(bn256.GT).Add(CT.C, pvoabe.Ops.Pair(ek.U, CT.Cprime))
+	CT.Epoch = ek.Epoch
+	return nil
+}
+
+// UpdateDSK 由未吊销用户执行，返回新纪元的 DSK·U
+func (pvoabe *PVOABE) UpdateDSK(DSK *bn256.G1, ek *EpochKey) *bn256.G1 {
+	return new(bn256.G1).Add(DSK, ek.U)
+}
+
+// RevokeAttribute 把属性 attr 升级到下一个版本，用于用户只失去单个属性的情形，见 PVGSS.RevokeAttribute
+func (pvoabe *PVOABE) RevokeAttribute(pk *PublicKey, sk *PVGSS.SecretKey, attr string) (*PVGSS.AttrUpdate, error) {
+	return pvoabe.pvgss.RevokeAttribute(pk.PP, sk, attr)
+}
+
+// AttrKeyUpdate 为仍持有 upd.Attr 的用户生成 OSK 的属性密钥更新
+func (pvoabe *PVOABE) AttrKeyUpdate(upd *PVGSS.AttrUpdate, OSK *PVGSS.OSK) (*PVGSS.KeyUpdate, error) {
+	return pvoabe.pvgss.KeyUpdate(upd, OSK)
+}
+
+// ApplyAttrKeyUpdate 把属性密钥更新应用到 OSK，DSK 不受属性吊销影响
+func (pvoabe *PVOABE) ApplyAttrKeyUpdate(OSK *PVGSS.OSK, ku *PVGSS.KeyUpdate) error {
+	return pvoabe.pvgss.ApplyKeyUpdate(OSK, ku)
+}
+
+// UpdateShares 由云端执行，只更新密文中 ρ(i) = upd.Attr 的份额
+func (pvoabe *PVOABE) UpdateShares(upd *PVGSS.AttrUpdate, ct map[int]*PVGSS.CipherText, CT *CipherText) (int, error) {
+	return pvoabe.pvgss.UpdateShares(upd, ct, CT.Msp)
+}
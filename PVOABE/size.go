@@ -0,0 +1,65 @@
+package PVOABE
+
+import "github.com/AUKUS561/PVOABE/size"
+
+// Size 列出密文 C、C'、B 与策略摘要的大小，访问结构不计入
+func (ct *CipherText) Size() size.Report {
+	return size.Breakdown("PVOABE.CipherText", ct)
+}
+
+// EncodedLen 返回密文在编码 enc 下的字节数
+func (ct *CipherText) EncodedLen(enc size.Encoding) int {
+	return ct.Size().Len(enc)
+}
+
+// Size 列出公钥中 PVGSS 公共参数与 e(g,g)^α 的大小
+func (pk *PublicKey) Size() size.Report {
+	r := size.Breakdown("PVOABE.PublicKey", pk.PP)
+	r.Add("Base", size.Of(pk.Base))
+	return r
+}
+
+// EncodedLen 返回公钥在编码 enc 下的字节数
+func (pk *PublicKey) EncodedLen(enc size.Encoding) int {
+	return pk.Size().Len(enc)
+}
+
+// Size 列出策略更新令牌的大小，策略按字符串字节数计
+func (tok *UpdateToken) Size() size.Report {
+	return size.Breakdown("PVOABE.UpdateToken", tok)
+}
+
+// EncodedLen 返回策略更新令牌在编码 enc 下的字节数
+func (tok *UpdateToken) EncodedLen(enc size.Encoding) int {
+	return tok.Size().Len(enc)
+}
+
+// Size 列出多权威机构密文 C0、各行 C1、C2、C3 与密钥承诺的大小
+func (ct *MACipherText) Size() size.Report {
+	return size.Breakdown("PVOABE.MACipherText", ct)
+}
+
+// EncodedLen 返回多权威机构密文在编码 enc 下的字节数
+func (ct *MACipherText) EncodedLen(enc size.Encoding) int {
+	return ct.Size().Len(enc)
+}
+
+// Size 列出密钥策略模式密文 C、C'、B、属性集与摘要的大小
+func (ct *KPCipherText) Size() size.Report {
+	return size.Breakdown("PVOABE.KPCipherText", ct)
+}
+
+// EncodedLen 返回密钥策略模式密文在编码 enc 下的字节数
+func (ct *KPCipherText) EncodedLen(enc size.Encoding) int {
+	return ct.Size().Len(enc)
+}
+
+// Size 列出 CCA 密文的密文头与 D 的大小
+func (ct *CCACipherText) Size() size.Report {
+	return size.Breakdown("PVOABE.CCACipherText", ct)
+}
+
+// EncodedLen 返回 CCA 密文在编码 enc 下的字节数
+func (ct *CCACipherText) EncodedLen(enc size.Encoding) int {
+	return ct.Size().Len(enc)
+}
@@ -0,0 +1,254 @@
+package PVOABE
+
+import (
+	"crypto/ed25519"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AUKUS561/PVOABE/DLEQ"
+	"github.com/AUKUS561/PVOABE/PVGSS"
+	"github.com/fentec-project/bn256"
+)
+
+/*
+时间约束
+
+Timeline 把时间按 Unit 划分为 2^Depth 个时间纪元，并把纪元组织成一棵满二叉树，
+每个结点对应一个范围属性 epoch_<层>_<序号>，覆盖其子树下的所有纪元：
+  - 密钥嵌入其有效期的结束纪元 to，编码为覆盖区间 [0, to] 的最少结点集合（至多 Depth 个属性）；
+  - 密文的时间约束是可读纪元 T，编码为 T 到根的路径上所有结点的 OR（Depth+1 个属性）。
+两者有交集当且仅当 T ≤ to，即密钥在 T 或之后的某个时刻有效，因此之后签发的密钥同样能读取更早可读的记录，
+而在 T 之前就已过期的密钥即使云端不检查时钟也无法解密。
+
+EncTimed 在策略后自动追加时间约束：T 取记录的可读时间 notBefore，未指定时取加密时刻。
+限时密文的 Digest = headerDigest(Msp, NotBefore)，任何人都能重新计算，ODecTimed 据此拒绝改写过的 NotBefore；
+同时改写 Digest 则与 ODec 的 DLEQ 证明及密钥承诺不符。策略更新令牌的新摘要同样写入 NotBefore。
+
+密钥的有效期 [from, to] 由权威机构签名（KeyValidity），签名绑定 OSK.L，而不是从 OSK.KXs 的属性名推断，
+因此用户删去 epoch_* 属性也无法绕过过期检查。
+云端在 ODecTimed 中验证签名，按时钟检查密钥是否过期、记录是否已可读，并返回具体原因。
+*/
+
+const epochPrefix = "epoch_"
+
+var (
+	ErrKeyExpired      = errors.New("PVOABE: key has expired")
+	ErrKeyNotYetValid  = errors.New("PVOABE: key is not yet valid")
+	ErrNotYetReadable  = errors.New("PVOABE: ciphertext is not yet readable")
+	ErrOutsideTimeline = errors.New("PVOABE: time outside timeline")
+	ErrInvalidValidity = errors.New("PVOABE: invalid key validity certificate")
+)
+
+// KeyValidity 是权威机构对密钥有效期的签名，L 为所绑定密钥的 OSK.L
+type KeyValidity struct {
+	L    *bn256.G2
+	From int
+	To   int
+	Sig  []byte
+}
+
+// Timeline 描述时间纪元的划分，Clock 为 nil 时使用 time.Now，测试中可注入固定时钟
+type Timeline struct {
+	Start time.Time
+	Unit  time.Duration
+	Depth int
+	Clock func() time.Time
+}
+
+// NewTimeline 返回从 start 开始、每个纪元长 unit、共 2^depth 个纪元的时间线
+func NewTimeline(start time.Time, unit time.Duration, depth int) *Timeline {
+	return &Timeline{Start: start, Unit: unit, Depth: depth}
+}
+
+// Now 返回时钟的当前时间
+func (tl *Timeline) Now() time.Time {
+	if tl.Clock != nil {
+		return tl.Clock()
+	}
+	return time.Now()
+}
+
+// Epoch 返回 t 所在的纪元序号
+func (tl *Timeline) Epoch(t time.Time) (int, error) {
+	if t.Before(tl.Start) {
+		return 0, fmt.Errorf("%w: %s is before %s", ErrOutsideTimeline, t.Format(time.RFC3339), tl.Start.Format(time.RFC3339))
+	}
+	e := t.Sub(tl.Start) / tl.Unit
+	if e >= time.Duration(1)<<tl.Depth {
+		return 0, fmt.Errorf("%w: %s is after the last epoch", ErrOutsideTimeline, t.Format(time.RFC3339))
+	}
+	return int(e), nil
+}
+
+// EpochStart 返回纪元 e 的起始时间
+func (tl *Timeline) EpochStart(e int) time.Time {
+	return tl.Start.Add(time.Duration(e) * tl.Unit)
+}
+
+// Attributes 返回树中所有结点的范围属性，Setup 时加入属性全集
+func (tl *Timeline) Attributes() []string {
+	var attrs []string
+	for level := 0; level <= tl.Depth; level++ {
+		for i := 0; i < 1<<level; i++ {
+			attrs = append(attrs, nodeAttr(level, i))
+		}
+	}
+	return attrs
+}
+
+// Cover 返回覆盖纪元区间 [from, to] 的最少结点属性
+func (tl *Timeline) Cover(from, to int) []string {
+	var attrs []string
+	var walk func(level, index int)
+	walk = func(level, index int) {
+		lo, hi := tl.leaves(level, index)
+		if hi < from || lo > to {
+			return
+		}
+		if from <= lo && hi <= to {
+			attrs = append(attrs, nodeAttr(level, index))
+			return
+		}
+		walk(level+1, 2*index)
+		walk(level+1, 2*index+1)
+	}
+	walk(0, 0)
+	return attrs
+}
+
+// Path 返回纪元 e 到根路径上所有结点的属性
+func (tl *Timeline) Path(e int) []string {
+	attrs := make([]string, 0, tl.Depth+1)
+	for level := 0; level <= tl.Depth; level++ {
+		attrs = append(attrs, nodeAttr(level, e>>(tl.Depth-level)))
+	}
+	return attrs
+}
+
+// Constraint 返回要求密钥在纪元 e 有效的策略片段
+func (tl *Timeline) Constraint(e int) string {
+	return "(" + strings.Join(tl.Path(e), " OR ") + ")"
+}
+
+// leaves 返回结点 (level, index) 覆盖的纪元区间
+func (tl *Timeline) leaves(level, index int) (int, int) {
+	shift := tl.Depth - level
+	return index << shift, (index+1)<<shift - 1
+}
+
+func nodeAttr(level, index int) string {
+	return epochPrefix + strconv.Itoa(level) + "_" + strconv.Itoa(index)
+}
+
+// message 是签名覆盖的内容：时间线参数、密钥的 L 与有效期
+func (kv *KeyValidity) message(tl *Timeline) []byte {
+	msg := []byte("PVOABE-validity")
+	msg = binary.BigEndian.AppendUint64(msg, uint64(tl.Start.UnixNano()))
+	msg = binary.BigEndian.AppendUint64(msg, uint64(tl.Unit))
+	msg = binary.BigEndian.AppendUint64(msg, uint64(tl.Depth))
+	msg = append(msg, kv.L.Marshal()...)
+	msg = binary.BigEndian.AppendUint64(msg, uint64(kv.From))
+	return binary.BigEndian.AppendUint64(msg, uint64(kv.To))
+}
+
+// Verify 用权威机构的公钥 vk 验证有效期签名，并检查它属于 OSK
+func (kv *KeyValidity) Verify(vk ed25519.PublicKey, tl *Timeline, OSK *PVGSS.OSK) error {
+	if kv == nil || kv.L == nil || OSK == nil || OSK.L == nil || kv.L.String() != OSK.L.String() {
+		return ErrInvalidValidity
+	}
+	if kv.From < 0 || kv.From > kv.To || !ed25519.Verify(vk, kv.message(tl), kv.Sig) {
+		return ErrInvalidValidity
+	}
+	return nil
+}
+
+// KeyGenTimed 与 KeyGen 相同，但密钥只在 [notBefore, notAfter] 所在的纪元内有效：
+// 密钥嵌入覆盖 [0, to] 的范围属性，有效期由权威机构签名并随密钥交给用户，ODecTimed 时提交给云端
+func (pvoabe *PVOABE) KeyGenTimed(pk *PublicKey, auth *Authority, tl *Timeline, su []string, notBefore, notAfter time.Time) (*PVGSS.OSK, *bn256.G1, *KeyValidity, error) {
+	from, err := tl.Epoch(notBefore)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	to, err := tl.Epoch(notAfter)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if from > to {
+		return nil, nil, nil, fmt.Errorf("key validity ends before it starts")
+	}
+	OSK, DSK, err := pvoabe.KeyGen(pk, auth.alpha, append(append([]string(nil), su...), tl.Cover(0, to)...))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	kv := &KeyValidity{L: OSK.L, From: from, To: to}
+	kv.Sig = ed25519.Sign(auth.signKey, kv.message(tl))
+	return OSK, DSK, kv, nil
+}
+
+// EncTimed 按策略加密并追加时间约束：只有在 notBefore 所在纪元或之后仍有效的密钥才能解密，
+// 云端在 notBefore 之前拒绝 ODecTimed。notBefore 为零值时取时钟的当前时间
+func (pvoabe *PVOABE) EncTimed(pk *PublicKey, tl *Timeline, accessPolicy string, notBefore time.Time) (*CipherText, *bn256.GT, error) {
+	if notBefore.IsZero() {
+		notBefore = tl.Now()
+	}
+	e, err := tl.Epoch(notBefore)
+	if err != nil {
+		return nil, nil, err
+	}
+	msp, _, err := canonicalMSP(pk, "("+accessPolicy+") AND "+tl.Constraint(e))
+	if err != nil {
+		return nil, nil, err
+	}
+	CT, key, _, err := pvoabe.encMSP(pk, msp, headerDigest(msp, notBefore))
+	if err != nil {
+		return nil, nil, err
+	}
+	CT.NotBefore = notBefore
+	return CT, key, nil
+}
+
+// ODecTimed 是云端按时钟执行的 ODec：验证权威机构 vk 对密钥有效期 kv 的签名，
+// 拒绝尚未可读的密文、已过期或尚未生效的密钥，并返回具体原因。没有有效期签名的密钥一律拒绝
+func (pvoabe *PVOABE) ODecTimed(pk *PublicKey, tl *Timeline, vk ed25519.PublicKey, ct map[int]*PVGSS.CipherText, CT *CipherText, OSK *PVGSS.OSK, kv *KeyValidity, sk *PVGSS.SecretKey) (*bn256.GT, *DLEQ.Prfs, error) {
+	if err := kv.Verify(vk, tl, OSK); err != nil {
+		return nil, nil, err
+	}
+	now := tl.Now()
+	if now.Before(CT.NotBefore) {
+		return nil, nil, fmt.Errorf("%w: readable from %s", ErrNotYetReadable, CT.NotBefore.Format(time.RFC3339))
+	}
+	if !CT.NotBefore.IsZero() {
+		//NotBefore 须与 Digest 一致，且其纪元的路径须出现在策略中
+		if CT.checkHeader() != nil {
+			return nil, nil, fmt.Errorf("not-before time does not match the ciphertext digest")
+		}
+		e, err := tl.Epoch(CT.NotBefore)
+		if err != nil {
+			return nil, nil, err
+		}
+		rows := make(map[string]bool, len(CT.Msp.RowToAttrib))
+		for _, x := range CT.Msp.RowToAttrib {
+			rows[x] = true
+		}
+		for _, x := range tl.Path(e) {
+			if !rows[x] {
+				return nil, nil, fmt.Errorf("not-before time does not match the ciphertext policy")
+			}
+		}
+	}
+	e, err := tl.Epoch(now)
+	if err != nil {
+		return nil, nil, err
+	}
+	if e < kv.From {
+		return nil, nil, fmt.Errorf("%w: valid from %s", ErrKeyNotYetValid, tl.EpochStart(kv.From).Format(time.RFC3339))
+	}
+	if e > kv.To {
+		return nil, nil, fmt.Errorf("%w: expired at %s", ErrKeyExpired, tl.EpochStart(kv.To+1).Format(time.RFC3339))
+	}
+	return pvoabe.ODecWithContext(pk, ct, CT, OSK, sk)
+}
@@ -0,0 +1,185 @@
+package PVOABE
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/AUKUS561/PVOABE/PVGSS"
+	"github.com/fentec-project/bn256"
+	"github.com/fentec-project/gofe/sample"
+)
+
+/*
+白盒追踪
+
+KeyGen 签发的 (OSK, DSK) 不含身份，泄露后无法定位来源。可追踪密钥仿照 VOABE 的 w^{1/(b+H(IDu))}：
+  - NewTracer 选取 b←Zp 并公开 W = g2^b，此后加密的密文额外携带 CW = W^s；
+  - KeyGenTraceable 为用户选取 c←Zp，DSK 改为 K = (g^α h^t)^{1/(b+c)}，c 随密钥一起交给用户，
+    权威机构在身份表中记录 c → 用户标识；
+  - 解密时 e(K, CW·C'^c) = e(g^α h^t, g2^s)，除以 R 后与 Dec 相同。
+
+K 与 c 必须配对使用，用户不能在不知道 b 的情况下把 c 换成其他值。
+Trace 先检查泄露的密钥是否结构良好，再由 c 在身份表中查出用户。
+
+与吊销（revocation.go）同时使用时，可追踪密钥记录签发时的纪元，Tracer 记录各纪元的 e(g,g)^{α_e}，
+Trace 按密钥所在纪元的 Base 检查。用户无法自行计算 (DSK·U)^{1/(b+c)}，因此 Revoke 之后由权威机构用
+UpdateTraceable 为每个未被吊销的可追踪密钥计算 U^{1/(b+c)}，用户以 ApplyUpdate 把 K 移到新纪元。
+*/
+
+var (
+	ErrMalformedKey    = errors.New("PVOABE: leaked key is not well-formed")
+	ErrUnknownIdentity = errors.New("PVOABE: leaked key not found in identity table")
+)
+
+// Tracer 是权威机构的追踪状态：追踪私钥 b、身份表与各纪元的 Base
+type Tracer struct {
+	Table map[string]string //c 的十进制表示 → 用户标识
+
+	b     *big.Int
+	bases map[int]*bn256.GT //纪元 → e(g,g)^{α_e}
+}
+
+// TraceableKey 是可追踪的用户密钥：交给云端的 OSK 与用户保留的 (K, c)
+type TraceableKey struct {
+	OSK   *PVGSS.OSK
+	K     *bn256.G1 //(g^α h^t)^{1/(b+c)}
+	C     *big.Int
+	Epoch int //K 所在的纪元
+}
+
+// NewTracer 启用追踪：选取 b 并设置 pk.W = g2^b，须在加密之前调用
+func (pvoabe *PVOABE) NewTracer(pk *PublicKey) (*Tracer, error) {
+	if pk.W != nil {
+		return nil, fmt.Errorf("tracing already enabled")
+	}
+	sampler := sample.NewUniformRange(big.NewInt(1), pvoabe.P)
+	b, err := sampler.Sample()
+	if err != nil {
+		return nil, err
+	}
+	pk.W = pvoabe.Ops.G2Base(b)
+	return &Tracer{Table: make(map[string]string), b: b, bases: map[int]*bn256.GT{pk.Epoch: pk.Base}}, nil
+}
+
+// KeyGenTraceable 与 KeyGen 相同，但 DSK 嵌入随机 c，并在身份表中记录 c → id。
+// mk 须是 pk 当前纪元的 α_e
+func (pvoabe *PVOABE) KeyGenTraceable(pk *PublicKey, mk *big.Int, tr *Tracer, id string, su []string) (*TraceableKey, error) {
+	if id == "" {
+		return nil, fmt.Errorf("empty user identity")
+	}
+	if pk.W == nil {
+		return nil, fmt.Errorf("tracing not enabled")
+	}
+	if base, ok := tr.bases[pk.Epoch]; !ok || base.String() != pk.Base.String() {
+		return nil, fmt.Errorf("%w: tracer has no base for epoch %d, run UpdateTraceable after Revoke", ErrEpochMismatch, pk.Epoch)
+	}
+	OSK, DSK, err := pvoabe.KeyGen(pk, mk, su)
+	if err != nil {
+		return nil, err
+	}
+	sampler := sample.NewUniformRange(big.NewInt(1), pvoabe.P)
+	var c, inv *big.Int
+	for inv == nil {
+		if c, err = sampler.Sample(); err != nil {
+			return nil, err
+		}
+		if _, taken := tr.Table[c.String()]; taken {
+			continue
+		}
+		inv = new(big.Int).ModInverse(new(big.Int).Add(tr.b, c), pvoabe.P)
+	}
+	OSK.ID = id
+	tr.Table[c.String()] = id
+	return &TraceableKey{OSK: OSK, K: pvoabe.Ops.G1Mul(DSK, inv), C: c, Epoch: pk.Epoch}, nil
+}
+
+// UpdateTraceable 由权威机构在 Revoke 之后执行：记录新纪元的 Base，并为 rl 之外的每个可追踪密钥
+// 计算 U^{1/(b+c)}，返回标识 → 更新值。pk 须已由 Revoke 更新到 ek.Epoch
+func (pvoabe *PVOABE) UpdateTraceable(pk *PublicKey, tr *Tracer, rl *RevocationList, ek *EpochKey) (map[string]*bn256.G1, error) {
+	if pk.Epoch != ek.Epoch || rl.Epoch != ek.Epoch {
+		return nil, fmt.Errorf("%w: public key at epoch %d, revocation list at epoch %d, update key for epoch %d", ErrEpochMismatch, pk.Epoch, rl.Epoch, ek.Epoch)
+	}
+	if _, ok := tr.bases[ek.Epoch-1]; !ok {
+		return nil, fmt.Errorf("%w: tracer has no base for epoch %d", ErrEpochMismatch, ek.Epoch-1)
+	}
+	tr.bases[ek.Epoch] = pk.Base
+	updates := make(map[string]*bn256.G1, len(tr.Table))
+	for cs, id := range tr.Table {
+		if rl.Contains(id) {
+			continue
+		}
+		c, _ := new(big.Int).SetString(cs, 10)
+		inv := new(big.Int).ModInverse(new(big.Int).Add(tr.b, c), pvoabe.P)
+		updates[id] = pvoabe.Ops.G1Mul(ek.U, inv)
+	}
+	return updates, nil
+}
+
+// ApplyUpdate 由用户执行，把 K 从纪元 epoch-1 移到 epoch：K ← K·U^{1/(b+c)}
+func (key *TraceableKey) ApplyUpdate(upd *bn256.G1, epoch int) error {
+	if upd == nil {
+		return fmt.Errorf("nil update")
+	}
+	if key.Epoch != epoch-1 {
+		return fmt.Errorf("%w: key at epoch %d, update for epoch %d", ErrEpochMismatch, key.Epoch, epoch)
+	}
+	key.K = new(bn256.G1).Add(key.K, upd)
+	key.Epoch = epoch
+	return nil
+}
+
+// DecTraceable 用可追踪密钥解密：e(K, CW·C'^c) / R = e(g,g)^{αs}
+func (pvoabe *PVOABE) DecTraceable(CT *CipherText, key *TraceableKey, R *bn256.GT) (*bn256.GT, error) {
+	if CT.CW == nil {
+		return nil, fmt.Errorf("ciphertext was not encrypted under a traceable public key")
+	}
+	if key == nil || key.K == nil || key.C == nil || R == nil {
+		return nil, fmt.Errorf("nil input")
+	}
+	if key.Epoch != CT.Epoch {
+		return nil, fmt.Errorf("%w: ciphertext at epoch %d, key at epoch %d", ErrEpochMismatch, CT.Epoch, key.Epoch)
+	}
+	if err := CT.checkHeader(); err != nil {
+		return nil, err
+	}
+	C2 := new(bn256.G2).Add(CT.CW, pvoabe.Ops.G2Mul(CT.Cprime, key.C))
+	T := new(bn256.GT).Add(pvoabe.Ops.Pair(key.K, C2), new(bn256.GT).Neg(R))
+	keyGt := new(bn256.GT).Add(CT.C, new(bn256.GT).Neg(T))
+	if err := pvoabe.checkKeyTag(CT.Tag, keyGt, CT.originDigest()); err != nil {
+		return nil, err
+	}
+	return keyGt, nil
+}
+
+// Trace 检查泄露的密钥是否结构良好，并由身份表返回其持有者：
+// e(h^t, g2) = e(h, L)，e(K, W·g2^c) = e(g,g)^{α_e} · e(h^t, g2)，α_e 为密钥所在纪元的主密钥
+func (tr *Tracer) Trace(pk *PublicKey, leaked *TraceableKey) (string, error) {
+	if pk.W == nil {
+		return "", fmt.Errorf("tracing not enabled")
+	}
+	if leaked == nil || leaked.OSK == nil || leaked.OSK.L == nil || leaked.OSK.Ht == nil || leaked.K == nil || leaked.C == nil {
+		return "", ErrMalformedKey
+	}
+	if leaked.C.Sign() <= 0 || leaked.C.Cmp(pk.PP.Order) >= 0 {
+		return "", fmt.Errorf("%w: c out of range", ErrMalformedKey)
+	}
+	g2 := new(bn256.G2).ScalarBaseMult(big.NewInt(1))
+	eHt := bn256.Pair(leaked.OSK.Ht, g2)
+	if eHt.String() != bn256.Pair(pk.PP.H, leaked.OSK.L).String() {
+		return "", fmt.Errorf("%w: h^t does not match L", ErrMalformedKey)
+	}
+	base, ok := tr.bases[leaked.Epoch]
+	if !ok {
+		return "", fmt.Errorf("%w: unknown epoch %d", ErrMalformedKey, leaked.Epoch)
+	}
+	wc := new(bn256.G2).Add(pk.W, new(bn256.G2).ScalarBaseMult(leaked.C))
+	if bn256.Pair(leaked.K, wc).String() != new(bn256.GT).Add(base, eHt).String() {
+		return "", fmt.Errorf("%w: K does not match c", ErrMalformedKey)
+	}
+	id, ok := tr.Table[leaked.C.String()]
+	if !ok {
+		return "", ErrUnknownIdentity
+	}
+	return id, nil
+}
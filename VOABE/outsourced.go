@@ -0,0 +1,159 @@
+package VOABE
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/AUKUS561/PVOABE/oabe"
+	"github.com/fentec-project/bn256"
+)
+
+// Name 是 VOABE 在 oabe 接口中的方案名
+const Name = "VOABE"
+
+// Outsourced 把 VOABE 适配为 oabe.OutsourcedABE：
+// EncCS + GenProofForPV 对应 ProxyEncrypt，PV 的 VerifyProofSymmetric + Sanitize 对应 VerifyEncryption，
+// DecCS/DecDU 对应 ProxyDecrypt/Decrypt。DecCS 需要 Sanitize 之后的密文，因此 VerifyEncryption 不可省略
+type Outsourced struct {
+	*VOABE
+}
+
+func NewOutsourced() *Outsourced {
+	return &Outsourced{VOABE: NewVOABE()}
+}
+
+// publicParams 是 pk 加上 PV 的公钥
+type publicParams struct {
+	PK     *pk
+	PkPV   *bn256.G1
+	PkPVG2 *bn256.G2
+}
+
+// outsourcedCT 记录 DO 的中间密文、CS 的最终密文以及 CS 给 PV 的证明
+type outsourcedCT struct {
+	Do        *Cph
+	PolicySet []string
+	CS        *CPh
+	Proof     *Proof
+	Sanitized bool
+}
+
+// Final 返回交给 DU 的密文：CS 生成并经 PV Sanitize 的 CPh
+func (v *outsourcedCT) Final() any { return v.CS }
+
+func (o *Outsourced) Name() string { return Name }
+
+func (o *Outsourced) Supports() oabe.Support {
+	return oabe.Support{ProxyEncrypt: true, VerifyEncryption: true}
+}
+
+func (o *Outsourced) Setup(universe []string) (*oabe.Authority, error) {
+	pk, msk := o.SetUp(universe)
+	pkPV, pkPVG2, skPV := o.KeyGenPV(pk, msk)
+	return &oabe.Authority{
+		Scheme:   Name,
+		Universe: universe,
+		Public:   &publicParams{PK: pk, PkPV: pkPV, PkPVG2: pkPVG2},
+		Master:   msk,
+		Verifier: skPV,
+	}, nil
+}
+
+func (o *Outsourced) KeyGen(auth *oabe.Authority, id string, attrs []string) (*oabe.UserKey, error) {
+	if err := oabe.Check(Name, auth.Scheme); err != nil {
+		return nil, err
+	}
+	skCS, skU := o.KeyGenU(auth.Public.(*publicParams).PK, auth.Master.(*msk), id, attrs)
+	return &oabe.UserKey{Scheme: Name, ID: id, Attrs: attrs, Proxy: skCS, User: skU}, nil
+}
+
+func (o *Outsourced) Encrypt(auth *oabe.Authority, owner *oabe.UserKey, policy string) (*oabe.Ciphertext, *bn256.GT, error) {
+	if err := oabe.Check(Name, auth.Scheme); err != nil {
+		return nil, nil, err
+	}
+	pp := auth.Public.(*publicParams)
+	cph, KR, policySet, err := o.EncDoPolicy(pp.PK, pp.PkPV, pp.PkPVG2, policy)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &oabe.Ciphertext{Scheme: Name, Policy: policy, Value: &outsourcedCT{Do: cph, PolicySet: policySet}}, KR, nil
+}
+
+func (o *Outsourced) ProxyEncrypt(auth *oabe.Authority, owner *oabe.UserKey, ct *oabe.Ciphertext) error {
+	if err := oabe.Check(Name, ct.Scheme); err != nil {
+		return err
+	}
+	pp := auth.Public.(*publicParams)
+	v := ct.Value.(*outsourcedCT)
+	v.CS = o.EncCS(pp.PK, v.Do, pp.PkPV)
+
+	// 证明只覆盖 DO 持有的策略属性 S*DO
+	held := make(map[string]bool, len(owner.Attrs))
+	for _, a := range owner.Attrs {
+		held[a] = true
+	}
+	var SDoStar []string
+	for _, a := range v.PolicySet {
+		if held[a] {
+			SDoStar = append(SDoStar, a)
+		}
+	}
+	proof, err := o.GenProofForPV(pp.PK, owner.Proxy.(*SKcs), v.CS, owner.ID, SDoStar)
+	if err != nil {
+		return err
+	}
+	v.Proof = proof
+	return nil
+}
+
+func (o *Outsourced) VerifyEncryption(auth *oabe.Authority, owner *oabe.UserKey, ct *oabe.Ciphertext) error {
+	if err := oabe.Check(Name, ct.Scheme); err != nil {
+		return err
+	}
+	pp := auth.Public.(*publicParams)
+	v := ct.Value.(*outsourcedCT)
+	if v.CS == nil || v.Proof == nil {
+		return errors.New("VOABE: ciphertext has not been processed by CS")
+	}
+	if v.Sanitized {
+		return nil
+	}
+	if !o.VerifyProofSymmetric(pp.PK, v.CS, v.Proof, owner.ID) {
+		return oabe.ErrVerificationFailed
+	}
+	sanitized, proof := o.Sanitize(pp.PK, auth.Verifier.(*big.Int), v.CS)
+	//CS 存储之前检查 PV 的 Sanitize 证明
+	if !o.VerifySanitize(pp.PK, pp.PkPV, v.CS, sanitized, proof) {
+		return oabe.ErrVerificationFailed
+	}
+	v.CS = sanitized
+	v.Sanitized = true
+	return nil
+}
+
+func (o *Outsourced) ProxyDecrypt(auth *oabe.Authority, user *oabe.UserKey, ct *oabe.Ciphertext) (*oabe.Transformed, error) {
+	if err := oabe.Check(Name, ct.Scheme); err != nil {
+		return nil, err
+	}
+	v := ct.Value.(*outsourcedCT)
+	if !v.Sanitized {
+		return nil, fmt.Errorf("VOABE: ciphertext must pass VerifyEncryption (PV sanitize) before DecCS")
+	}
+	phi, err := o.DecCS(auth.Public.(*publicParams).PK, v.CS, user.Proxy.(*SKcs), user.Attrs)
+	if err != nil {
+		return nil, err
+	}
+	return &oabe.Transformed{Scheme: Name, Value: phi}, nil
+}
+
+func (o *Outsourced) VerifyDecryption(auth *oabe.Authority, user *oabe.UserKey, ct *oabe.Ciphertext, tr *oabe.Transformed) error {
+	return oabe.ErrNotSupported
+}
+
+func (o *Outsourced) Decrypt(auth *oabe.Authority, user *oabe.UserKey, ct *oabe.Ciphertext, tr *oabe.Transformed) (*bn256.GT, error) {
+	if err := oabe.Check(Name, tr.Scheme); err != nil {
+		return nil, err
+	}
+	return o.DecDU(tr.Value.(*bn256.GT), ct.Value.(*outsourcedCT).CS, user.User.(*Sku))
+}
@@ -0,0 +1,205 @@
+package VOABE
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"io"
+	"math/big"
+	"sort"
+
+	"github.com/AUKUS561/PVOABE/LSSS"
+	"github.com/fentec-project/bn256"
+)
+
+/*
+Sanitize proof
+
+Sanitize is linear in the PV's secret c and its fresh r. Writing F(c, r) for
+
+	pkPV = g^c,  C0 = (g^a)^r,  Cnew/C = e(g, C')^{-c} · e(g,g)^{α r},
+	C'new/C' = g^r,  C''new/C'' = C'^{-c} · w^r,
+	Ci,new/Ci = Di^c · hρ(i)^{-r},  Di,new/Di = g^r,
+
+the PV proves knowledge of (c, r) with F(c, r) = Y, where Y is computed from the two ciphertexts
+and pkPV (a Schnorr proof for a linear map, made non-interactive with Fiat-Shamir). The statement
+binds the MSP, so the proof cannot be replayed on another ciphertext.
+*/
+
+// SanitizeProof is a NIZK that Sanitize used the c behind pkPV and one consistent r
+type SanitizeProof struct {
+	E, Zc, Zr *big.Int
+}
+
+// sanitizeImage is a point in the range of F
+type sanitizeImage struct {
+	Pk, C0  *bn256.G1
+	C       *bn256.GT
+	CPrime  *bn256.G2
+	CSecond *bn256.G2
+	Ci, Di  map[int]*bn256.G1
+}
+
+// sanitizeMap evaluates F(c, r) on the input ciphertext; it returns nil if a row has no hx
+func (voabe *VOABE) sanitizeMap(pk *pk, before *CPh, eGC *bn256.GT, c, r *big.Int) *sanitizeImage {
+	cNeg := new(big.Int).Sub(voabe.P, c)
+	cNeg.Mod(cNeg, voabe.P)
+	rNeg := new(big.Int).Sub(voabe.P, r)
+	rNeg.Mod(rNeg, voabe.P)
+
+	img := &sanitizeImage{
+		Pk:     voabe.Ops.G1Mul(pk.G, c),
+		C0:     voabe.Ops.G1Mul(pk.Ga, r),
+		C:      new(bn256.GT).Add(voabe.Ops.GTExp(eGC, cNeg), voabe.Ops.GTExp(pk.Base, r)),
+		CPrime: voabe.Ops.G2Mul(pk.G2, r),
+		Ci:     make(map[int]*bn256.G1, len(before.Ci)),
+		Di:     make(map[int]*bn256.G1, len(before.Di)),
+	}
+	img.CSecond = new(bn256.G2).Add(voabe.Ops.G2Mul(before.CPrime2, cNeg), voabe.Ops.G2Mul(pk.WG2, r))
+	gr := voabe.Ops.G1Mul(pk.G, r)
+	for i, Di := range before.Di {
+		if i < 0 || i >= len(before.MSP.RowToAttrib) {
+			return nil
+		}
+		hx, ok := pk.Hx[before.MSP.RowToAttrib[i]]
+		if !ok {
+			return nil
+		}
+		img.Ci[i] = new(bn256.G1).Add(voabe.Ops.G1Mul(Di, c), voabe.Ops.G1Mul(hx, rNeg))
+		img.Di[i] = gr
+	}
+	return img
+}
+
+// sanitizeDelta computes Y = (pkPV, C0, after/before component-wise); it returns nil if the two
+// ciphertexts do not have the same rows
+func sanitizeDelta(pkPV *bn256.G1, before, after *CPh) *sanitizeImage {
+	if len(before.Ci) != len(after.Ci) || len(before.Di) != len(after.Di) || len(before.Ci) != len(before.Di) {
+		return nil
+	}
+	y := &sanitizeImage{
+		Pk:      pkPV,
+		C0:      after.C0,
+		C:       new(bn256.GT).Add(after.C, new(bn256.GT).Neg(before.C)),
+		CPrime:  new(bn256.G2).Add(after.CPrime2, new(bn256.G2).Neg(before.CPrime2)),
+		CSecond: new(bn256.G2).Add(after.CSecond2, new(bn256.G2).Neg(before.CSecond2)),
+		Ci:      make(map[int]*bn256.G1, len(before.Ci)),
+		Di:      make(map[int]*bn256.G1, len(before.Di)),
+	}
+	for i := range before.Ci {
+		ci, cOK := after.Ci[i]
+		di, dOK := after.Di[i]
+		bi, bOK := before.Di[i]
+		if !cOK || !dOK || !bOK || ci == nil || di == nil || bi == nil || before.Ci[i] == nil {
+			return nil
+		}
+		y.Ci[i] = new(bn256.G1).Add(ci, new(bn256.G1).Neg(before.Ci[i]))
+		y.Di[i] = new(bn256.G1).Add(di, new(bn256.G1).Neg(bi))
+	}
+	return y
+}
+
+// combine returns a · y^{-e} in every component
+func (a *sanitizeImage) combine(y *sanitizeImage, e *big.Int, p *big.Int) *sanitizeImage {
+	eNeg := new(big.Int).Sub(p, e)
+	eNeg.Mod(eNeg, p)
+	out := &sanitizeImage{
+		Pk:      new(bn256.G1).Add(a.Pk, new(bn256.G1).ScalarMult(y.Pk, eNeg)),
+		C0:      new(bn256.G1).Add(a.C0, new(bn256.G1).ScalarMult(y.C0, eNeg)),
+		C:       new(bn256.GT).Add(a.C, new(bn256.GT).ScalarMult(y.C, eNeg)),
+		CPrime:  new(bn256.G2).Add(a.CPrime, new(bn256.G2).ScalarMult(y.CPrime, eNeg)),
+		CSecond: new(bn256.G2).Add(a.CSecond, new(bn256.G2).ScalarMult(y.CSecond, eNeg)),
+		Ci:      make(map[int]*bn256.G1, len(a.Ci)),
+		Di:      make(map[int]*bn256.G1, len(a.Di)),
+	}
+	for i := range a.Ci {
+		out.Ci[i] = new(bn256.G1).Add(a.Ci[i], new(bn256.G1).ScalarMult(y.Ci[i], eNeg))
+		out.Di[i] = new(bn256.G1).Add(a.Di[i], new(bn256.G1).ScalarMult(y.Di[i], eNeg))
+	}
+	return out
+}
+
+// write hashes the image with rows in ascending order
+func (a *sanitizeImage) write(h io.Writer) {
+	h.Write(a.Pk.Marshal())
+	h.Write(a.C0.Marshal())
+	h.Write(a.C.Marshal())
+	h.Write(a.CPrime.Marshal())
+	h.Write(a.CSecond.Marshal())
+	rows := make([]int, 0, len(a.Ci))
+	for i := range a.Ci {
+		rows = append(rows, i)
+	}
+	sort.Ints(rows)
+	for _, i := range rows {
+		h.Write(a.Ci[i].Marshal())
+		h.Write(a.Di[i].Marshal())
+	}
+}
+
+// sanitizeChallenge is the Fiat-Shamir challenge H(MSP, before, Y, A) mod p
+func (voabe *VOABE) sanitizeChallenge(before *CPh, y, a *sanitizeImage) *big.Int {
+	h := sha256.New()
+	h.Write([]byte("VOABE/sanitize"))
+	h.Write(LSSS.MSPDigest(before.MSP))
+	h.Write(before.C.Marshal())
+	h.Write(before.CPrime2.Marshal())
+	h.Write(before.CSecond2.Marshal())
+	y.write(h)
+	a.write(h)
+	e := new(big.Int).SetBytes(h.Sum(nil))
+	return e.Mod(e, voabe.P)
+}
+
+// proveSanitize proves that after = Sanitize(before) under (c, r)
+func (voabe *VOABE) proveSanitize(pk *pk, c, r *big.Int, before, after *CPh) *SanitizeProof {
+	kc, err := rand.Int(rand.Reader, voabe.P)
+	if err != nil {
+		return nil
+	}
+	kr, err := rand.Int(rand.Reader, voabe.P)
+	if err != nil {
+		return nil
+	}
+	eGC := voabe.Ops.Pair(pk.G, before.CPrime2)
+	a := voabe.sanitizeMap(pk, before, eGC, kc, kr)
+	y := sanitizeDelta(voabe.Ops.G1Mul(pk.G, c), before, after)
+	if a == nil || y == nil {
+		return nil
+	}
+	e := voabe.sanitizeChallenge(before, y, a)
+
+	zc := new(big.Int).Mul(e, c)
+	zc.Add(zc, kc).Mod(zc, voabe.P)
+	zr := new(big.Int).Mul(e, r)
+	zr.Add(zr, kr).Mod(zr, voabe.P)
+	return &SanitizeProof{E: e, Zc: zc, Zr: zr}
+}
+
+// VerifySanitize checks that after was produced from before by Sanitize with the c behind pkPV.
+// The CS runs it before storing the sanitized ciphertext
+func (voabe *VOABE) VerifySanitize(pk *pk, pkPV *bn256.G1, before, after *CPh, proof *SanitizeProof) bool {
+	if proof == nil || proof.E == nil || proof.Zc == nil || proof.Zr == nil || pkPV == nil {
+		return false
+	}
+	if before == nil || after == nil || before.Cph == nil || after.Cph == nil || before.C0 != nil || after.C0 == nil {
+		return false
+	}
+	if after.C == nil || after.CPrime2 == nil || after.CSecond2 == nil || after.MSP == nil || before.MSP == nil {
+		return false
+	}
+	if string(LSSS.MSPDigest(before.MSP)) != string(LSSS.MSPDigest(after.MSP)) {
+		return false
+	}
+	y := sanitizeDelta(pkPV, before, after)
+	if y == nil {
+		return false
+	}
+	eGC := voabe.Ops.Pair(pk.G, before.CPrime2)
+	fz := voabe.sanitizeMap(pk, before, eGC, proof.Zc, proof.Zr)
+	if fz == nil {
+		return false
+	}
+	//A = F(zc, zr) · Y^{-e}
+	a := fz.combine(y, proof.E, voabe.P)
+	return voabe.sanitizeChallenge(before, y, a).Cmp(proof.E) == 0
+}
@@ -0,0 +1,53 @@
+package VOABE
+
+import "github.com/AUKUS561/PVOABE/size"
+
+// Size 列出 DO 中间密文的 C、CPrime2、CSecond2 与 {λi}
+func (c *Cph) Size() size.Report {
+	return size.Breakdown("VOABE.Cph", c)
+}
+
+// EncodedLen 返回 DO 中间密文在编码 enc 下的字节数
+func (c *Cph) EncodedLen(enc size.Encoding) int {
+	return c.Size().Len(enc)
+}
+
+// Size 列出 CS 密文的各部分，包括嵌入的 Cph 与 C0、{Ci}、{Di}
+func (c *CPh) Size() size.Report {
+	return size.Breakdown("VOABE.CPh", c)
+}
+
+// EncodedLen 返回 CS 密文在编码 enc 下的字节数
+func (c *CPh) EncodedLen(enc size.Encoding) int {
+	return c.Size().Len(enc)
+}
+
+// Size 列出 CS 发给 PV 的证明中每个元素，S*DO 按属性名的字节数计
+func (p *Proof) Size() size.Report {
+	return size.Breakdown("VOABE.Proof", p)
+}
+
+// EncodedLen 返回证明在编码 enc 下的字节数
+func (p *Proof) EncodedLen(enc size.Encoding) int {
+	return p.Size().Len(enc)
+}
+
+// Size 列出 CS 持有的用户密钥 SKcs
+func (sk *SKcs) Size() size.Report {
+	return size.Breakdown("VOABE.SKcs", sk)
+}
+
+// EncodedLen 返回 SKcs 在编码 enc 下的字节数
+func (sk *SKcs) EncodedLen(enc size.Encoding) int {
+	return sk.Size().Len(enc)
+}
+
+// Size 列出 Sanitize 证明的挑战与两个响应
+func (p *SanitizeProof) Size() size.Report {
+	return size.Breakdown("VOABE.SanitizeProof", p)
+}
+
+// EncodedLen 返回 Sanitize 证明在编码 enc 下的字节数
+func (p *SanitizeProof) EncodedLen(enc size.Encoding) int {
+	return p.Size().Len(enc)
+}
@@ -0,0 +1,190 @@
+package VOABE
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/fentec-project/bn256"
+)
+
+/*
+Tracing and blacklist
+
+KeyGenU binds IDu into Ku = g^{α1} g^{a tu} w^{1/(b+H(IDu))} and Ru = g^{1/(b+H(IDu))},
+and registers Ru -> IDu in the identity table (VOABE.Identities). Trace checks that a leaked SKcs is well-formed,
+    e(Ku, g) = e(g,g)^{α1} · e(g^a, Lu) · e(Ru, w)   and   e(g, Ku,x) = e(hx, Lu) for every x,
+looks up Ru in the identity table, and confirms the identity with msk: Ru^{b+H(IDu)} = g.
+
+Blacklisted identities are refused by DecCS (keys are matched through Ru) and by
+VerifyProofSymmetric (the DO identity is given explicitly). DecCS fails closed: when an
+identity table is configured, a key whose Ru is not in it is refused with ErrUnknownIdentity,
+so a cloud with an empty or stale table does not decrypt for keys it cannot attribute.
+*/
+
+var (
+	ErrBlacklisted     = errors.New("VOABE: identity is blacklisted")
+	ErrMalformedKey    = errors.New("VOABE: key is not well-formed")
+	ErrUnknownIdentity = errors.New("VOABE: key not found in identity table")
+)
+
+// IdentityTable is the authority's identity table (Ru -> IDu) and the blacklist of identities.
+// KeyGenU registers every issued key in it; Trace, DecCS and VerifyProofSymmetric consult it.
+// The cloud that runs DecCS is usually not the process that ran KeyGenU, so the table is an
+// interface: share one table between them, or persist a MemoryTable with Snapshot and LoadMemoryTable
+type IdentityTable interface {
+	Register(Ru *bn256.G1, IDu string)
+	Lookup(Ru *bn256.G1) (string, bool)
+	Blacklist(ids ...string)
+	IsBlacklisted(id string) bool
+}
+
+// MemoryTable is an in-memory IdentityTable, safe for concurrent use
+type MemoryTable struct {
+	mu        sync.RWMutex
+	ids       map[string]string
+	blacklist map[string]bool
+}
+
+func NewMemoryTable() *MemoryTable {
+	return &MemoryTable{ids: make(map[string]string), blacklist: make(map[string]bool)}
+}
+
+func (t *MemoryTable) Register(Ru *bn256.G1, IDu string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.ids[string(Ru.Marshal())] = IDu
+}
+
+func (t *MemoryTable) Lookup(Ru *bn256.G1) (string, bool) {
+	if Ru == nil {
+		return "", false
+	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	id, ok := t.ids[string(Ru.Marshal())]
+	return id, ok
+}
+
+func (t *MemoryTable) Blacklist(ids ...string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, id := range ids {
+		t.blacklist[id] = true
+	}
+}
+
+func (t *MemoryTable) IsBlacklisted(id string) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.blacklist[id]
+}
+
+// TableSnapshot is the serializable content of a MemoryTable
+type TableSnapshot struct {
+	IDs       map[string]string `json:"ids"` // hex(Ru.Marshal()) -> IDu
+	Blacklist []string          `json:"blacklist"`
+}
+
+// Snapshot returns the content of the table, e.g. to be stored as JSON
+func (t *MemoryTable) Snapshot() TableSnapshot {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	s := TableSnapshot{IDs: make(map[string]string, len(t.ids))}
+	for ru, id := range t.ids {
+		s.IDs[hex.EncodeToString([]byte(ru))] = id
+	}
+	for id := range t.blacklist {
+		s.Blacklist = append(s.Blacklist, id)
+	}
+	sort.Strings(s.Blacklist)
+	return s
+}
+
+// LoadMemoryTable rebuilds a MemoryTable from a snapshot, checking that every Ru is a point of G1
+func LoadMemoryTable(s TableSnapshot) (*MemoryTable, error) {
+	t := NewMemoryTable()
+	for ru, id := range s.IDs {
+		b, err := hex.DecodeString(ru)
+		if err != nil {
+			return nil, fmt.Errorf("VOABE: identity table entry %q: %w", id, err)
+		}
+		Ru := new(bn256.G1)
+		if _, err := Ru.Unmarshal(b); err != nil {
+			return nil, fmt.Errorf("VOABE: identity table entry %q: %w", id, err)
+		}
+		t.Register(Ru, id)
+	}
+	t.Blacklist(s.Blacklist...)
+	return t, nil
+}
+
+// Blacklist adds identities to the blacklist; it does nothing when no identity table is configured
+func (voabe *VOABE) Blacklist(ids ...string) {
+	if voabe.Identities != nil {
+		voabe.Identities.Blacklist(ids...)
+	}
+}
+
+// IsBlacklisted reports whether id is on the blacklist
+func (voabe *VOABE) IsBlacklisted(id string) bool {
+	return voabe.Identities != nil && voabe.Identities.IsBlacklisted(id)
+}
+
+// checkIdentity refuses a key whose Ru is not in the identity table or whose identity is
+// blacklisted. Without an identity table there is nothing to check against
+func (voabe *VOABE) checkIdentity(Ru *bn256.G1) error {
+	if voabe.Identities == nil {
+		return nil
+	}
+	id, ok := voabe.Identities.Lookup(Ru)
+	if !ok {
+		return ErrUnknownIdentity
+	}
+	if voabe.Identities.IsBlacklisted(id) {
+		return fmt.Errorf("%w: %s", ErrBlacklisted, id)
+	}
+	return nil
+}
+
+// Trace checks that skcs is a well-formed key and returns the identity it was issued to
+func (voabe *VOABE) Trace(pk *pk, msk *msk, skcs *SKcs) (string, error) {
+	if skcs == nil || skcs.Ku == nil || skcs.Lu2 == nil || skcs.Ru == nil {
+		return "", ErrMalformedKey
+	}
+	//e(Ku, g) == e(g,g)^α1 · e(g^a, Lu) · e(Ru, w)
+	left := voabe.Ops.Pair(skcs.Ku, pk.G2)
+	right := new(bn256.GT).Add(pk.Base1, voabe.Ops.Pair(pk.Ga, skcs.Lu2))
+	right.Add(right, voabe.Ops.Pair(skcs.Ru, pk.WG2))
+	if left.String() != right.String() {
+		return "", fmt.Errorf("%w: Ku does not match Lu and Ru", ErrMalformedKey)
+	}
+	//e(g, Ku,x) == e(hx, Lu)
+	for attr, kux2 := range skcs.Kux2 {
+		hx, ok := pk.Hx[attr]
+		if !ok || kux2 == nil {
+			return "", fmt.Errorf("%w: unknown attribute %s", ErrMalformedKey, attr)
+		}
+		if voabe.Ops.Pair(pk.G, kux2).String() != voabe.Ops.Pair(hx, skcs.Lu2).String() {
+			return "", fmt.Errorf("%w: Ku,x for %s does not match Lu", ErrMalformedKey, attr)
+		}
+	}
+
+	if voabe.Identities == nil {
+		return "", ErrUnknownIdentity
+	}
+	id, ok := voabe.Identities.Lookup(skcs.Ru)
+	if !ok {
+		return "", ErrUnknownIdentity
+	}
+	//Ru^{b+H(IDu)} == g
+	e := new(big.Int).Add(msk.b, HashToBigInt(id))
+	e.Mod(e, voabe.P)
+	if voabe.Ops.G1Mul(skcs.Ru, e).String() != pk.G.String() {
+		return "", fmt.Errorf("%w: Ru does not match identity %s", ErrMalformedKey, id)
+	}
+	return id, nil
+}
@@ -10,21 +10,35 @@ import (
 	"log"
 	"math/big"
 	"sort"
-	"strconv"
 
 	"github.com/AUKUS561/PVOABE/LSSS"
+	"github.com/AUKUS561/PVOABE/opcount"
+	"github.com/AUKUS561/PVOABE/policy"
+	"github.com/AUKUS561/PVOABE/policygen"
 	"github.com/fentec-project/bn256"
 	"github.com/fentec-project/gofe/abe"
 	"github.com/fentec-project/gofe/sample"
 )
 
 type VOABE struct {
-	P *big.Int
+	P     *big.Int
+	Plans *LSSS.PlanCache  // reconstruction plans shared by DecCS calls on the same policy
+	Ops   *opcount.Counter // operation counter, nil disables counting
+	// Identities is the identity table and blacklist consulted by Trace, DecCS and VerifyProofSymmetric.
+	// NewVOABE sets an in-memory table; nil disables the identity checks
+	Identities IdentityTable
+}
+
+// SetCounter enables operation counting with c, or disables it when c is nil
+func (voabe *VOABE) SetCounter(c *opcount.Counter) {
+	voabe.Ops = c
 }
 
 func NewVOABE() *VOABE {
 	return &VOABE{
-		P: bn256.Order,
+		P:          bn256.Order,
+		Plans:      LSSS.NewPlanCache(),
+		Identities: NewMemoryTable(),
 	}
 }
 
@@ -62,22 +76,22 @@ func (voabe *VOABE) SetUp(U []string) (*pk, *msk) {
 	a, _ := sampler.Sample()
 	b, _ := sampler.Sample()
 
-	g := new(bn256.G1).ScalarBaseMult(big.NewInt(1))   //g
-	h := new(bn256.G1).ScalarBaseMult(big.NewInt(2))   //h
-	w := new(bn256.G1).ScalarBaseMult(big.NewInt(3))   //w
-	wG2 := new(bn256.G2).ScalarBaseMult(big.NewInt(3)) //wG2
+	g := voabe.Ops.G1Base(big.NewInt(1))   //g
+	h := voabe.Ops.G1Base(big.NewInt(2))   //h
+	w := voabe.Ops.G1Base(big.NewInt(3))   //w
+	wG2 := voabe.Ops.G2Base(big.NewInt(3)) //wG2
 
 	//g1:e(g,g)^alpha->e(g,g1)^alpha,only for pairing
-	g2 := new(bn256.G2).ScalarBaseMult(big.NewInt(1))
-	pair := bn256.Pair(g, g2)
-	base := new(bn256.GT).ScalarMult(pair, alpha)
-	base1 := new(bn256.GT).ScalarMult(pair, alpha1)
+	g2 := voabe.Ops.G2Base(big.NewInt(1))
+	pair := voabe.Ops.Pair(g, g2)
+	base := voabe.Ops.GTExp(pair, alpha)
+	base1 := voabe.Ops.GTExp(pair, alpha1)
 	//g^a
-	ga := new(bn256.G1).ScalarMult(g, a)
+	ga := voabe.Ops.G1Mul(g, a)
 	//ga2 := new(bn256.G2).ScalarMult(g2, a)
 	//g^b
-	gb := new(bn256.G1).ScalarMult(g, b)
-	g2b := new(bn256.G2).ScalarMult(g2, b)
+	gb := voabe.Ops.G1Mul(g, b)
+	g2b := voabe.Ops.G2Mul(g2, b)
 	//Hx as a map which attribute as index and G1 elements as value
 	// Hx: map attribute name -> G1 element
 	hx := make(map[string]*bn256.G1)
@@ -88,8 +102,8 @@ func (voabe *VOABE) SetUp(U []string) (*pk, *msk) {
 		ri, _ := sampler.Sample()
 		//x := HashToG1(attr)
 		//hx[attr] = x
-		hx[attr] = new(bn256.G1).ScalarBaseMult(ri)
-		hxG2[attr] = new(bn256.G2).ScalarBaseMult(ri)
+		hx[attr] = voabe.Ops.G1Base(ri)
+		hxG2[attr] = voabe.Ops.G2Base(ri)
 	}
 	return &pk{
 			Order: voabe.P,
@@ -116,8 +130,8 @@ func (voabe *VOABE) KeyGenPV(pk *pk, msk *msk) (pkPV *bn256.G1, pkPVG2 *bn256.G2
 	sampler := sample.NewUniformRange(big.NewInt(1), voabe.P)
 	c, _ := sampler.Sample()
 	//pkPV=g^c
-	pkPV = new(bn256.G1).ScalarMult(pk.G, c)
-	pkPVG2 = new(bn256.G2).ScalarMult(pk.G2, c)
+	pkPV = voabe.Ops.G1Mul(pk.G, c)
+	pkPVG2 = voabe.Ops.G2Mul(pk.G2, c)
 	//skPV=c
 	return pkPV, pkPVG2, c
 }
@@ -161,10 +175,10 @@ func (voabe *VOABE) KeyGenU(pk *pk, msk *msk, IDu string, Su []string) (*SKcs, *
 	invDenom := new(big.Int).ModInverse(denom, voabe.P) // 1/(b+H(IDu))
 
 	// Ku = g^{α1} g^{a tu} w^{1/(b+H(IDu))}
-	Ku := new(bn256.G1).ScalarMult(pk.G, msk.alpha1) // g^{α1}
-	tmp := new(bn256.G1).ScalarMult(pk.Ga, t)        // g^{a tu}
+	Ku := voabe.Ops.G1Mul(pk.G, msk.alpha1) // g^{α1}
+	tmp := voabe.Ops.G1Mul(pk.Ga, t)        // g^{a tu}
 	Ku.Add(Ku, tmp)
-	tmpW := new(bn256.G1).ScalarMult(pk.W, invDenom) // w^{1/(b+H(IDu))}
+	tmpW := voabe.Ops.G1Mul(pk.W, invDenom) // w^{1/(b+H(IDu))}
 	Ku.Add(Ku, tmpW)
 
 	// Ku2 = g2^{α1} g2^{a tu} w2^{1/(b+H(IDu))}
@@ -176,10 +190,10 @@ func (voabe *VOABE) KeyGenU(pk *pk, msk *msk, IDu string, Su []string) (*SKcs, *
 
 	// Lu = g^{tu}
 	//Lu := new(bn256.G1).ScalarMult(pk.G, t)
-	Lu2 := new(bn256.G2).ScalarMult(pk.G2, t)
+	Lu2 := voabe.Ops.G2Mul(pk.G2, t)
 
 	// Ru = g^{1/(b+H(IDu))}
-	Ru := new(bn256.G1).ScalarMult(pk.G, invDenom)
+	Ru := voabe.Ops.G1Mul(pk.G, invDenom)
 	//Ru2 := new(bn256.G2).ScalarMult(pk.G2, invDenom)
 
 	// K{u,x} = hx^{tu}  for x ∈ Su
@@ -198,11 +212,11 @@ func (voabe *VOABE) KeyGenU(pk *pk, msk *msk, IDu string, Su []string) (*SKcs, *
 		// exp.Mod(exp, voabe.P)
 		// hx2 := new(bn256.G2).ScalarMult(pk.G2, exp)
 		// Kux2[attr] = new(bn256.G2).ScalarMult(hx2, t) // hx^{t} in G2
-		Kux2[attr] = new(bn256.G2).ScalarMult(pk.HxG2[attr], t)
+		Kux2[attr] = voabe.Ops.G2Mul(pk.HxG2[attr], t)
 	}
 	// sku = g^{α2} g^{a tu}
-	b1 := new(bn256.G1).ScalarMult(pk.G, msk.alpha2)
-	b2 := new(bn256.G1).ScalarMult(pk.Ga, t)
+	b1 := voabe.Ops.G1Mul(pk.G, msk.alpha2)
+	b2 := voabe.Ops.G1Mul(pk.Ga, t)
 	SkUser := new(bn256.G1).Add(b1, b2)
 
 	// sku(G2)
@@ -221,6 +235,10 @@ func (voabe *VOABE) KeyGenU(pk *pk, msk *msk, IDu string, Su []string) (*SKcs, *
 		Kux2: Kux2,
 	}
 
+	if voabe.Identities != nil {
+		voabe.Identities.Register(Ru, IDu)
+	}
+
 	userKey := &Sku{
 		Sku: SkUser,
 		//Sku2: SkUserG2,
@@ -239,84 +257,65 @@ type Cph struct {
 	MSP      *abe.MSP         // access structure
 }
 
+// GeneratePolicy 返回随机策略中出现的属性及策略本身
 func GeneratePolicy(attrCount int) ([]string, string) {
+	p := policygen.GeneratePolicy(attrCount)
+	return policy.MustParse(p).Attributes(), p
+}
 
-	attrs := make([]string, attrCount)
-	for i := 0; i < attrCount; i++ {
-		attrs[i] = "Attr" + strconv.Itoa(i+1)
-	}
-
-	randInt := func(n int) int {
-		r, _ := rand.Int(rand.Reader, big.NewInt(int64(n)))
-		return int(r.Int64())
-	}
-
-	for i := attrCount - 1; i > 0; i-- {
-		j := randInt(i + 1)
-		attrs[i], attrs[j] = attrs[j], attrs[i]
+// EncDo encrypt the record R by access structure Γ and send the intermediate ciphertexts to CS
+func (voave *VOABE) EncDo(pk *pk, pkPV *bn256.G1, pkPVG2 *bn256.G2, attrNum int) (*Cph, []string) {
+	_, p := GeneratePolicy(attrNum)
+	cph, _, policySet, err := voave.EncDoPolicy(pk, pkPV, pkPVG2, p)
+	if err != nil {
+		log.Fatalf("EncDo: %v", err)
 	}
+	return cph, policySet
+}
 
-	var build func([]string) string
-	build = func(list []string) string {
-
-		if len(list) == 1 {
-			return list[0]
-		}
-
-		op := "AND"
-		if randInt(2) == 0 {
-			op = "OR"
-		}
-
-		split := randInt(len(list)-1) + 1 // [1, len-1]
-		left := build(list[:split])
-		right := build(list[split:])
-
-		return "(" + left + " " + op + " " + right + ")"
+// EncDoPolicy 与 EncDo 相同，但使用给定的策略字符串，另外返回封装的密钥 KR 与策略中出现的属性
+func (voave *VOABE) EncDoPolicy(pk *pk, pkPV *bn256.G1, pkPVG2 *bn256.G2, policyStr string) (*Cph, *bn256.GT, []string, error) {
+	node, err := policy.Parse(policyStr)
+	if err != nil {
+		return nil, nil, nil, err
 	}
-
-	policy := build(attrs)
-
-	if len(policy) > 2 && policy[0] == '(' && policy[len(policy)-1] == ')' {
-		policy = policy[1 : len(policy)-1]
+	msp, err := node.ToMSP() //根据访问控制策略构建msp矩阵
+	if err != nil {
+		return nil, nil, nil, err
 	}
+	policySet := node.Attributes()
 
-	return attrs, policy
-}
-
-// EncDo encrypt the record R by access structure Γ and send the intermediate ciphertexts to CS
-func (voave *VOABE) EncDo(pk *pk, pkPV *bn256.G1, pkPVG2 *bn256.G2, attrNum int) (*Cph, []string) {
 	//Generate symmetric key KR
 	sampler := sample.NewUniformRange(big.NewInt(1), voave.P)
 	k, _ := sampler.Sample()
-	KR := new(bn256.GT).ScalarMult(pk.Base, k) //Base = e(g,g)^alpha
+	KR := voave.Ops.GTExp(pk.Base, k) //Base = e(g,g)^alpha
 
 	//λi = Mi · v
 	s, _ := sampler.Sample()
 
-	policySet, policy := GeneratePolicy(attrNum)
-	//fmt.Printf("Policy=%v\n", policy)
-	msp, _ := abe.BooleanToMSP(policy, false) //根据访问控制策略构建msp矩阵
-	Lambdai, _ := LSSS.Share(msp, s, voave.P)
+	Lambdai, err := LSSS.Share(msp, s, voave.P)
+	if err != nil {
+		return nil, nil, nil, err
+	}
 
 	//Compute C, C', C''
 	//Cpart1 = e(g,g)^{αs} = Base^s
-	C1 := new(bn256.GT).ScalarMult(pk.Base, s)
+	C1 := voave.Ops.GTExp(pk.Base, s)
 
 	//Cpart2 = e(g, pkPV)^s
-	b := bn256.Pair(pkPV, pk.G2)
-	C2 := new(bn256.GT).ScalarMult(b, s)
+	b := voave.Ops.Pair(pkPV, pk.G2)
+	C2 := voave.Ops.GTExp(b, s)
 
 	//C = KR * e(g,g)^{α s} * e(g,pkPV)^s
 	C := new(bn256.GT).Add(KR, C1)
 	C.Add(C, C2)
 
 	//C' = g^s
-	CPrime2 := new(bn256.G2).ScalarMult(pk.G2, s)
+	CPrime2 := voave.Ops.G2Mul(pk.G2, s)
 
 	//C'' = w^s pkPV^s
-	ws := new(bn256.G2).ScalarMult(pk.WG2, s)
-	pvs := new(bn256.G2).ScalarMult(pkPVG2, s)
+	ws := voave.Ops.G2Mul(pk.WG2, s)
+	pvs := voave.Ops.G2Mul(pkPVG2, s)
 	CSecond2 := new(bn256.G2).Add(ws, pvs)
 
 	return &Cph{
@@ -326,7 +325,7 @@ func (voave *VOABE) EncDo(pk *pk, pkPV *bn256.G1, pkPVG2 *bn256.G2, attrNum int)
 		CSecond2: CSecond2,
 		Lambdas:  Lambdai,
 		MSP:      msp,
-	}, policySet
+	}, KR, policySet, nil
 }
 
 // Final ciphertext
@@ -340,20 +339,23 @@ type CPh struct {
 func (voabe *VOABE) EncCS(pk *pk, cph *Cph, pkPV *bn256.G1) *CPh {
 	sampler := sample.NewUniformRange(big.NewInt(1), voabe.P)
 
+	// term1 = (g^a)^{λ_i} = g^{a λ_i}
+	gaLambdas := LSSS.ExpShares(pk.Ga, cph.Lambdas)
+	voabe.Ops.Add(opcount.G1Mul, len(cph.Lambdas))
+
 	CiMap := make(map[int]*bn256.G1)
 	DiMap := make(map[int]*bn256.G1)
-	for i, lambda := range cph.Lambdas {
+	for i := range cph.Lambdas {
 		//ri ∈ Z*_p
 		ri, _ := sampler.Sample()
 
 		// D_i = g^{ri}
-		Di := new(bn256.G1).ScalarMult(pk.G, ri)
+		Di := voabe.Ops.G1Mul(pk.G, ri)
 		DiMap[i] = Di
 
 		// Ci = g^{a λi} h^{- ri * H(ρ(i))} pkPV^{-r_i}
 
-		// term1 = (g^a)^{λ_i} = g^{a λ_i}
-		term1 := new(bn256.G1).ScalarMult(pk.Ga, lambda)
+		term1 := gaLambdas[i]
 
 		// Take the attribute name corresponding to this line ρ(i)
 		if i < 0 || i >= len(cph.MSP.RowToAttrib) {
@@ -368,10 +370,10 @@ func (voabe *VOABE) EncCS(pk *pk, cph *Cph, pkPV *bn256.G1) *CPh {
 		riNeg := new(big.Int).Sub(voabe.P, ri)
 		riNeg.Mod(riNeg, voabe.P)
 
-		term2 := new(bn256.G1).ScalarMult(hx, riNeg)
+		term2 := voabe.Ops.G1Mul(hx, riNeg)
 
 		// term3 = pkPV^{- ri}
-		term3 := new(bn256.G1).ScalarMult(pkPV, riNeg)
+		term3 := voabe.Ops.G1Mul(pkPV, riNeg)
 
 		Ci := new(bn256.G1).Add(term1, term2)
 		Ci.Add(Ci, term3)
@@ -447,18 +449,18 @@ func (voabe *VOABE) GenProofForPV(pk *pk, skDOcs *SKcs, cph *CPh, IDDO string, S
 	z, _ := sampler.Sample()
 
 	//K'DO = KDO · (g^a)^t
-	gat := new(bn256.G1).ScalarMult(pk.Ga, t)
+	gat := voabe.Ops.G1Mul(pk.Ga, t)
 	KDoPrime := new(bn256.G1).Add(skDOcs.Ku, gat)
 
 	//L'DO = LDO · g^t
 	//gt := new(bn256.G1).ScalarMult(pk.G, t)
-	gt2 := new(bn256.G2).ScalarMult(pk.G2, t)
+	gt2 := voabe.Ops.G2Mul(pk.G2, t)
 	//LDoPrime := new(bn256.G1).Add(skDOcs.Lu, gt)
 	LDoPrime2 := new(bn256.G2).Add(skDOcs.Lu2, gt2)
 
 	//∏_{x∈S*_DO} K'_{DO,x} = ∏ (K_{DO,x} · h_x^t)
 	// Initialize the product as the group identity: g^0
-	prodK := new(bn256.G2).ScalarMult(pk.G2, big.NewInt(0))
+	prodK := voabe.Ops.G2Mul(pk.G2, big.NewInt(0))
 	first := true
 
 	for i := 0; i < len(SDoStar); i++ {
@@ -474,7 +476,7 @@ func (voabe *VOABE) GenProofForPV(pk *pk, skDOcs *SKcs, cph *CPh, IDDO string, S
 			return nil, fmt.Errorf("hx for attr %s not found in pk.Hx", attr)
 		}
 		// hx^t
-		hxtG2 := new(bn256.G2).ScalarMult(hxG2, t)
+		hxtG2 := voabe.Ops.G2Mul(hxG2, t)
 		// K'{DO,x} = K{DO,x} · hx^t
 		KDoPrimeX := new(bn256.G2).Add(Kux2, hxtG2)
 
@@ -487,7 +489,7 @@ func (voabe *VOABE) GenProofForPV(pk *pk, skDOcs *SKcs, cph *CPh, IDDO string, S
 	}
 
 	//R'DO = RDO^z
-	RDoPrime := new(bn256.G1).ScalarMult(skDOcs.Ru, z)
+	RDoPrime := voabe.Ops.G1Mul(skDOcs.Ru, z)
 	//RDoPrime2 := new(bn256.G2).ScalarMult(skDOcs.Ru2, z)
 
 	//Prepare 1/z
@@ -504,28 +506,28 @@ func (voabe *VOABE) GenProofForPV(pk *pk, skDOcs *SKcs, cph *CPh, IDDO string, S
 	HIDOverZ.Mod(HIDOverZ, voabe.P)
 
 	//A1 = (L'DO)^{H(cph)} w^y
-	LDoPrimeExp := new(bn256.G2).ScalarMult(LDoPrime2, Hcph)
-	wy := new(bn256.G2).ScalarMult(pk.WG2, y)
+	LDoPrimeExp := voabe.Ops.G2Mul(LDoPrime2, Hcph)
+	wy := voabe.Ops.G2Mul(pk.WG2, y)
 	A1 := new(bn256.G2).Add(LDoPrimeExp, wy)
 
 	//A2 = g^y
-	A2 := new(bn256.G2).ScalarMult(pk.G2, y)
+	A2 := voabe.Ops.G2Mul(pk.G2, y)
 
 	//A3 = w^{1/z}
-	A3 := new(bn256.G2).ScalarMult(pk.WG2, invZ)
+	A3 := voabe.Ops.G2Mul(pk.WG2, invZ)
 
 	//A4 = g^{b/z} g^{H(ID_DO)/z}
-	gbOverZ := new(bn256.G2).ScalarMult(pk.G2b, invZ) // (g^b)^{1/z} = g^{b/z}
-	gHIDOverZ := new(bn256.G2).ScalarMult(pk.G2, HIDOverZ)
+	gbOverZ := voabe.Ops.G2Mul(pk.G2b, invZ) // (g^b)^{1/z} = g^{b/z}
+	gHIDOverZ := voabe.Ops.G2Mul(pk.G2, HIDOverZ)
 	A4 := new(bn256.G2).Add(gbOverZ, gHIDOverZ)
 
 	//A5 = e(g,g)^{H(ID_DO)/z}
-	egg := bn256.Pair(pk.G, pk.G2) // e(g,g)
-	A5 := new(bn256.GT).ScalarMult(egg, HIDOverZ)
+	egg := voabe.Ops.Pair(pk.G, pk.G2) // e(g,g)
+	A5 := voabe.Ops.GTExp(egg, HIDOverZ)
 
 	//A6 = g^{1/z}
 	//A6 := new(bn256.G1).ScalarMult(pk.G, invZ)
-	A6_2 := new(bn256.G2).ScalarMult(pk.G2, invZ)
+	A6_2 := voabe.Ops.G2Mul(pk.G2, invZ)
 
 	proof := &Proof{
 		KDoPrime: KDoPrime,
@@ -548,20 +550,24 @@ func (voabe *VOABE) GenProofForPV(pk *pk, skDOcs *SKcs, cph *CPh, IDDO string, S
 }
 
 func (voabe *VOABE) VerifyProofSymmetric(pk *pk, cph *CPh, proof *Proof, IDDO string) bool {
+	if voabe.IsBlacklisted(IDDO) {
+		fmt.Printf("%s is blacklisted!\n", IDDO)
+		return false
+	}
 	Hcph := HashCphToScalar(cph, voabe.P)
 	HID := HashToBigInt(IDDO)
 	HID.Mod(HID, voabe.P)
 
 	//six pairing check
 	//1. e(K'_DO, g) == e(g,g)^α1 · e(L'DO, g^a) · e(R'DO, A3)
-	left1 := bn256.Pair(proof.KDoPrime, pk.G2)
+	left1 := voabe.Ops.Pair(proof.KDoPrime, pk.G2)
 
 	right1 := new(bn256.GT).Set(pk.Base1) // e(g,g)^α1
 	//multiply e(L'DO, g^a)
-	termL := bn256.Pair(pk.Ga, proof.LDoPrime2)
+	termL := voabe.Ops.Pair(pk.Ga, proof.LDoPrime2)
 	right1.Add(right1, termL)
 	//multiply e(R'DO, A3)
-	termR := bn256.Pair(proof.RDoPrime, proof.A3)
+	termR := voabe.Ops.Pair(proof.RDoPrime, proof.A3)
 	right1.Add(right1, termR)
 
 	if left1.String() != right1.String() {
@@ -570,17 +576,17 @@ func (voabe *VOABE) VerifyProofSymmetric(pk *pk, cph *CPh, proof *Proof, IDDO st
 	}
 
 	//2. e(A3, g) == e(w, A6)
-	left2 := bn256.Pair(pk.G, proof.A3)
-	right2 := bn256.Pair(pk.W, proof.A6_2)
+	left2 := voabe.Ops.Pair(pk.G, proof.A3)
+	right2 := voabe.Ops.Pair(pk.W, proof.A6_2)
 	if left2.String() != right2.String() {
 		fmt.Printf("Eq2 is false!\n")
 		return false
 	}
 
 	//3. e(A4, g) == e(g^b, A6) · A5
-	left3 := bn256.Pair(pk.G, proof.A4)
+	left3 := voabe.Ops.Pair(pk.G, proof.A4)
 
-	right3 := bn256.Pair(pk.Gb, proof.A6_2)
+	right3 := voabe.Ops.Pair(pk.Gb, proof.A6_2)
 	right3.Add(right3, proof.A5) //Add A5
 	if left3.String() != right3.String() {
 		fmt.Printf("Eq3 is false!\n")
@@ -588,8 +594,8 @@ func (voabe *VOABE) VerifyProofSymmetric(pk *pk, cph *CPh, proof *Proof, IDDO st
 	}
 
 	//4. e(R'DO, A4) == e(g,g)
-	egg := bn256.Pair(pk.G, pk.G2) // e(g,g)
-	left4 := bn256.Pair(proof.RDoPrime, proof.A4)
+	egg := voabe.Ops.Pair(pk.G, pk.G2) // e(g,g)
+	left4 := voabe.Ops.Pair(proof.RDoPrime, proof.A4)
 	if left4.String() != egg.String() {
 		fmt.Printf("Eq4 is false!\n")
 		return false
@@ -597,7 +603,7 @@ func (voabe *VOABE) VerifyProofSymmetric(pk *pk, cph *CPh, proof *Proof, IDDO st
 
 	//5. e(∏K'{DO,x}, g) == e(∏hx, L'DO)
 	// Compute ∏ hx
-	prodHx := new(bn256.G1).ScalarMult(pk.G, big.NewInt(0))
+	prodHx := voabe.Ops.G1Mul(pk.G, big.NewInt(0))
 	first := true
 	for i := 0; i < len(proof.SDoStar); i++ {
 		attr := proof.SDoStar[i]
@@ -614,22 +620,22 @@ func (voabe *VOABE) VerifyProofSymmetric(pk *pk, cph *CPh, proof *Proof, IDDO st
 		}
 	}
 
-	left5 := bn256.Pair(pk.G, proof.ProdKDoPrime)
-	right5 := bn256.Pair(prodHx, proof.LDoPrime2)
+	left5 := voabe.Ops.Pair(pk.G, proof.ProdKDoPrime)
+	right5 := voabe.Ops.Pair(prodHx, proof.LDoPrime2)
 	if left5.String() != right5.String() {
 		fmt.Printf("Eq5 is false!\n")
 		return false
 	}
 
 	// 6. e(A1, g) == e(L'DO, g)^{H(cph)} · e(A2, w)
-	left6 := bn256.Pair(pk.G, proof.A1)
+	left6 := voabe.Ops.Pair(pk.G, proof.A1)
 
 	// e(L'DO, g)^{H(cph)}
-	baseLg := bn256.Pair(pk.G, proof.LDoPrime2)
-	termLg := new(bn256.GT).ScalarMult(baseLg, Hcph)
+	baseLg := voabe.Ops.Pair(pk.G, proof.LDoPrime2)
+	termLg := voabe.Ops.GTExp(baseLg, Hcph)
 
 	// e(A2, w)
-	termAw := bn256.Pair(pk.W, proof.A2)
+	termAw := voabe.Ops.Pair(pk.W, proof.A2)
 
 	right6 := new(bn256.GT).Add(termLg, termAw)
 
@@ -642,8 +648,9 @@ func (voabe *VOABE) VerifyProofSymmetric(pk *pk, cph *CPh, proof *Proof, IDDO st
 }
 
 // Sanitize: PV sanitize the final ciphertext with its own secret key skPV = c
-// Output: updated cph (with C0 and re-randomize all components)
-func (voabe *VOABE) Sanitize(pk *pk, skPV *big.Int, cph *CPh) *CPh {
+// Output: a new sanitized cph (with C0 and re-randomize all components) and a proof
+// for VerifySanitize; the input cph is not modified
+func (voabe *VOABE) Sanitize(pk *pk, skPV *big.Int, cph *CPh) (*CPh, *SanitizeProof) {
 
 	sampler := sample.NewUniformRange(big.NewInt(1), voabe.P)
 	r, err := sampler.Sample()
@@ -659,26 +666,26 @@ func (voabe *VOABE) Sanitize(pk *pk, skPV *big.Int, cph *CPh) *CPh {
 	cNeg.Mod(cNeg, voabe.P)
 
 	// Compute C0 = g^{a r} = (g^a)^r
-	C0 := new(bn256.G1).ScalarMult(pk.Ga, r)
+	C0 := voabe.Ops.G1Mul(pk.Ga, r)
 
 	//Update Cnew = C · e(g, C')^{-c} · e(g,g)^{α r}
-	pairGCPrime := bn256.Pair(pk.G, cph.CPrime2)                //e(g, C')
-	termPairNegC := new(bn256.GT).ScalarMult(pairGCPrime, cNeg) // e(g, C')^{-c}
-	termAlphaR := new(bn256.GT).ScalarMult(pk.Base, r)          // e(g,g)^{α r}
+	pairGCPrime := voabe.Ops.Pair(pk.G, cph.CPrime2)   //e(g, C')
+	termPairNegC := voabe.Ops.GTExp(pairGCPrime, cNeg) // e(g, C')^{-c}
+	termAlphaR := voabe.Ops.GTExp(pk.Base, r)          // e(g,g)^{α r}
 
 	Cnew := new(bn256.GT).Add(cph.C, termPairNegC)
 	Cnew.Add(Cnew, termAlphaR)
 
 	//Compute (C')^{-c} for  C'' （before update C'）
-	termCPrimeNegC := new(bn256.G2).ScalarMult(cph.CPrime2, cNeg) // (C')^{-c}
+	termCPrimeNegC := voabe.Ops.G2Mul(cph.CPrime2, cNeg) // (C')^{-c}
 
 	//C'new = C' * g^r = g^s * g^r = g^{s+r}
-	gr := new(bn256.G1).ScalarMult(pk.G, r)
-	grG2 := new(bn256.G2).ScalarMult(pk.G2, r)
+	gr := voabe.Ops.G1Mul(pk.G, r)
+	grG2 := voabe.Ops.G2Mul(pk.G2, r)
 	CPrimeNew := new(bn256.G2).Add(cph.CPrime2, grG2)
 
 	//Update C'' = C'' · (C')^{-c} · w^{r}
-	wr := new(bn256.G2).ScalarMult(pk.WG2, r)
+	wr := voabe.Ops.G2Mul(pk.WG2, r)
 	CSecondNew := new(bn256.G2).Add(cph.CSecond2, termCPrimeNegC)
 	CSecondNew.Add(CSecondNew, wr)
 
@@ -693,7 +700,7 @@ func (voabe *VOABE) Sanitize(pk *pk, skPV *big.Int, cph *CPh) *CPh {
 		}
 
 		// Di^c
-		termDic := new(bn256.G1).ScalarMult(Di, skPV)
+		termDic := voabe.Ops.G1Mul(Di, skPV)
 
 		// Find the attribute name ρ(i) corresponding to this row, and then take h{ρ(i)}
 		if i < 0 || i >= len(cph.MSP.RowToAttrib) {
@@ -706,7 +713,7 @@ func (voabe *VOABE) Sanitize(pk *pk, skPV *big.Int, cph *CPh) *CPh {
 		}
 
 		// h{ρ(i)}^{-r}
-		termHxr := new(bn256.G1).ScalarMult(hx, rNeg)
+		termHxr := voabe.Ops.G1Mul(hx, rNeg)
 
 		// Ci = Ci · Di^c · h{ρ(i)}^{-r}
 		CiNew := new(bn256.G1).Add(Ci, termDic)
@@ -719,33 +726,35 @@ func (voabe *VOABE) Sanitize(pk *pk, skPV *big.Int, cph *CPh) *CPh {
 		DiNewMap[i] = DiNew
 	}
 
-	//Write back to the original cph structure
-	cph.C0 = C0
-	cph.C = Cnew
-	cph.CPrime2 = CPrimeNew
-	cph.CSecond2 = CSecondNew
-	cph.Ci = CiNewMap
-	cph.Di = DiNewMap
+	//Build a new cph, the input is left unchanged
+	inner := *cph.Cph
+	inner.C = Cnew
+	inner.CPrime2 = CPrimeNew
+	inner.CSecond2 = CSecondNew
+	out := &CPh{Cph: &inner, C0: C0, Ci: CiNewMap, Di: DiNewMap}
 
-	return cph
+	return out, voabe.proveSanitize(pk, skPV, r, cph, out)
 }
 
 // DecCS:CS uses skCS to outsource decryption of the sanitize ciphertext cph
 func (voabe *VOABE) DecCS(pk *pk, cph *CPh, skCS *SKcs, SDU []string) (*bn256.GT, error) {
+	if err := voabe.checkIdentity(skCS.Ru); err != nil {
+		return nil, fmt.Errorf("DecCS: %w", err)
+	}
 
-	wMap, err := LSSS.ReconstructCoefficients(cph.MSP, SDU, voabe.P)
+	plan, err := voabe.Plans.Plan(nil, cph.MSP, SDU, voabe.P)
 	if err != nil {
 		return nil, fmt.Errorf("DecCS: reconstruct coefficients failed: %v", err)
 	}
-	if len(wMap) == 0 {
+	if len(plan.Rows) == 0 {
 		return nil, fmt.Errorf("DecCS: SDU does not satisfy Γ (empty wMap)")
 	}
 
 	//Compute term1 = e(C', KDU) = Pair(CPrime, Ku2)
-	term1 := bn256.Pair(skCS.Ku, cph.CPrime2)
+	term1 := voabe.Ops.Pair(skCS.Ku, cph.CPrime2)
 
 	//Compute term2 = e(RDU, C'')-> Pair(C'', Ru2)
-	term2 := bn256.Pair(skCS.Ru, cph.CSecond2)
+	term2 := voabe.Ops.Pair(skCS.Ru, cph.CSecond2)
 	//term2^{-1} = term2^{p-1}
 	//minusOne := new(big.Int).Sub(voabe.P, big.NewInt(1))
 	//term2Inv := new(bn256.GT).ScalarMult(term2, minusOne)
@@ -756,14 +765,11 @@ func (voabe *VOABE) DecCS(pk *pk, cph *CPh, skCS *SKcs, SDU []string) (*bn256.GT
 
 	//Calculate the product part:
 	//prod = ∏{i∈I} [ e(Ci,LDU) e(Di,KDU,ρ(i)) ]^{wi} * e(LDU, C0)
-	//First, initialize prod to GT unit element (use e(g,g)^0)
-	egg := bn256.Pair(pk.G, pk.G2)
-	prod := new(bn256.GT).ScalarMult(egg, big.NewInt(0))
-	first := true
-	for i, wi := range wMap {
+	pairs := make(map[int]*bn256.GT, len(plan.Rows))
+	for _, i := range plan.Rows {
 
 		// e(Ci, LDU) → Pair(Ci, Lu2)
-		eCiL := bn256.Pair(cph.Ci[i], skCS.Lu2)
+		eCiL := voabe.Ops.Pair(cph.Ci[i], skCS.Lu2)
 
 		if i < 0 || i >= len(cph.MSP.RowToAttrib) {
 			return nil, fmt.Errorf("DecCS: MSP.RowToAttrib index %d out of range", i)
@@ -775,27 +781,21 @@ func (voabe *VOABE) DecCS(pk *pk, cph *CPh, skCS *SKcs, SDU []string) (*bn256.GT
 			return nil, fmt.Errorf("DecCS: no Kux2 for attribute %s", attr)
 		}
 
-		eDiK := bn256.Pair(cph.Di[i], kux2)
+		eDiK := voabe.Ops.Pair(cph.Di[i], kux2)
 		// Add：e(Ci,LDU) * e(Di,KDU,ρ(i))
-		tmpPair := new(bn256.GT).Add(eCiL, eDiK)
-		// (...) ^wi
-		tmpPairW := new(bn256.GT).ScalarMult(tmpPair, wi)
-
-		if first {
-			prod.Set(tmpPairW)
-			first = false
-		} else {
-			prod.Add(prod, tmpPairW)
-		}
+		pairs[i] = new(bn256.GT).Add(eCiL, eDiK)
 	}
+	// (...) ^wi
+	prod := LSSS.Combine(pairs, plan.Coeffs)
+	voabe.Ops.Add(opcount.GTExp, len(plan.Coeffs))
 
 	//e(LDU, C0) → Pair(C0, Lu2)
-	eLC0 := bn256.Pair(cph.C0, skCS.Lu2)
+	eLC0 := voabe.Ops.Pair(cph.C0, skCS.Lu2)
 	prod.Add(prod, eLC0)
 
 	//(...)^-2
 	two := big.NewInt(2)
-	prodSquared := new(bn256.GT).ScalarMult(prod, two)
+	prodSquared := voabe.Ops.GTExp(prod, two)
 
 	prodSquaredInv := new(bn256.GT).Neg(prodSquared)
 
@@ -811,7 +811,7 @@ func (voabe *VOABE) DecDU(phiDU *bn256.GT, cph *CPh, skDU *Sku) (*bn256.GT, erro
 	}
 
 	//Compute e(skDU, C')->Pair(CPrime, Sku2)
-	eSkCPrime := bn256.Pair(skDU.Sku, cph.CPrime2)
+	eSkCPrime := voabe.Ops.Pair(skDU.Sku, cph.CPrime2)
 
 	//Calculate the denominator denom = φDU · e(skDU, C')
 	denom := new(bn256.GT).Add(phiDU, eSkCPrime)
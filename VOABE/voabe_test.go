@@ -1,6 +1,7 @@
 package VOABE
 
 import (
+	"encoding/json"
 	"fmt"
 	"math/big"
 	"strconv"
@@ -124,7 +125,7 @@ func TestVOABE_FullFlowWithPolicy(t *testing.T) {
 	var cphSan *CPh
 	starttime = time.Now().UnixMilli()
 	for i := 0; i < int(n); i++ {
-		cphSan = voabe.Sanitize(pk, skPV, cph)
+		cphSan, _ = voabe.Sanitize(pk, skPV, cph)
 	}
 	endtime = time.Now().UnixMilli()
 	fmt.Printf("Sanitize algorithm is %.4f ms\n", float64(endtime-starttime)/float64(n))
@@ -154,3 +155,152 @@ func TestVOABE_FullFlowWithPolicy(t *testing.T) {
 	//require.Equal(t, KR, decKR, "decrypted record should equal original R")
 	//t.Logf("Decrypted Message: %s", decR)
 }
+
+func TestTraceAndBlacklist(t *testing.T) {
+	voabe := NewVOABE()
+	pk, msk := voabe.SetUp([]string{"Doctor", "Nurse", "Cardiology"})
+	pkPV, pkPVG2, skPV := voabe.KeyGenPV(pk, msk)
+	IDDO, IDDU := "DO-001", "DU-001"
+	skDOcs, _ := voabe.KeyGenU(pk, msk, IDDO, []string{"Doctor", "Cardiology"})
+	skDUcs, skDU := voabe.KeyGenU(pk, msk, IDDU, []string{"Doctor", "Cardiology"})
+	skOthercs, _ := voabe.KeyGenU(pk, msk, "DU-002", []string{"Nurse"})
+
+	cphDo, KR, policySet, err := voabe.EncDoPolicy(pk, pkPV, pkPVG2, "Doctor AND Cardiology")
+	require.NoError(t, err)
+	cph := voabe.EncCS(pk, cphDo, pkPV)
+	proof, err := voabe.GenProofForPV(pk, skDOcs, cph, IDDO, policySet)
+	require.NoError(t, err)
+	require.True(t, voabe.VerifyProofSymmetric(pk, cph, proof, IDDO))
+	cph, _ = voabe.Sanitize(pk, skPV, cph)
+	phi, err := voabe.DecCS(pk, cph, skDUcs, []string{"Doctor", "Cardiology"})
+	require.NoError(t, err)
+	got, err := voabe.DecDU(phi, cph, skDU)
+	require.NoError(t, err)
+	require.Equal(t, KR.String(), got.String())
+
+	// 泄露的 SKcs 追踪到其身份
+	id, err := voabe.Trace(pk, msk, skDUcs)
+	require.NoError(t, err)
+	require.Equal(t, IDDU, id)
+	id, err = voabe.Trace(pk, msk, skOthercs)
+	require.NoError(t, err)
+	require.Equal(t, "DU-002", id)
+
+	// 拼接他人的 Ru 或属性密钥的密钥不是结构良好的
+	_, err = voabe.Trace(pk, msk, &SKcs{Ku: skDUcs.Ku, Lu2: skDUcs.Lu2, Ru: skOthercs.Ru, Kux2: skDUcs.Kux2})
+	require.ErrorIs(t, err, ErrMalformedKey)
+	_, err = voabe.Trace(pk, msk, &SKcs{Ku: skDUcs.Ku, Lu2: skDUcs.Lu2, Ru: skDUcs.Ru, Kux2: skOthercs.Kux2})
+	require.ErrorIs(t, err, ErrMalformedKey)
+	_, err = voabe.Trace(pk, msk, &SKcs{Ku: skDUcs.Ku, Lu2: skDUcs.Lu2, Ru: skDUcs.Ru, Kux2: skDUcs.Kux2})
+	require.NoError(t, err)
+	other := NewVOABE()
+	_, err = other.Trace(pk, msk, skDUcs)
+	require.ErrorIs(t, err, ErrUnknownIdentity)
+
+	// 独立的 CS 实例：身份表为空时拒绝无法归属的密钥，共享或从快照恢复身份表后可以解密
+	SDU := []string{"Doctor", "Cardiology"}
+	cloud := NewVOABE()
+	_, err = cloud.DecCS(pk, cph, skDUcs, SDU)
+	require.ErrorIs(t, err, ErrUnknownIdentity)
+	cloud.Identities = voabe.Identities
+	_, err = cloud.DecCS(pk, cph, skDUcs, SDU)
+	require.NoError(t, err)
+	cloud.Identities = nil
+	_, err = cloud.DecCS(pk, cph, skDUcs, SDU)
+	require.NoError(t, err, "identity checks are disabled without a table")
+
+	// 列入黑名单后 CS 拒绝为其解密，PV 拒绝其证明
+	voabe.Blacklist(IDDU, IDDO)
+	require.True(t, voabe.IsBlacklisted(IDDU))
+	_, err = voabe.DecCS(pk, cph, skDUcs, SDU)
+	require.ErrorIs(t, err, ErrBlacklisted)
+	require.False(t, voabe.VerifyProofSymmetric(pk, cph, proof, IDDO))
+
+	// 身份表与黑名单可以持久化
+	table, ok := voabe.Identities.(*MemoryTable)
+	require.True(t, ok)
+	data, err := json.Marshal(table.Snapshot())
+	require.NoError(t, err)
+	var snap TableSnapshot
+	require.NoError(t, json.Unmarshal(data, &snap))
+	require.Len(t, snap.IDs, 3)
+	restored, err := LoadMemoryTable(snap)
+	require.NoError(t, err)
+	cloud.Identities = restored
+	_, err = cloud.DecCS(pk, cph, skDUcs, SDU)
+	require.ErrorIs(t, err, ErrBlacklisted)
+	_, err = cloud.DecCS(pk, cph, skOthercs, []string{"Nurse"})
+	require.NotErrorIs(t, err, ErrUnknownIdentity)
+	id, err = cloud.Trace(pk, msk, skOthercs)
+	require.NoError(t, err)
+	require.Equal(t, "DU-002", id)
+
+	snap.IDs["00"] = "broken"
+	_, err = LoadMemoryTable(snap)
+	require.Error(t, err)
+}
+
+func TestSanitizeProof(t *testing.T) {
+	voabe := NewVOABE()
+	pk, msk := voabe.SetUp([]string{"Doctor", "Nurse", "Cardiology"})
+	pkPV, pkPVG2, skPV := voabe.KeyGenPV(pk, msk)
+	otherPV, _, _ := voabe.KeyGenPV(pk, msk)
+	IDDO := "DO-001"
+	skDOcs, _ := voabe.KeyGenU(pk, msk, IDDO, []string{"Doctor", "Cardiology", "Nurse"})
+	skDUcs, skDU := voabe.KeyGenU(pk, msk, "DU-001", []string{"Doctor", "Cardiology"})
+
+	cphDo, KR, policySet, err := voabe.EncDoPolicy(pk, pkPV, pkPVG2, "Doctor AND (Cardiology OR Nurse)")
+	require.NoError(t, err)
+	cph := voabe.EncCS(pk, cphDo, pkPV)
+	proof, err := voabe.GenProofForPV(pk, skDOcs, cph, IDDO, policySet)
+	require.NoError(t, err)
+	require.True(t, voabe.VerifyProofSymmetric(pk, cph, proof, IDDO))
+
+	C, Ci0 := cph.C.String(), cph.Ci[0].String()
+	san, sp := voabe.Sanitize(pk, skPV, cph)
+	require.NotNil(t, sp)
+	require.Nil(t, cph.C0, "Sanitize must not modify its input")
+	require.Equal(t, C, cph.C.String())
+	require.Equal(t, Ci0, cph.Ci[0].String())
+	require.True(t, voabe.VerifySanitize(pk, pkPV, cph, san, sp))
+
+	phi, err := voabe.DecCS(pk, san, skDUcs, []string{"Doctor", "Cardiology"})
+	require.NoError(t, err)
+	got, err := voabe.DecDU(phi, san, skDU)
+	require.NoError(t, err)
+	require.Equal(t, KR.String(), got.String())
+
+	// 另一个 PV 的公钥、重复 Sanitize 或把证明挪到另一次 Sanitize 上都无法通过
+	require.False(t, voabe.VerifySanitize(pk, otherPV, cph, san, sp))
+	require.False(t, voabe.VerifySanitize(pk, pkPV, san, san, sp))
+	san2, sp2 := voabe.Sanitize(pk, skPV, cph)
+	require.True(t, voabe.VerifySanitize(pk, pkPV, cph, san2, sp2))
+	require.False(t, voabe.VerifySanitize(pk, pkPV, cph, san2, sp))
+
+	// 篡改任一分量（包括只改一行的 Di，即使用不一致的 r）都被拒绝
+	g := new(bn256.G1).ScalarBaseMult(big.NewInt(1))
+	g2 := new(bn256.G2).ScalarBaseMult(big.NewInt(1))
+	tamper := func(f func(out *CPh)) *CPh {
+		inner := *san.Cph
+		out := &CPh{Cph: &inner, C0: san.C0, Ci: map[int]*bn256.G1{}, Di: map[int]*bn256.G1{}}
+		for i := range san.Ci {
+			out.Ci[i], out.Di[i] = san.Ci[i], san.Di[i]
+		}
+		f(out)
+		return out
+	}
+	for name, f := range map[string]func(out *CPh){
+		"C":   func(out *CPh) { out.C = new(bn256.GT).Add(out.C, bn256.Pair(g, g2)) },
+		"C'":  func(out *CPh) { out.CPrime2 = new(bn256.G2).Add(out.CPrime2, g2) },
+		"C''": func(out *CPh) { out.CSecond2 = new(bn256.G2).Add(out.CSecond2, g2) },
+		"C0":  func(out *CPh) { out.C0 = new(bn256.G1).Add(out.C0, g) },
+		"Ci":  func(out *CPh) { out.Ci[1] = new(bn256.G1).Add(out.Ci[1], g) },
+		"Di":  func(out *CPh) { out.Di[1] = new(bn256.G1).Add(out.Di[1], g) },
+		"row": func(out *CPh) { delete(out.Ci, 1); delete(out.Di, 1) },
+	} {
+		require.False(t, voabe.VerifySanitize(pk, pkPV, cph, tamper(f), sp), name)
+	}
+	bad := *sp
+	bad.Zr = new(big.Int).Add(sp.Zr, big.NewInt(1))
+	require.False(t, voabe.VerifySanitize(pk, pkPV, cph, san, &bad))
+}
@@ -0,0 +1,524 @@
+package bench
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AUKUS561/PVOABE/oabe"
+	"github.com/AUKUS561/PVOABE/oabe/schemes"
+	"github.com/AUKUS561/PVOABE/opcount"
+	"github.com/AUKUS561/PVOABE/policy"
+	"github.com/AUKUS561/PVOABE/policygen"
+	"github.com/AUKUS561/PVOABE/size"
+)
+
+/*
+bench 对 oabe.OutsourcedABE 的每个算法做基准测试，按方案 × 属性数 × 策略形状扫描，
+结果可输出为 CSV 或 JSON，用于重新生成报告中的对比表格。
+
+策略与用户属性由 policygen 按 Seed 生成，相同的 Config 得到相同的输入。
+
+使用方式:
+
+	results, err := bench.Run(bench.Config{Seed: 1, Attrs: []int{5, 10, 20}})
+	bench.WriteCSV(os.Stdout, results)
+*/
+
+// 各算法名，与 oabe.OutsourcedABE 的方法名一致
+const (
+	OpSetup            = "Setup"
+	OpKeyGen           = "KeyGen"
+	OpEncrypt          = "Encrypt"
+	OpProxyEncrypt     = "ProxyEncrypt"
+	OpVerifyEncryption = "VerifyEncryption"
+	OpProxyDecrypt     = "ProxyDecrypt"
+	OpVerifyDecryption = "VerifyDecryption"
+	OpDecrypt          = "Decrypt"
+)
+
+// Ops 是全部算法，按执行顺序排列
+var Ops = []string{OpSetup, OpKeyGen, OpEncrypt, OpProxyEncrypt, OpVerifyEncryption, OpProxyDecrypt, OpVerifyDecryption, OpDecrypt}
+
+// Shapes 是可选的策略形状，值为 policygen.Config（Seed 由 Config.Seed 覆盖）
+var Shapes = map[string]policygen.Config{
+	"andor":     {FanOut: 2, Satisfiable: 1},                     // 随机二叉 AND/OR 树，与原 GeneratePolicy 相同
+	"wide":      {Depth: 2, FanOut: 5, Satisfiable: 1},           // 浅而宽
+	"threshold": {FanOut: 4, ThresholdProb: 0.5, Satisfiable: 1}, // 含 k-of-n 门限门
+	"reuse":     {FanOut: 3, ReuseProb: 0.5, Satisfiable: 1},     // 属性在多行重复出现
+}
+
+// Config 描述一次扫描
+type Config struct {
+	Seed    int64
+	Schemes []string // 为空时使用 schemes.Names()
+	Attrs   []int    // 策略中的属性数
+	Shapes  []string // 为空时只用 "andor"
+	Ops     []string // 为空时使用 Ops
+
+	// BenchTime 与 go test 的 -benchtime 格式相同，如 "500ms"、"20x"；为空时使用默认的 1s
+	BenchTime string
+
+	// Costs 非空时按运算次数估算每个算法的耗时，见 opcount.Calibrate
+	Costs *opcount.Costs
+}
+
+// Result 是一个 (方案, 属性数, 形状, 算法) 组合的测量结果
+type Result struct {
+	Scheme               string  `json:"scheme"`
+	Shape                string  `json:"shape"`
+	Attrs                int     `json:"attrs"`
+	Rows                 int     `json:"rows"` // 策略 MSP 的行数
+	Op                   string  `json:"op"`
+	N                    int     `json:"n"`
+	NsPerOp              int64   `json:"ns_per_op"`
+	MsPerOp              float64 `json:"ms_per_op"`
+	AllocsPerOp          int64   `json:"allocs_per_op"`
+	BytesPerOp           int64   `json:"bytes_per_op"`
+	CiphertextBytes      int     `json:"ciphertext_bytes"`      // 最终密文的字节数（未压缩编码）
+	CiphertextCompressed int     `json:"ciphertext_compressed"` // 最终密文的字节数（压缩编码）
+
+	opcount.Counts       // 单次执行的配对、标量乘、GT 幂与哈希次数
+	EstimatedNs    int64 `json:"estimated_ns,omitempty"` // 由运算次数与 Config.Costs 估算的耗时
+}
+
+// Case 是一次测量的输入，Run 与 go test -bench 共用
+type Case struct {
+	Scheme   oabe.OutsourcedABE
+	Shape    string
+	Attrs    int
+	Policy   string
+	Rows     int
+	Universe []string
+	UserSet  []string // 满足策略的极小属性集合
+}
+
+// NewCase 按 seed 生成策略与用户属性集合
+func NewCase(scheme string, shape string, attrs int, seed int64) (*Case, error) {
+	s, err := schemes.New(scheme)
+	if err != nil {
+		return nil, err
+	}
+	cfg, ok := Shapes[shape]
+	if !ok {
+		return nil, fmt.Errorf("bench: unknown policy shape %q", shape)
+	}
+	cfg.Seed = seed
+	g := policygen.New(cfg)
+	w, err := g.Workload(attrs, 1)
+	if err != nil {
+		return nil, err
+	}
+	msp, _, err := policy.Canonicalize(w.Policy)
+	if err != nil {
+		return nil, err
+	}
+	return &Case{
+		Scheme:   s,
+		Shape:    shape,
+		Attrs:    attrs,
+		Policy:   w.Policy,
+		Rows:     len(msp.Mat),
+		Universe: w.Attributes,
+		UserSet:  w.Users[0],
+	}, nil
+}
+
+// state 是执行到某一步之前需要的全部中间结果
+type state struct {
+	auth        *oabe.Authority
+	owner, user *oabe.UserKey
+	ct          *oabe.Ciphertext
+	tr          *oabe.Transformed
+}
+
+// prepare 依次执行 op 之前的所有步骤
+func (c *Case) prepare(op string) (*state, error) {
+	s := c.Scheme
+	st := &state{}
+	var err error
+	if op == OpSetup {
+		return st, nil
+	}
+	if st.auth, err = s.Setup(c.Universe); err != nil {
+		return nil, err
+	}
+	if op == OpKeyGen {
+		return st, nil
+	}
+	if st.owner, err = s.KeyGen(st.auth, "DO-001", c.Universe); err != nil {
+		return nil, err
+	}
+	if st.user, err = s.KeyGen(st.auth, "DU-001", c.UserSet); err != nil {
+		return nil, err
+	}
+	if op == OpEncrypt {
+		return st, nil
+	}
+	if err := c.encrypt(st); err != nil {
+		return nil, err
+	}
+	if op == OpProxyEncrypt {
+		return st, nil
+	}
+	if err := s.ProxyEncrypt(st.auth, st.owner, st.ct); err != nil {
+		return nil, err
+	}
+	if op == OpVerifyEncryption {
+		return st, nil
+	}
+	if err := s.VerifyEncryption(st.auth, st.owner, st.ct); err != nil && !errors.Is(err, oabe.ErrNotSupported) {
+		return nil, err
+	}
+	if op == OpProxyDecrypt {
+		return st, nil
+	}
+	if st.tr, err = s.ProxyDecrypt(st.auth, st.user, st.ct); err != nil {
+		return nil, err
+	}
+	return st, nil
+}
+
+func (c *Case) encrypt(st *state) error {
+	ct, _, err := c.Scheme.Encrypt(st.auth, st.owner, c.Policy)
+	st.ct = ct
+	return err
+}
+
+// step 执行一次算法 op
+func (c *Case) step(op string, st *state) error {
+	s := c.Scheme
+	var err error
+	switch op {
+	case OpSetup:
+		_, err = s.Setup(c.Universe)
+	case OpKeyGen:
+		_, err = s.KeyGen(st.auth, "DU-001", c.UserSet)
+	case OpEncrypt:
+		_, _, err = s.Encrypt(st.auth, st.owner, c.Policy)
+	case OpProxyEncrypt:
+		err = s.ProxyEncrypt(st.auth, st.owner, st.ct)
+	case OpVerifyEncryption:
+		err = s.VerifyEncryption(st.auth, st.owner, st.ct)
+	case OpProxyDecrypt:
+		_, err = s.ProxyDecrypt(st.auth, st.user, st.ct)
+	case OpVerifyDecryption:
+		err = s.VerifyDecryption(st.auth, st.user, st.ct, st.tr)
+	case OpDecrypt:
+		_, err = s.Decrypt(st.auth, st.user, st.ct, st.tr)
+	default:
+		err = fmt.Errorf("bench: unknown op %q", op)
+	}
+	return err
+}
+
+// mutates 表示 op 会就地修改密文，每次迭代都要重新准备
+func mutates(op string) bool {
+	return op == OpProxyEncrypt || op == OpVerifyEncryption
+}
+
+// Supported 判断方案是否实现了 op
+func (c *Case) Supported(op string) bool {
+	sup := c.Scheme.Supports()
+	switch op {
+	case OpVerifyEncryption:
+		return sup.VerifyEncryption
+	case OpVerifyDecryption:
+		return sup.VerifyDecryption
+	}
+	return true
+}
+
+// Measurement 是一次计时的结果
+type Measurement struct {
+	N      int           // 执行次数
+	Total  time.Duration // N 次执行的总耗时，不含准备阶段
+	Allocs uint64        // N 次执行的总分配次数
+	Bytes  uint64        // N 次执行的总分配字节数
+}
+
+func (m Measurement) NsPerOp() int64 {
+	if m.N == 0 {
+		return 0
+	}
+	return m.Total.Nanoseconds() / int64(m.N)
+}
+
+func (m Measurement) AllocsPerOp() int64 {
+	if m.N == 0 {
+		return 0
+	}
+	return int64(m.Allocs) / int64(m.N)
+}
+
+func (m Measurement) BytesPerOp() int64 {
+	if m.N == 0 {
+		return 0
+	}
+	return int64(m.Bytes) / int64(m.N)
+}
+
+// benchTime 是解析后的 Config.BenchTime：n > 0 时执行 n 次，否则执行到累计耗时达到 d
+type benchTime struct {
+	n int
+	d time.Duration
+}
+
+// parseBenchTime 解析 "500ms"、"20x" 形式的 benchtime，为空时为 1s
+func parseBenchTime(s string) (benchTime, error) {
+	if s == "" {
+		return benchTime{d: time.Second}, nil
+	}
+	if strings.HasSuffix(s, "x") {
+		n, err := strconv.Atoi(strings.TrimSuffix(s, "x"))
+		if err != nil || n <= 0 {
+			return benchTime{}, fmt.Errorf("bench: invalid benchtime %q", s)
+		}
+		return benchTime{n: n}, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return benchTime{}, fmt.Errorf("bench: invalid benchtime %q", s)
+	}
+	return benchTime{d: d}, nil
+}
+
+// Bench 是 op 的 testing.B 基准函数，供 go test -bench 使用；不修改密文的算法共用一份准备好的状态。
+// 只构造基准函数，不调用 testing.Init，也不修改 testing 的命令行参数
+func (c *Case) Bench(op string) func(b *testing.B) {
+	return func(b *testing.B) {
+		b.ReportAllocs()
+		st, err := c.prepare(op)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if mutates(op) && i > 0 {
+				b.StopTimer()
+				if st, err = c.prepare(op); err != nil {
+					b.Fatal(err)
+				}
+				b.StartTimer()
+			}
+			if err := c.step(op, st); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+// Measure 用 time.Now 对 op 计时，执行次数或时长由 benchtime 决定（格式同 Config.BenchTime）。
+// 准备与计时方式与 Bench 相同，但不经过 testing.Benchmark，因此结果与 go test -bench 的数字分别测得。
+// 不修改密文的算法共用一份准备好的状态，修改密文的算法每次重新准备，准备阶段不计入耗时与分配
+func (c *Case) Measure(op string, benchtime string) (Measurement, error) {
+	bt, err := parseBenchTime(benchtime)
+	if err != nil {
+		return Measurement{}, err
+	}
+	st, err := c.prepare(op)
+	if err != nil {
+		return Measurement{}, err
+	}
+	var m Measurement
+	var before, after runtime.MemStats
+	for m.N == 0 || (bt.n > 0 && m.N < bt.n) || (bt.n == 0 && m.Total < bt.d) {
+		if mutates(op) && m.N > 0 {
+			if st, err = c.prepare(op); err != nil {
+				return Measurement{}, err
+			}
+		}
+		runtime.ReadMemStats(&before)
+		start := time.Now()
+		err := c.step(op, st)
+		m.Total += time.Since(start)
+		runtime.ReadMemStats(&after)
+		if err != nil {
+			return Measurement{}, err
+		}
+		m.Allocs += after.Mallocs - before.Mallocs
+		m.Bytes += after.TotalAlloc - before.TotalAlloc
+		m.N++
+	}
+	return m, nil
+}
+
+// Count 返回执行一次 op 的运算次数；方案不支持计数时返回零值。
+// 准备阶段的运算不计入，计数结束后关闭计数，不影响随后的计时
+func (c *Case) Count(op string) (opcount.Counts, error) {
+	counted, ok := c.Scheme.(oabe.Counted)
+	if !ok {
+		return opcount.Counts{}, nil
+	}
+	counter := opcount.New()
+	counted.SetCounter(counter)
+	defer counted.SetCounter(nil)
+
+	st, err := c.prepare(op)
+	if err != nil {
+		return opcount.Counts{}, err
+	}
+	counter.Reset()
+	if err := c.step(op, st); err != nil {
+		return opcount.Counts{}, err
+	}
+	return counter.Snapshot(), nil
+}
+
+// CiphertextSize 返回完整外包加密（以及方案支持时的验证）之后密文的元素个数，访问结构不计入
+func (c *Case) CiphertextSize() (size.Elements, error) {
+	v, err := c.finalCiphertext()
+	if err != nil {
+		return size.Elements{}, err
+	}
+	return size.Of(v), nil
+}
+
+// CiphertextReport 按组成部分列出最终密文的大小
+func (c *Case) CiphertextReport() (size.Report, error) {
+	v, err := c.finalCiphertext()
+	if err != nil {
+		return size.Report{}, err
+	}
+	name := fmt.Sprintf("%s/%s/attrs=%d ciphertext", c.Scheme.Name(), c.Shape, c.Attrs)
+	if s, ok := v.(size.Sizer); ok {
+		r := s.Size()
+		r.Name = name
+		return r, nil
+	}
+	return size.Breakdown(name, v), nil
+}
+
+// finalCiphertext 返回交给用户的密文。适配器的密文包装带有中间结果时，通过 Final() 取出最终密文
+func (c *Case) finalCiphertext() (any, error) {
+	st, err := c.prepare(OpProxyDecrypt)
+	if err != nil {
+		return nil, err
+	}
+	v := st.ct.Value
+	if f, ok := v.(interface{ Final() any }); ok {
+		v = f.Final()
+	}
+	return v, nil
+}
+
+// Run 按 cfg 扫描所有组合，每个算法用 Measure 计时
+func Run(cfg Config) ([]Result, error) {
+	names := cfg.Schemes
+	if len(names) == 0 {
+		names = schemes.Names()
+	}
+	shapes := cfg.Shapes
+	if len(shapes) == 0 {
+		shapes = []string{"andor"}
+	}
+	ops := cfg.Ops
+	if len(ops) == 0 {
+		ops = Ops
+	}
+
+	if _, err := parseBenchTime(cfg.BenchTime); err != nil {
+		return nil, err
+	}
+
+	var results []Result
+	for _, name := range names {
+		for _, shape := range shapes {
+			for _, attrs := range cfg.Attrs {
+				c, err := NewCase(name, shape, attrs, cfg.Seed)
+				if err != nil {
+					return nil, err
+				}
+				ctSize, err := c.CiphertextSize()
+				if err != nil {
+					return nil, fmt.Errorf("%s/%s/%d: %w", name, shape, attrs, err)
+				}
+				for _, op := range ops {
+					if !c.Supported(op) {
+						continue
+					}
+					counts, err := c.Count(op)
+					if err != nil {
+						return nil, fmt.Errorf("%s/%s/%d/%s: %w", name, shape, attrs, op, err)
+					}
+					r, err := c.Measure(op, cfg.BenchTime)
+					if err != nil {
+						return nil, fmt.Errorf("%s/%s/%d/%s: %w", name, shape, attrs, op, err)
+					}
+					var estimated int64
+					if cfg.Costs != nil {
+						estimated = cfg.Costs.Estimate(counts).Nanoseconds()
+					}
+					results = append(results, Result{
+						Scheme:               name,
+						Shape:                shape,
+						Attrs:                attrs,
+						Rows:                 c.Rows,
+						Op:                   op,
+						N:                    r.N,
+						NsPerOp:              r.NsPerOp(),
+						MsPerOp:              float64(r.NsPerOp()) / 1e6,
+						AllocsPerOp:          r.AllocsPerOp(),
+						BytesPerOp:           r.BytesPerOp(),
+						CiphertextBytes:      ctSize.Len(size.Uncompressed),
+						CiphertextCompressed: ctSize.Len(size.Compressed),
+						Counts:               counts,
+						EstimatedNs:          estimated,
+					})
+				}
+			}
+		}
+	}
+	return results, nil
+}
+
+// csvHeader 与 Result 的 json 标签一致
+var csvHeader = []string{"scheme", "shape", "attrs", "rows", "op", "n", "ns_per_op", "ms_per_op", "allocs_per_op", "bytes_per_op", "ciphertext_bytes", "ciphertext_compressed",
+	"pairings", "g1_mul", "g2_mul", "gt_exp", "hash_g1", "hash_g2", "estimated_ns"}
+
+// WriteCSV 以 CSV 输出结果，第一行为表头
+func WriteCSV(w io.Writer, results []Result) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, r := range results {
+		record := []string{
+			r.Scheme,
+			r.Shape,
+			strconv.Itoa(r.Attrs),
+			strconv.Itoa(r.Rows),
+			r.Op,
+			strconv.Itoa(r.N),
+			strconv.FormatInt(r.NsPerOp, 10),
+			strconv.FormatFloat(r.MsPerOp, 'f', 4, 64),
+			strconv.FormatInt(r.AllocsPerOp, 10),
+			strconv.FormatInt(r.BytesPerOp, 10),
+			strconv.Itoa(r.CiphertextBytes),
+			strconv.Itoa(r.CiphertextCompressed),
+		}
+		for _, k := range opcount.Kinds() {
+			record = append(record, strconv.FormatInt(r.Get(k), 10))
+		}
+		record = append(record, strconv.FormatInt(r.EstimatedNs, 10))
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteJSON 以 JSON 数组输出结果
+func WriteJSON(w io.Writer, results []Result) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(results)
+}
@@ -0,0 +1,97 @@
+package bench
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/AUKUS561/PVOABE/oabe/schemes"
+	"github.com/stretchr/testify/require"
+)
+
+// go test -bench . ./bench
+func BenchmarkSchemes(b *testing.B) {
+	for _, name := range schemes.Names() {
+		for _, attrs := range []int{5, 10, 20} {
+			c, err := NewCase(name, "andor", attrs, 1)
+			if err != nil {
+				b.Fatal(err)
+			}
+			for _, op := range Ops {
+				if !c.Supported(op) {
+					continue
+				}
+				b.Run(name+"/attrs="+strconv.Itoa(attrs)+"/"+op, c.Bench(op))
+			}
+		}
+	}
+}
+
+func TestRunAndWrite(t *testing.T) {
+	cfg := Config{Seed: 3, Attrs: []int{4}, Shapes: []string{"andor", "threshold"}, BenchTime: "1x"}
+	results, err := Run(cfg)
+	require.NoError(t, err)
+
+	perScheme := make(map[string]int)
+	for _, r := range results {
+		perScheme[r.Scheme]++
+		require.Positive(t, r.NsPerOp, "%s %s", r.Scheme, r.Op)
+		require.Positive(t, r.CiphertextBytes)
+		require.Less(t, r.CiphertextCompressed, r.CiphertextBytes)
+		require.Positive(t, r.Rows)
+		if r.Op == OpProxyDecrypt || r.Op == OpDecrypt {
+			require.Positive(t, r.Pairings+r.GTExp, "%s %s", r.Scheme, r.Op)
+		}
+	}
+	// 每种形状：PVOABE 8 个算法，VOABE 7 个，ECPABE 与 FEABSE 各 6 个
+	require.Equal(t, map[string]int{"PVOABE": 16, "VOABE": 14, "ECPABE": 12, "FEABSE": 12}, perScheme)
+
+	// 相同的种子得到相同的策略
+	a, err := NewCase("PVOABE", "threshold", 12, 9)
+	require.NoError(t, err)
+	b, err := NewCase("PVOABE", "threshold", 12, 9)
+	require.NoError(t, err)
+	require.Equal(t, a.Policy, b.Policy)
+	require.Equal(t, a.UserSet, b.UserSet)
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteCSV(&buf, results))
+	records, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, len(results)+1)
+	require.Equal(t, csvHeader, records[0])
+
+	buf.Reset()
+	require.NoError(t, WriteJSON(&buf, results))
+	var decoded []Result
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	require.Equal(t, results, decoded)
+
+	_, err = NewCase("PVOABE", "spiral", 4, 1)
+	require.Error(t, err)
+}
+
+func TestMeasure(t *testing.T) {
+	c, err := NewCase("PVOABE", "andor", 4, 1)
+	require.NoError(t, err)
+	for _, op := range []string{OpEncrypt, OpProxyEncrypt} {
+		m, err := c.Measure(op, "3x")
+		require.NoError(t, err)
+		require.Equal(t, 3, m.N, op)
+		require.Positive(t, m.NsPerOp(), op)
+		require.Positive(t, m.AllocsPerOp(), op)
+	}
+	m, err := c.Measure(OpDecrypt, "20ms")
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, m.Total, 20*time.Millisecond)
+
+	for _, bt := range []string{"0x", "x", "-1s", "fast"} {
+		_, err = c.Measure(OpDecrypt, bt)
+		require.Error(t, err, bt)
+		_, err = Run(Config{Attrs: []int{4}, BenchTime: bt})
+		require.Error(t, err, bt)
+	}
+}
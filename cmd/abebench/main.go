@@ -0,0 +1,149 @@
+// abebench 对四个方案的每个算法做基准测试，按属性数与策略形状扫描，输出 CSV 或 JSON。
+//
+// 使用方式:
+//
+//	go run ./cmd/abebench -attrs 5,10,20 -shapes andor,threshold -format csv -o results.csv
+//	go run ./cmd/abebench -schemes PVOABE,VOABE -ops Encrypt,ProxyDecrypt -benchtime 20x -format json
+//	go run ./cmd/abebench -sizes -attrs 10,20 -shapes andor
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/AUKUS561/PVOABE/bench"
+	"github.com/AUKUS561/PVOABE/oabe/schemes"
+	"github.com/AUKUS561/PVOABE/opcount"
+	"github.com/AUKUS561/PVOABE/size"
+)
+
+func main() {
+	var (
+		schemeList = flag.String("schemes", strings.Join(schemes.Names(), ","), "comma separated schemes")
+		attrList   = flag.String("attrs", "5,10,20,30", "comma separated attribute counts")
+		shapeList  = flag.String("shapes", "andor", "comma separated policy shapes: andor, wide, threshold, reuse")
+		opList     = flag.String("ops", strings.Join(bench.Ops, ","), "comma separated algorithms")
+		seed       = flag.Int64("seed", 1, "seed for policy and user generation")
+		benchtime  = flag.String("benchtime", "1s", "run time per algorithm, e.g. 500ms or 50x")
+		format     = flag.String("format", "csv", "output format: csv or json")
+		out        = flag.String("o", "", "output file (default stdout)")
+		sizes      = flag.Bool("sizes", false, "print a per-component ciphertext size report instead of benchmarking")
+		estimate   = flag.Int("estimate", 0, "calibrate per-operation costs with this many iterations and report estimated_ns (0 disables)")
+	)
+	flag.Parse()
+
+	if *sizes {
+		if err := report(*schemeList, *attrList, *shapeList, *seed, *out); err != nil {
+			fmt.Fprintln(os.Stderr, "abebench:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if err := run(*schemeList, *attrList, *shapeList, *opList, *seed, *benchtime, *format, *out, *estimate); err != nil {
+		fmt.Fprintln(os.Stderr, "abebench:", err)
+		os.Exit(1)
+	}
+}
+
+// report 输出每个 (方案, 形状, 属性数) 组合的最终密文在两种编码下的大小
+func report(schemeList, attrList, shapeList string, seed int64, out string) error {
+	attrs, err := parseAttrs(attrList)
+	if err != nil {
+		return err
+	}
+	var reports []size.Report
+	for _, name := range split(schemeList) {
+		for _, shape := range split(shapeList) {
+			for _, n := range attrs {
+				c, err := bench.NewCase(name, shape, n, seed)
+				if err != nil {
+					return err
+				}
+				r, err := c.CiphertextReport()
+				if err != nil {
+					return err
+				}
+				reports = append(reports, r)
+			}
+		}
+	}
+	return withOutput(out, func(w io.Writer) error {
+		return size.Write(w, reports...)
+	})
+}
+
+func run(schemeList, attrList, shapeList, opList string, seed int64, benchtime, format, out string, estimate int) error {
+	attrs, err := parseAttrs(attrList)
+	if err != nil {
+		return err
+	}
+	if format != "csv" && format != "json" {
+		return fmt.Errorf("unknown format %q", format)
+	}
+
+	var costs *opcount.Costs
+	if estimate > 0 {
+		c := opcount.Calibrate(estimate)
+		costs = &c
+	}
+
+	results, err := bench.Run(bench.Config{
+		Seed:      seed,
+		Schemes:   split(schemeList),
+		Attrs:     attrs,
+		Shapes:    split(shapeList),
+		Ops:       split(opList),
+		BenchTime: benchtime,
+		Costs:     costs,
+	})
+	if err != nil {
+		return err
+	}
+
+	return withOutput(out, func(w io.Writer) error {
+		if format == "json" {
+			return bench.WriteJSON(w, results)
+		}
+		return bench.WriteCSV(w, results)
+	})
+}
+
+func parseAttrs(s string) ([]int, error) {
+	var attrs []int
+	for _, part := range split(s) {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid attribute count %q", part)
+		}
+		attrs = append(attrs, n)
+	}
+	return attrs, nil
+}
+
+// withOutput 把 write 的输出写到文件 out，out 为空时写到标准输出
+func withOutput(out string, write func(io.Writer) error) error {
+	if out == "" {
+		return write(os.Stdout)
+	}
+	f, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return write(f)
+}
+
+func split(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
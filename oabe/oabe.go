@@ -0,0 +1,141 @@
+package oabe
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/AUKUS561/PVOABE/opcount"
+	"github.com/fentec-project/bn256"
+)
+
+/*
+OutsourcedABE 是 PVOABE、VOABE、ECPABE、FEABSE 共用的接口，按角色划分步骤：
+
+	授权机构  Setup, KeyGen
+	数据拥有者 Encrypt
+	代理服务器 ProxyEncrypt, ProxyDecrypt
+	验证方    VerifyEncryption, VerifyDecryption（方案不支持时返回 ErrNotSupported）
+	数据用户  Decrypt
+
+各方案的密钥与密文对本接口是不透明的，只在各自的适配器内部做类型断言，
+因此同一段代码可以通过 schemes.New(name) 在不同方案之间切换。
+
+使用方式:
+
+	s, _ := schemes.New("PVOABE")
+	auth, _ := s.Setup(U)
+	owner, _ := s.KeyGen(auth, "DO", SDO)
+	user, _ := s.KeyGen(auth, "DU", SDU)
+	key, recovered, err := oabe.Run(s, auth, owner, user, "Attr1 AND Attr2")
+*/
+type OutsourcedABE interface {
+	// Name 返回方案名，与 schemes.New 的参数一致
+	Name() string
+	// Supports 说明方案支持哪些可选步骤
+	Supports() Support
+
+	// Setup 由授权机构在属性全集 universe 上运行
+	Setup(universe []string) (*Authority, error)
+	// KeyGen 由授权机构为身份 id、属性集合 attrs 签发密钥
+	KeyGen(auth *Authority, id string, attrs []string) (*UserKey, error)
+
+	// Encrypt 由数据拥有者在策略 policy 下封装一个随机的 GT 密钥，返回（尚未外包处理的）密文与该密钥
+	Encrypt(auth *Authority, owner *UserKey, policy string) (*Ciphertext, *bn256.GT, error)
+	// ProxyEncrypt 由代理服务器完成外包加密，就地更新 ct；方案没有外包加密时不做任何事
+	ProxyEncrypt(auth *Authority, owner *UserKey, ct *Ciphertext) error
+	// VerifyEncryption 验证代理服务器的外包加密结果；验证失败返回 ErrVerificationFailed
+	VerifyEncryption(auth *Authority, owner *UserKey, ct *Ciphertext) error
+
+	// ProxyDecrypt 由代理服务器用用户的转换密钥做外包解密
+	ProxyDecrypt(auth *Authority, user *UserKey, ct *Ciphertext) (*Transformed, error)
+	// VerifyDecryption 验证代理服务器的外包解密结果；验证失败返回 ErrVerificationFailed
+	VerifyDecryption(auth *Authority, user *UserKey, ct *Ciphertext, tr *Transformed) error
+	// Decrypt 由数据用户用自己的私钥从外包解密结果中恢复 GT 密钥
+	Decrypt(auth *Authority, user *UserKey, ct *Ciphertext, tr *Transformed) (*bn256.GT, error)
+}
+
+// Counted 由支持运算计数的适配器实现，c 为 nil 时关闭计数
+type Counted interface {
+	SetCounter(c *opcount.Counter)
+}
+
+var (
+	ErrNotSupported       = errors.New("oabe: operation not supported by this scheme")
+	ErrVerificationFailed = errors.New("oabe: verification failed")
+	ErrWrongScheme        = errors.New("oabe: value was produced by a different scheme")
+)
+
+// Support 列出方案支持的可选步骤
+type Support struct {
+	ProxyEncrypt     bool
+	VerifyEncryption bool
+	VerifyDecryption bool
+}
+
+// Authority 是 Setup 的输出，按持有者划分
+type Authority struct {
+	Scheme   string
+	Universe []string
+	Public   any // 公开参数，所有角色可见
+	Master   any // 授权机构主密钥，只用于 KeyGen
+	Proxy    any // 代理服务器的私钥（如 PVOABE 的 PVGSS 私钥），没有时为 nil
+	Verifier any // 验证方的私钥（如 VOABE 中 PV 的 skPV），没有时为 nil
+}
+
+// UserKey 是 KeyGen 的输出：Proxy 交给代理服务器，User 由用户自己保存
+type UserKey struct {
+	Scheme string
+	ID     string
+	Attrs  []string
+	Proxy  any
+	User   any
+}
+
+// Ciphertext 包装各方案自己的密文
+type Ciphertext struct {
+	Scheme string
+	Policy string
+	Value  any
+}
+
+// Transformed 是外包解密的结果以及（方案支持时）正确性证明
+type Transformed struct {
+	Scheme string
+	Value  any
+	Proof  any
+}
+
+// Run 按顺序执行 Encrypt、ProxyEncrypt、VerifyEncryption、ProxyDecrypt、VerifyDecryption、Decrypt，
+// 跳过方案不支持的验证步骤，返回封装的密钥与用户恢复出的密钥
+func Run(s OutsourcedABE, auth *Authority, owner, user *UserKey, policy string) (key, recovered *bn256.GT, err error) {
+	ct, key, err := s.Encrypt(auth, owner, policy)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s Encrypt: %w", s.Name(), err)
+	}
+	if err := s.ProxyEncrypt(auth, owner, ct); err != nil {
+		return nil, nil, fmt.Errorf("%s ProxyEncrypt: %w", s.Name(), err)
+	}
+	if err := s.VerifyEncryption(auth, owner, ct); err != nil && !errors.Is(err, ErrNotSupported) {
+		return nil, nil, fmt.Errorf("%s VerifyEncryption: %w", s.Name(), err)
+	}
+	tr, err := s.ProxyDecrypt(auth, user, ct)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s ProxyDecrypt: %w", s.Name(), err)
+	}
+	if err := s.VerifyDecryption(auth, user, ct, tr); err != nil && !errors.Is(err, ErrNotSupported) {
+		return nil, nil, fmt.Errorf("%s VerifyDecryption: %w", s.Name(), err)
+	}
+	recovered, err = s.Decrypt(auth, user, ct, tr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s Decrypt: %w", s.Name(), err)
+	}
+	return key, recovered, nil
+}
+
+// Check 确认密钥或密文由方案 scheme 生成，供适配器在类型断言前调用
+func Check(scheme, got string) error {
+	if got != scheme {
+		return fmt.Errorf("%w: expected %s, got %s", ErrWrongScheme, scheme, got)
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package schemes
+
+import (
+	"fmt"
+
+	ecpabe "github.com/AUKUS561/PVOABE/ECPABE"
+	feabse "github.com/AUKUS561/PVOABE/FEABSE"
+	"github.com/AUKUS561/PVOABE/PVOABE"
+	"github.com/AUKUS561/PVOABE/VOABE"
+	"github.com/AUKUS561/PVOABE/oabe"
+)
+
+// 方案名 -> 构造函数，schemes 包单独存在是为了让 oabe 不依赖具体方案
+var constructors = map[string]func() oabe.OutsourcedABE{
+	PVOABE.Name: func() oabe.OutsourcedABE { return PVOABE.NewOutsourced() },
+	VOABE.Name:  func() oabe.OutsourcedABE { return VOABE.NewOutsourced() },
+	ecpabe.Name: func() oabe.OutsourcedABE { return ecpabe.NewOutsourced() },
+	feabse.Name: func() oabe.OutsourcedABE { return feabse.NewOutsourced() },
+	//可选变体，不在 Names() 中，需要时按名字显式选择
+	ecpabe.VerifiableName: func() oabe.OutsourcedABE { return ecpabe.NewOutsourcedVerifiable() },
+}
+
+// Variants 返回可按名字选择、但不在默认列表 Names() 中的方案变体
+func Variants() []string {
+	return []string{ecpabe.VerifiableName}
+}
+
+// Names 返回所有方案名，顺序固定
+func Names() []string {
+	return []string{PVOABE.Name, VOABE.Name, ecpabe.Name, feabse.Name}
+}
+
+// New 按方案名创建适配器，如 New("PVOABE")
+func New(name string) (oabe.OutsourcedABE, error) {
+	c, ok := constructors[name]
+	if !ok {
+		return nil, fmt.Errorf("oabe: unknown scheme %q, expected one of %v", name, append(Names(), Variants()...))
+	}
+	return c(), nil
+}
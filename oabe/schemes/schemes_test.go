@@ -0,0 +1,75 @@
+package schemes
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/AUKUS561/PVOABE/oabe"
+	"github.com/AUKUS561/PVOABE/policygen"
+	"github.com/fentec-project/bn256"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAllSchemesRoundTrip(t *testing.T) {
+	U := policygen.Attributes(10)
+	accessPolicy := "Attr1 AND (Attr2 OR Attr3) AND 2 OF (Attr4, Attr5, Attr6)"
+
+	for _, name := range append(Names(), Variants()...) {
+		t.Run(name, func(t *testing.T) {
+			s, err := New(name)
+			require.NoError(t, err)
+			require.Equal(t, name, s.Name())
+
+			auth, err := s.Setup(U)
+			require.NoError(t, err)
+			owner, err := s.KeyGen(auth, "DO-001", U)
+			require.NoError(t, err)
+			user, err := s.KeyGen(auth, "DU-001", []string{"Attr1", "Attr3", "Attr4", "Attr6"})
+			require.NoError(t, err)
+
+			key, recovered, err := oabe.Run(s, auth, owner, user, accessPolicy)
+			require.NoError(t, err)
+			require.Equal(t, key.String(), recovered.String(), "%s should recover the encapsulated key", name)
+
+			// 属性不满足策略时代理服务器无法完成外包解密
+			outsider, err := s.KeyGen(auth, "DU-002", []string{"Attr1", "Attr3", "Attr4"})
+			require.NoError(t, err)
+			_, _, err = oabe.Run(s, auth, owner, outsider, accessPolicy)
+			require.Error(t, err)
+
+			// 不支持的验证步骤返回 ErrNotSupported
+			ct, _, err := s.Encrypt(auth, owner, accessPolicy)
+			require.NoError(t, err)
+			require.NoError(t, s.ProxyEncrypt(auth, owner, ct))
+			err = s.VerifyEncryption(auth, owner, ct)
+			require.Equal(t, !s.Supports().VerifyEncryption, errors.Is(err, oabe.ErrNotSupported))
+			require.True(t, err == nil || errors.Is(err, oabe.ErrNotSupported))
+
+			tr, err := s.ProxyDecrypt(auth, user, ct)
+			require.NoError(t, err)
+			err = s.VerifyDecryption(auth, user, ct, tr)
+			if s.Supports().VerifyDecryption {
+				require.NoError(t, err)
+				// 代理服务器篡改外包解密结果会被发现
+				forged := *tr
+				forged.Value = new(bn256.GT).Add(tr.Value.(*bn256.GT), tr.Value.(*bn256.GT))
+				require.ErrorIs(t, s.VerifyDecryption(auth, user, ct, &forged), oabe.ErrVerificationFailed)
+			} else {
+				require.ErrorIs(t, err, oabe.ErrNotSupported)
+			}
+		})
+	}
+
+	_, err := New("CPABE")
+	require.Error(t, err)
+}
+
+func TestWrongScheme(t *testing.T) {
+	U := policygen.Attributes(3)
+	a, _ := New(Names()[0])
+	b, _ := New(Names()[3])
+	authA, err := a.Setup(U)
+	require.NoError(t, err)
+	_, err = b.KeyGen(authA, "DU", U)
+	require.ErrorIs(t, err, oabe.ErrWrongScheme)
+}
@@ -0,0 +1,54 @@
+package opcount
+
+import (
+	"crypto/sha256"
+	"math/big"
+	"time"
+
+	"github.com/fentec-project/bn256"
+)
+
+// Costs 是每类运算的单次耗时，用于由运算次数估算算法的运行时间
+type Costs [numKinds]time.Duration
+
+// Estimate 返回 n 对应的估算耗时 Σ n_k · cost_k
+func (c Costs) Estimate(n Counts) time.Duration {
+	var total time.Duration
+	for _, k := range Kinds() {
+		total += time.Duration(n.Get(k)) * c[k]
+	}
+	return total
+}
+
+// Calibrate 在本机上把每类运算各执行 iterations 次，测出单次耗时。
+// 哈希到曲线按各方案的实现计：SHA-256 后做一次基点标量乘
+func Calibrate(iterations int) Costs {
+	if iterations < 1 {
+		iterations = 1
+	}
+	k, _ := new(big.Int).SetString("1234567890123456789012345678901234567890", 10)
+	g1 := new(bn256.G1).ScalarBaseMult(k)
+	g2 := new(bn256.G2).ScalarBaseMult(k)
+	gt := bn256.Pair(g1, g2)
+
+	measure := func(f func(i int)) time.Duration {
+		start := time.Now()
+		for i := 0; i < iterations; i++ {
+			f(i)
+		}
+		return time.Since(start) / time.Duration(iterations)
+	}
+	hash := func(i int) *big.Int {
+		h := sha256.Sum256([]byte{byte(i), byte(i >> 8)})
+		return new(big.Int).SetBytes(h[:])
+	}
+
+	var c Costs
+	c[Pairing] = measure(func(int) { bn256.Pair(g1, g2) })
+	c[G1Mul] = measure(func(int) { new(bn256.G1).ScalarMult(g1, k) })
+	c[G2Mul] = measure(func(int) { new(bn256.G2).ScalarMult(g2, k) })
+	c[GTExp] = measure(func(int) { new(bn256.GT).ScalarMult(gt, k) })
+	c[HashToG1] = measure(func(i int) { new(bn256.G1).ScalarBaseMult(hash(i)) })
+	c[HashToG2] = measure(func(i int) { new(bn256.G2).ScalarBaseMult(hash(i)) })
+	return c
+}
@@ -0,0 +1,79 @@
+package opcount
+
+// Size 是决定运算次数的输入规模
+type Size struct {
+	Universe int // 属性全集的大小
+	Attrs    int // 用户密钥的属性数 |Su|
+	Rows     int // 策略 MSP 的行数 l
+	Used     int // 参与重构的行数 |I|，即重构计划中系数非零的行
+}
+
+// Formula 由输入规模解析地给出算法执行一次的运算次数，
+// 与 Costs.Estimate 组合即可在不运行算法的情况下估算任意规模的耗时
+type Formula func(Size) Counts
+
+// DLEQ 证明与验证的运算次数，与 DLEQ.ProofCost、DLEQ.VerifyCost 相同
+var (
+	dleqProof  = Counts{GTExp: 1, G1Mul: 1}
+	dleqVerify = Counts{GTExp: 2, G1Mul: 2}
+)
+
+// PVGSS 是 PVGSS 各算法的运算次数，键为方法名。未启用否定属性与隐藏策略
+var PVGSS = map[string]Formula{
+	// h = g^β、pk = h^a，每个属性 hx、hx'、pkx、pkx'
+	"Setup": func(s Size) Counts {
+		return Counts{G1Mul: int64(2 + 2*s.Universe), G2Mul: int64(2 * s.Universe)}
+	},
+	// L = g2^t、h^t，每个属性 Kx = pkx'^t
+	"KeyGen": func(s Size) Counts {
+		return Counts{G1Mul: 1, G2Mul: int64(1 + s.Attrs)}
+	},
+	// 每行 B^{λi}、pki^{-ri} 与 g^{ri}
+	"Share": func(s Size) Counts {
+		return Counts{G1Mul: int64(3 * s.Rows)}
+	},
+	// 每行 e(Ci, g2)、e(Ci', pkx') 与一次 GT 幂，再加 e(pk, C')
+	"SVerify": func(s Size) Counts {
+		return Counts{Pairings: int64(2*s.Rows + 1), GTExp: int64(s.Rows)}
+	},
+	// 每个参与重构的行两次配对与一次 GT 幂，R = ˜R^{1/a}，再加 DLEQ 证明
+	"Recon": func(s Size) Counts {
+		return Counts{Pairings: int64(2 * s.Used), GTExp: int64(s.Used + 1)}.Plus(dleqProof)
+	},
+	// 重新计算 ˜R，再验证 DLEQ 证明
+	"DVerify": func(s Size) Counts {
+		return Counts{Pairings: int64(2 * s.Used), GTExp: int64(s.Used)}.Plus(dleqVerify)
+	},
+}
+
+// PVOABE 是 PVOABE 各算法的运算次数，键为方法名。未启用追踪、纪元更新与隐藏策略
+var PVOABE = map[string]Formula{
+	// PVGSS.Setup，再加 e(g, g2)^α
+	"Setup": func(s Size) Counts {
+		return PVGSS["Setup"](s).Plus(Counts{Pairings: 1, GTExp: 1})
+	},
+	// PVGSS.KeyGen，再加 DSK = g^α · h^t 中的 g^α
+	"KeyGen": func(s Size) Counts {
+		return PVGSS["KeyGen"](s).Plus(Counts{G1Mul: 1})
+	},
+	// B = pk^s、C' = g2^s、e(g,g)^{αs}，以及密钥承诺 (g^z, Z^m)
+	"Enc": func(Size) Counts {
+		return Counts{G1Mul: 3, G2Mul: 1, GTExp: 1}
+	},
+	"OEnc": func(s Size) Counts {
+		return PVGSS["Share"](s)
+	},
+	"OEncVer": func(s Size) Counts {
+		return PVGSS["SVerify"](s)
+	},
+	"ODec": func(s Size) Counts {
+		return PVGSS["Recon"](s)
+	},
+	"ODecVer": func(s Size) Counts {
+		return PVGSS["DVerify"](s)
+	},
+	// e(DSK, C') 与密钥承诺的检查 Z^m
+	"Dec": func(Size) Counts {
+		return Counts{Pairings: 1, G1Mul: 1}
+	},
+}
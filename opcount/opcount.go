@@ -0,0 +1,202 @@
+package opcount
+
+import (
+	"math/big"
+	"sync/atomic"
+
+	"github.com/fentec-project/bn256"
+)
+
+/*
+opcount 统计各算法中的配对、G1/G2 标量乘、GT 幂以及哈希到曲线的次数，
+用于按计算代价（而不只是运行时间）比较方案。
+
+各方案在实例上持有一个 *Counter（字段 Ops），为 nil 时所有方法直接调用 bn256，不做计数，
+因此关闭时没有额外开销。
+
+使用方式:
+
+	c := opcount.New()
+	pvgss.Ops = c
+	pvgss.SVerify(pp, ct, cprime, msp)
+	fmt.Println(c.Snapshot().Pairings)
+*/
+
+// Kind 是一类被计数的运算
+type Kind int
+
+const (
+	Pairing  Kind = iota // e(g1, g2)
+	G1Mul                // G1 上的标量乘（含 ScalarBaseMult）
+	G2Mul                // G2 上的标量乘（含 ScalarBaseMult）
+	GTExp                // GT 上的幂运算
+	HashToG1             // 哈希到 G1
+	HashToG2             // 哈希到 G2
+	numKinds
+)
+
+var kindNames = [numKinds]string{"pairings", "g1_mul", "g2_mul", "gt_exp", "hash_g1", "hash_g2"}
+
+func (k Kind) String() string {
+	if k < 0 || k >= numKinds {
+		return "unknown"
+	}
+	return kindNames[k]
+}
+
+// Kinds 返回所有运算类别，顺序固定
+func Kinds() []Kind {
+	out := make([]Kind, numKinds)
+	for i := range out {
+		out[i] = Kind(i)
+	}
+	return out
+}
+
+// Counts 是某一时刻各类运算的次数
+type Counts struct {
+	Pairings int64 `json:"pairings"`
+	G1Mul    int64 `json:"g1_mul"`
+	G2Mul    int64 `json:"g2_mul"`
+	GTExp    int64 `json:"gt_exp"`
+	HashToG1 int64 `json:"hash_g1"`
+	HashToG2 int64 `json:"hash_g2"`
+}
+
+// Get 返回类别 k 的次数
+func (c Counts) Get(k Kind) int64 {
+	return *c.field(k)
+}
+
+func (c *Counts) field(k Kind) *int64 {
+	switch k {
+	case Pairing:
+		return &c.Pairings
+	case G1Mul:
+		return &c.G1Mul
+	case G2Mul:
+		return &c.G2Mul
+	case GTExp:
+		return &c.GTExp
+	case HashToG1:
+		return &c.HashToG1
+	case HashToG2:
+		return &c.HashToG2
+	}
+	panic("opcount: unknown kind")
+}
+
+// Plus 返回 c + d
+func (c Counts) Plus(d Counts) Counts {
+	for _, k := range Kinds() {
+		*c.field(k) += d.Get(k)
+	}
+	return c
+}
+
+// Times 返回 c 的 n 倍
+func (c Counts) Times(n int) Counts {
+	for _, k := range Kinds() {
+		*c.field(k) *= int64(n)
+	}
+	return c
+}
+
+// Counter 是可被多个 goroutine 共享的计数器，nil 表示不计数
+type Counter struct {
+	n [numKinds]atomic.Int64
+}
+
+func New() *Counter {
+	return &Counter{}
+}
+
+// Add 把类别 k 的次数加 n
+func (c *Counter) Add(k Kind, n int) {
+	if c == nil {
+		return
+	}
+	c.n[k].Add(int64(n))
+}
+
+// AddCounts 累加一组已知的运算次数，用于不经过 Counter 的辅助函数（如 DLEQ、LSSS 的泛型函数）
+func (c *Counter) AddCounts(d Counts) {
+	if c == nil {
+		return
+	}
+	for _, k := range Kinds() {
+		c.n[k].Add(d.Get(k))
+	}
+}
+
+// Snapshot 返回当前的计数
+func (c *Counter) Snapshot() Counts {
+	var out Counts
+	if c == nil {
+		return out
+	}
+	for _, k := range Kinds() {
+		*out.field(k) = c.n[k].Load()
+	}
+	return out
+}
+
+// Reset 把所有计数清零
+func (c *Counter) Reset() {
+	if c == nil {
+		return
+	}
+	for i := range c.n {
+		c.n[i].Store(0)
+	}
+}
+
+//——————————————————————————————————————Counted bn256 operations————————————————————————————————————————————//
+
+// Pair 计算 e(a, b)
+func (c *Counter) Pair(a *bn256.G1, b *bn256.G2) *bn256.GT {
+	c.Add(Pairing, 1)
+	return bn256.Pair(a, b)
+}
+
+// G1Mul 计算 a^k ∈ G1
+func (c *Counter) G1Mul(a *bn256.G1, k *big.Int) *bn256.G1 {
+	c.Add(G1Mul, 1)
+	return new(bn256.G1).ScalarMult(a, k)
+}
+
+// G1Base 计算 g^k ∈ G1
+func (c *Counter) G1Base(k *big.Int) *bn256.G1 {
+	c.Add(G1Mul, 1)
+	return new(bn256.G1).ScalarBaseMult(k)
+}
+
+// G2Mul 计算 a^k ∈ G2
+func (c *Counter) G2Mul(a *bn256.G2, k *big.Int) *bn256.G2 {
+	c.Add(G2Mul, 1)
+	return new(bn256.G2).ScalarMult(a, k)
+}
+
+// G2Base 计算 g2^k ∈ G2
+func (c *Counter) G2Base(k *big.Int) *bn256.G2 {
+	c.Add(G2Mul, 1)
+	return new(bn256.G2).ScalarBaseMult(k)
+}
+
+// GTExp 计算 a^k ∈ GT
+func (c *Counter) GTExp(a *bn256.GT, k *big.Int) *bn256.GT {
+	c.Add(GTExp, 1)
+	return new(bn256.GT).ScalarMult(a, k)
+}
+
+// HashG1 调用哈希函数 h 并计一次 HashToG1
+func (c *Counter) HashG1(h func(string) *bn256.G1, s string) *bn256.G1 {
+	c.Add(HashToG1, 1)
+	return h(s)
+}
+
+// HashG2 调用哈希函数 h 并计一次 HashToG2
+func (c *Counter) HashG2(h func(string) *bn256.G2, s string) *bn256.G2 {
+	c.Add(HashToG2, 1)
+	return h(s)
+}
@@ -0,0 +1,69 @@
+package opcount
+
+import (
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fentec-project/bn256"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCounter(t *testing.T) {
+	// nil 计数器不计数，但运算照常执行
+	var off *Counter
+	g1 := off.G1Base(big.NewInt(3))
+	g2 := off.G2Base(big.NewInt(5))
+	require.Equal(t, bn256.Pair(g1, g2).String(), off.Pair(g1, g2).String())
+	off.Add(Pairing, 1)
+	off.Reset()
+	require.Equal(t, Counts{}, off.Snapshot())
+
+	c := New()
+	gt := c.Pair(g1, g2)
+	c.GTExp(gt, big.NewInt(2))
+	c.G1Mul(g1, big.NewInt(2))
+	c.G2Mul(g2, big.NewInt(2))
+	c.HashG1(func(string) *bn256.G1 { return g1 }, "a")
+	c.HashG2(func(string) *bn256.G2 { return g2 }, "a")
+	c.AddCounts(Counts{G1Mul: 2})
+	require.Equal(t, Counts{Pairings: 1, G1Mul: 3, G2Mul: 1, GTExp: 1, HashToG1: 1, HashToG2: 1}, c.Snapshot())
+
+	c.Reset()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				c.Add(GTExp, 1)
+			}
+		}()
+	}
+	wg.Wait()
+	require.Equal(t, int64(800), c.Snapshot().Get(GTExp))
+
+	n := Counts{Pairings: 1, G1Mul: 2}
+	require.Equal(t, Counts{Pairings: 3, G1Mul: 6}, n.Times(3))
+	require.Equal(t, Counts{Pairings: 2, G1Mul: 4}, n.Plus(n))
+	require.Equal(t, Counts{Pairings: 1, G1Mul: 2}, n)
+	require.Equal(t, "pairings", Pairing.String())
+	require.Len(t, Kinds(), 6)
+}
+
+func TestEstimate(t *testing.T) {
+	var costs Costs
+	costs[Pairing] = time.Millisecond
+	costs[GTExp] = time.Microsecond
+	require.Equal(t, 2*time.Millisecond+3*time.Microsecond, costs.Estimate(Counts{Pairings: 2, GTExp: 3, G1Mul: 7}))
+
+	// 解析公式与 Estimate 组合：SVerify = 2·rows+1 次配对与 rows 次 GT 幂
+	require.Equal(t, 21*time.Millisecond+10*time.Microsecond, costs.Estimate(PVGSS["SVerify"](Size{Rows: 10})))
+	require.Equal(t, PVGSS["Share"](Size{Rows: 4}), PVOABE["OEnc"](Size{Rows: 4}))
+
+	measured := Calibrate(2)
+	for _, k := range Kinds() {
+		require.Positive(t, measured[k], k.String())
+	}
+}
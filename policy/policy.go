@@ -0,0 +1,650 @@
+package policy
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/fentec-project/gofe/abe"
+	"github.com/fentec-project/gofe/data"
+)
+
+// Op is the kind of a policy node
+type Op int
+
+const (
+	OpAttr      Op = iota // leaf: a single attribute
+	OpAnd                 // all children must hold
+	OpOr                  // at least one child must hold
+	OpNot                 // the single child must not hold
+	OpThreshold           // at least K children must hold
+)
+
+// Node is one node of a boolean access policy.
+// Leaves carry Attr, gates carry Children, threshold gates also carry K.
+type Node struct {
+	Op       Op
+	Attr     string
+	K        int
+	Children []*Node
+}
+
+// digestTag separates policy digests from every other hash in the scheme
+const digestTag = "PVOABE/policy/v1"
+
+// NegationPrefix marks the explicit negative attribute "not_x" that the
+// authority issues to every user who does not hold x. NOT gates are
+// compiled into these attributes so the MSP stays monotone.
+const NegationPrefix = "not_"
+
+// Attr builds a leaf node
+func Attr(name string) *Node {
+	return &Node{Op: OpAttr, Attr: name}
+}
+
+// And builds an AND gate over the given children
+func And(children ...*Node) *Node {
+	return &Node{Op: OpAnd, Children: children}
+}
+
+// Or builds an OR gate over the given children
+func Or(children ...*Node) *Node {
+	return &Node{Op: OpOr, Children: children}
+}
+
+// Not builds a NOT gate over the given child
+func Not(child *Node) *Node {
+	return &Node{Op: OpNot, Children: []*Node{child}}
+}
+
+// Threshold builds a k-of-n gate over the given children
+func Threshold(k int, children ...*Node) *Node {
+	return &Node{Op: OpThreshold, K: k, Children: children}
+}
+
+// Negate returns the negative attribute for x, e.g. "Contractor" -> "not_Contractor"
+func Negate(x string) string {
+	return NegationPrefix + x
+}
+
+// IsNegated reports whether x is a negative attribute
+func IsNegated(x string) bool {
+	return strings.HasPrefix(x, NegationPrefix)
+}
+
+// WithNegations extends an attribute universe U with not_x for every x in U
+func WithNegations(U []string) []string {
+	out := make([]string, 0, 2*len(U))
+	out = append(out, U...)
+	for _, x := range U {
+		if !IsNegated(x) {
+			out = append(out, Negate(x))
+		}
+	}
+	return out
+}
+
+//——————————————————————————————————————Parsing————————————————————————————————————————————//
+
+// Parse reads a policy in the syntax accepted by abe.BooleanToMSP,
+// e.g. "Attr1 OR (Attr2 AND Attr3)". AND binds tighter than OR,
+// and a term may be negated with NOT, e.g. "Employee AND NOT Contractor".
+// Threshold gates are written "2 OF (Doctor, Nurse, Pharmacist)".
+func Parse(s string) (*Node, error) {
+	p := &parser{toks: tokenize(s)}
+	if len(p.toks) == 0 {
+		return nil, fmt.Errorf("policy: empty expression")
+	}
+	n, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos != len(p.toks) {
+		return nil, fmt.Errorf("policy: unexpected token %q", p.toks[p.pos])
+	}
+	return n, nil
+}
+
+// MustParse is like Parse but panics on error, for static policies in tests and examples
+func MustParse(s string) *Node {
+	n, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
+
+func tokenize(s string) []string {
+	var toks []string
+	var cur strings.Builder
+	flush := func() {
+		if cur.Len() > 0 {
+			toks = append(toks, cur.String())
+			cur.Reset()
+		}
+	}
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == ',':
+			flush()
+			toks = append(toks, string(r))
+		case unicode.IsSpace(r):
+			flush()
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	flush()
+	return toks
+}
+
+type parser struct {
+	toks []string
+	pos  int
+}
+
+func (p *parser) peek() string {
+	if p.pos < len(p.toks) {
+		return p.toks[p.pos]
+	}
+	return ""
+}
+
+func (p *parser) parseOr() (*Node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	children := []*Node{left}
+	for p.peek() == "OR" {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, right)
+	}
+	if len(children) == 1 {
+		return left, nil
+	}
+	return Or(children...), nil
+}
+
+func (p *parser) parseAnd() (*Node, error) {
+	left, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	children := []*Node{left}
+	for p.peek() == "AND" {
+		p.pos++
+		right, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, right)
+	}
+	if len(children) == 1 {
+		return left, nil
+	}
+	return And(children...), nil
+}
+
+func (p *parser) parseTerm() (*Node, error) {
+	tok := p.peek()
+	switch tok {
+	case "":
+		return nil, fmt.Errorf("policy: unexpected end of expression")
+	case "(":
+		p.pos++
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, fmt.Errorf("policy: missing closing parenthesis")
+		}
+		p.pos++
+		return n, nil
+	case "NOT":
+		p.pos++
+		child, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		return Not(child), nil
+	case ")", ",", "AND", "OR", "OF":
+		return nil, fmt.Errorf("policy: unexpected token %q", tok)
+	}
+	if k, err := strconv.Atoi(tok); err == nil && p.pos+1 < len(p.toks) && p.toks[p.pos+1] == "OF" {
+		return p.parseThreshold(k)
+	}
+	p.pos++
+	return Attr(tok), nil
+}
+
+// parseThreshold parses "(a, b, ...)" after "k OF"
+func (p *parser) parseThreshold(k int) (*Node, error) {
+	p.pos += 2
+	if p.peek() != "(" {
+		return nil, fmt.Errorf("policy: expected ( after %d OF", k)
+	}
+	p.pos++
+	var children []*Node
+	for {
+		child, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, child)
+		if p.peek() == "," {
+			p.pos++
+			continue
+		}
+		if p.peek() != ")" {
+			return nil, fmt.Errorf("policy: missing closing parenthesis in threshold gate")
+		}
+		p.pos++
+		break
+	}
+	if k < 1 || k > len(children) {
+		return nil, fmt.Errorf("policy: threshold %d OF %d children is out of range", k, len(children))
+	}
+	return Threshold(k, children...), nil
+}
+
+//——————————————————————————————————————Simplification————————————————————————————————————————————//
+
+// Simplify returns an equivalent policy in normal form:
+// negations are pushed down to the attributes (De Morgan),
+// nested gates of the same kind are flattened, duplicate children are removed,
+// absorbed clauses are dropped (A AND (A OR B) = A, A OR (A AND B) = A)
+// and single-child gates are collapsed. The input is not modified.
+func Simplify(n *Node) *Node {
+	cur := pushNot(n, false)
+	for {
+		next := simplifyOnce(cur)
+		if next.String() == cur.String() {
+			return next
+		}
+		cur = next
+	}
+}
+
+// pushNot rewrites n (negated if neg is set) so that NOT only appears directly above attributes
+func pushNot(n *Node, neg bool) *Node {
+	switch n.Op {
+	case OpAttr:
+		if neg {
+			return Not(Attr(n.Attr))
+		}
+		return Attr(n.Attr)
+	case OpNot:
+		return pushNot(n.Children[0], !neg)
+	case OpThreshold:
+		// NOT (k OF n) = (n-k+1) OF (NOT children)
+		k := n.K
+		if neg {
+			k = len(n.Children) - n.K + 1
+		}
+		c := &Node{Op: OpThreshold, K: k}
+		for _, child := range n.Children {
+			c.Children = append(c.Children, pushNot(child, neg))
+		}
+		return c
+	}
+	op := n.Op
+	if neg {
+		// De Morgan: NOT (A AND B) = NOT A OR NOT B
+		if op == OpAnd {
+			op = OpOr
+		} else {
+			op = OpAnd
+		}
+	}
+	c := &Node{Op: op}
+	for _, child := range n.Children {
+		c.Children = append(c.Children, pushNot(child, neg))
+	}
+	return c
+}
+
+func simplifyOnce(n *Node) *Node {
+	switch n.Op {
+	case OpAttr:
+		return Attr(n.Attr)
+	case OpNot:
+		return Not(simplifyOnce(n.Children[0]))
+	case OpThreshold:
+		// 1 OF n = OR, n OF n = AND; other thresholds keep duplicates since they count
+		switch n.K {
+		case 1:
+			return simplifyOnce(Or(n.Children...))
+		case len(n.Children):
+			return simplifyOnce(And(n.Children...))
+		}
+		c := &Node{Op: OpThreshold, K: n.K}
+		for _, child := range n.Children {
+			c.Children = append(c.Children, simplifyOnce(child))
+		}
+		sortChildren(c.Children)
+		return c
+	}
+
+	// flatten: (A AND (B AND C)) -> (A AND B AND C)
+	var flat []*Node
+	for _, c := range n.Children {
+		c = simplifyOnce(c)
+		if c.Op == n.Op {
+			flat = append(flat, c.Children...)
+		} else {
+			flat = append(flat, c)
+		}
+	}
+
+	// dedupe by canonical form
+	seen := make(map[string]bool)
+	var uniq []*Node
+	for _, c := range flat {
+		key := c.String()
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		uniq = append(uniq, c)
+	}
+
+	// absorption: under AND drop c if another child implies c,
+	// under OR drop c if c implies another child
+	var kept []*Node
+	for i, c := range uniq {
+		absorbed := false
+		for j, o := range uniq {
+			if i == j {
+				continue
+			}
+			if n.Op == OpAnd && implies(o, c) || n.Op == OpOr && implies(c, o) {
+				absorbed = true
+				break
+			}
+		}
+		if !absorbed {
+			kept = append(kept, c)
+		}
+	}
+
+	if len(kept) == 1 {
+		return kept[0]
+	}
+	sortChildren(kept)
+	return &Node{Op: n.Op, Children: kept}
+}
+
+// implies is a sound syntactic check that a ⊨ b
+func implies(a, b *Node) bool {
+	if a.String() == b.String() {
+		return true
+	}
+	switch {
+	case a.Op == OpOr:
+		for _, c := range a.Children {
+			if !implies(c, b) {
+				return false
+			}
+		}
+		return true
+	case b.Op == OpAnd:
+		for _, c := range b.Children {
+			if !implies(a, c) {
+				return false
+			}
+		}
+		return true
+	case b.Op == OpOr:
+		for _, c := range b.Children {
+			if implies(a, c) {
+				return true
+			}
+		}
+	}
+	if a.Op == OpAnd {
+		for _, c := range a.Children {
+			if implies(c, b) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func sortChildren(children []*Node) {
+	sort.SliceStable(children, func(i, j int) bool {
+		return children[i].String() < children[j].String()
+	})
+}
+
+//——————————————————————————————————————Output————————————————————————————————————————————//
+
+// String pretty-prints the policy in BooleanToMSP syntax.
+// Children are printed in sorted order, so two trees that only differ
+// in the order of their operands print identically.
+func (n *Node) String() string {
+	return n.format(true)
+}
+
+func (n *Node) format(top bool) string {
+	switch n.Op {
+	case OpAttr:
+		return n.Attr
+	case OpNot:
+		return "NOT " + n.Children[0].format(false)
+	case OpThreshold:
+		parts := make([]string, len(n.Children))
+		for i, c := range n.Children {
+			parts[i] = c.format(true)
+		}
+		sort.Strings(parts)
+		return strconv.Itoa(n.K) + " OF (" + strings.Join(parts, ", ") + ")"
+	}
+	parts := make([]string, len(n.Children))
+	for i, c := range n.Children {
+		parts[i] = c.format(false)
+	}
+	sort.Strings(parts)
+	sep := " AND "
+	if n.Op == OpOr {
+		sep = " OR "
+	}
+	s := strings.Join(parts, sep)
+	if top {
+		return s
+	}
+	return "(" + s + ")"
+}
+
+// Digest returns the canonical SHA-256 digest of the policy.
+// Logically identical policies written differently share a digest.
+func (n *Node) Digest() []byte {
+	h := sha256.New()
+	h.Write([]byte(digestTag))
+	h.Write([]byte(Simplify(n).String()))
+	return h.Sum(nil)
+}
+
+// Attributes returns the distinct attributes the compiled MSP refers to, in sorted order.
+// A negated attribute x is reported as not_x.
+func (n *Node) Attributes() []string {
+	set := make(map[string]bool)
+	var walk func(*Node)
+	walk = func(x *Node) {
+		if x.Op == OpAttr {
+			set[x.Attr] = true
+			return
+		}
+		for _, c := range x.Children {
+			walk(c)
+		}
+	}
+	walk(compileNot(Simplify(n)))
+	attrs := make([]string, 0, len(set))
+	for a := range set {
+		attrs = append(attrs, a)
+	}
+	sort.Strings(attrs)
+	return attrs
+}
+
+// Satisfied reports whether the attribute set satisfies the policy
+func (n *Node) Satisfied(attrs []string) bool {
+	set := make(map[string]bool, len(attrs))
+	for _, a := range attrs {
+		set[a] = true
+	}
+	return n.eval(set)
+}
+
+func (n *Node) eval(set map[string]bool) bool {
+	switch n.Op {
+	case OpAttr:
+		return set[n.Attr]
+	case OpNot:
+		return !n.Children[0].eval(set)
+	case OpThreshold:
+		count := 0
+		for _, c := range n.Children {
+			if c.eval(set) {
+				count++
+			}
+		}
+		return count >= n.K
+	case OpAnd:
+		for _, c := range n.Children {
+			if !c.eval(set) {
+				return false
+			}
+		}
+		return true
+	default:
+		for _, c := range n.Children {
+			if c.eval(set) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// ToMSP simplifies the policy and converts it into an MSP matrix.
+// Every NOT x is compiled into the explicit attribute not_x.
+// AND/OR policies go through abe.BooleanToMSP; policies with threshold gates
+// use the Vandermonde construction in thresholdMSP.
+func (n *Node) ToMSP() (*abe.MSP, error) {
+	compiled := compileNot(Simplify(n))
+	if compiled.hasThreshold() {
+		return thresholdMSP(compiled), nil
+	}
+	return abe.BooleanToMSP(compiled.String(), false)
+}
+
+func (n *Node) hasThreshold() bool {
+	if n.Op == OpThreshold {
+		return true
+	}
+	for _, c := range n.Children {
+		if c.hasThreshold() {
+			return true
+		}
+	}
+	return false
+}
+
+// thresholdMSP builds the MSP of a NOT-free policy with target vector (1, 0, ..., 0).
+// A k-of-n gate with vector v allocates k-1 fresh columns and gives its j-th child
+// v + j·e_c + j^2·e_{c+1} + ... + j^{k-1}·e_{c+k-2}: any k children recombine to v
+// through the inverse Vandermonde matrix, fewer than k cannot. OR is 1-of-n, AND is n-of-n.
+func thresholdMSP(root *Node) *abe.MSP {
+	type row struct {
+		attr string
+		vec  map[int]*big.Int
+	}
+	var rows []row
+	cols := 1
+	var build func(n *Node, vec map[int]*big.Int)
+	build = func(n *Node, vec map[int]*big.Int) {
+		if n.Op == OpAttr {
+			rows = append(rows, row{attr: n.Attr, vec: vec})
+			return
+		}
+		k := n.K
+		switch n.Op {
+		case OpAnd:
+			k = len(n.Children)
+		case OpOr:
+			k = 1
+		}
+		base := cols
+		cols += k - 1
+		for j, child := range n.Children {
+			x := big.NewInt(int64(j + 1))
+			cv := make(map[int]*big.Int, len(vec)+k-1)
+			for c, v := range vec {
+				cv[c] = v
+			}
+			pow := big.NewInt(1)
+			for m := 0; m < k-1; m++ {
+				pow = new(big.Int).Mul(pow, x)
+				cv[base+m] = pow
+			}
+			build(child, cv)
+		}
+	}
+	build(root, map[int]*big.Int{0: big.NewInt(1)})
+
+	mat := make(data.Matrix, len(rows))
+	rowToAttrib := make([]string, len(rows))
+	for i, r := range rows {
+		mat[i] = make(data.Vector, cols)
+		for c := 0; c < cols; c++ {
+			if v, ok := r.vec[c]; ok {
+				mat[i][c] = new(big.Int).Set(v)
+			} else {
+				mat[i][c] = big.NewInt(0)
+			}
+		}
+		rowToAttrib[i] = r.attr
+	}
+	return &abe.MSP{Mat: mat, RowToAttrib: rowToAttrib}
+}
+
+// compileNot replaces NOT x leaves of a simplified policy with the attribute not_x
+func compileNot(n *Node) *Node {
+	switch n.Op {
+	case OpAttr:
+		return Attr(n.Attr)
+	case OpNot:
+		return Attr(Negate(n.Children[0].Attr))
+	}
+	c := &Node{Op: n.Op, K: n.K}
+	for _, child := range n.Children {
+		c.Children = append(c.Children, compileNot(child))
+	}
+	return c
+}
+
+// Canonicalize parses, simplifies and converts a policy string in one step.
+// It returns the MSP together with the canonical digest of the policy.
+func Canonicalize(s string) (*abe.MSP, []byte, error) {
+	n, err := Parse(s)
+	if err != nil {
+		return nil, nil, err
+	}
+	msp, err := n.ToMSP()
+	if err != nil {
+		return nil, nil, err
+	}
+	return msp, n.Digest(), nil
+}
@@ -0,0 +1,115 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAndString(t *testing.T) {
+	n, err := Parse("Attr1 OR (Attr2 AND Attr3)")
+	require.NoError(t, err)
+	require.Equal(t, OpOr, n.Op)
+	require.Equal(t, "(Attr2 AND Attr3) OR Attr1", n.String())
+
+	// AND 优先级高于 OR
+	n, err = Parse("A OR B AND C")
+	require.NoError(t, err)
+	require.Equal(t, "(B AND C) OR A", n.String())
+
+	for _, bad := range []string{"", "A AND", "(A OR B", "A OR B)", "AND A", "A B"} {
+		_, err := Parse(bad)
+		require.Error(t, err, "policy %q should not parse", bad)
+	}
+}
+
+func TestSimplify(t *testing.T) {
+	cases := map[string]string{
+		"A AND (B AND C)":              "A AND B AND C",
+		"(A OR B) OR (C OR A)":         "A OR B OR C",
+		"A AND A":                      "A",
+		"A AND (A OR B)":               "A",
+		"A OR (A AND B)":               "A",
+		"(A AND B) OR (B AND A AND C)": "A AND B",
+		"(A OR B) AND (A OR B OR C)":   "A OR B",
+		"((A))":                        "A",
+		"(A AND B) OR (C AND D)":       "(A AND B) OR (C AND D)",
+	}
+	for in, want := range cases {
+		require.Equal(t, want, Simplify(MustParse(in)).String(), "simplify %q", in)
+	}
+}
+
+func TestDigest(t *testing.T) {
+	a := MustParse("Doctor AND (Cardiology OR Surgery)")
+	b := MustParse("(Surgery OR Cardiology) AND Doctor AND (Doctor OR Nurse)")
+	c := MustParse("Doctor OR (Cardiology AND Surgery)")
+	require.Equal(t, a.Digest(), b.Digest(), "equivalent policies must share a digest")
+	require.NotEqual(t, a.Digest(), c.Digest())
+	require.Len(t, a.Digest(), 32)
+}
+
+func TestToMSPCutsRows(t *testing.T) {
+	redundant := MustParse("(A AND B) OR (A AND B AND C) OR (B AND A)")
+	msp, err := redundant.ToMSP()
+	require.NoError(t, err)
+	require.Len(t, msp.Mat, 2)
+	require.ElementsMatch(t, []string{"A", "B"}, msp.RowToAttrib)
+
+	msp, digest, err := Canonicalize("Attr1 OR (Attr2 AND Attr3)")
+	require.NoError(t, err)
+	require.Len(t, msp.Mat, 3)
+	require.Equal(t, MustParse("(Attr3 AND Attr2) OR Attr1").Digest(), digest)
+}
+
+func TestSatisfied(t *testing.T) {
+	n := MustParse("Doctor AND (Cardiology OR Surgery)")
+	require.True(t, n.Satisfied([]string{"Doctor", "Surgery"}))
+	require.False(t, n.Satisfied([]string{"Doctor"}))
+	require.Equal(t, []string{"Cardiology", "Doctor", "Surgery"}, n.Attributes())
+}
+
+func TestNotGates(t *testing.T) {
+	n := MustParse("Employee AND NOT Contractor")
+	require.True(t, n.Satisfied([]string{"Employee"}))
+	require.False(t, n.Satisfied([]string{"Employee", "Contractor"}))
+	require.Equal(t, []string{"Employee", "not_Contractor"}, n.Attributes())
+
+	// De Morgan 与双重否定
+	require.Equal(t, "NOT A OR NOT B", Simplify(MustParse("NOT (A AND B)")).String())
+	require.Equal(t, "A", Simplify(MustParse("NOT NOT A")).String())
+	require.Equal(t,
+		MustParse("NOT (Contractor OR Intern) AND Employee").Digest(),
+		MustParse("Employee AND NOT Intern AND NOT Contractor").Digest())
+
+	msp, err := n.ToMSP()
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"Employee", "not_Contractor"}, msp.RowToAttrib)
+
+	require.Equal(t, []string{"A", "B", "not_A", "not_B"}, WithNegations([]string{"A", "B"}))
+	require.True(t, IsNegated(Negate("A")))
+}
+
+func TestThresholdGates(t *testing.T) {
+	n := MustParse("2 OF (Doctor, Nurse, Pharmacist) AND Hospital")
+	require.True(t, n.Satisfied([]string{"Hospital", "Doctor", "Nurse"}))
+	require.False(t, n.Satisfied([]string{"Hospital", "Doctor"}))
+	require.Equal(t, "2 OF (Doctor, Nurse, Pharmacist) AND Hospital", n.String())
+
+	// 1 OF n 即 OR，n OF n 即 AND
+	require.Equal(t, "A OR B", Simplify(MustParse("1 OF (A, B)")).String())
+	require.Equal(t, "A AND B", Simplify(MustParse("2 OF (B, A)")).String())
+	// NOT (2 OF 3) = 2 OF (NOT ...)
+	require.Equal(t, "2 OF (NOT A, NOT B, NOT C)", Simplify(MustParse("NOT 2 OF (A, B, C)")).String())
+
+	for _, bad := range []string{"0 OF (A, B)", "3 OF (A, B)", "2 OF A", "2 OF (A, B"} {
+		_, err := Parse(bad)
+		require.Error(t, err, "policy %q should not parse", bad)
+	}
+
+	msp, err := n.ToMSP()
+	require.NoError(t, err)
+	require.Len(t, msp.Mat, 4)
+	require.ElementsMatch(t, []string{"Doctor", "Nurse", "Pharmacist", "Hospital"}, msp.RowToAttrib)
+	require.Equal(t, 3, len(msp.Mat[0]))
+}
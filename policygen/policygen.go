@@ -0,0 +1,249 @@
+package policygen
+
+import (
+	"crypto/rand"
+	"errors"
+	"math"
+	"math/big"
+	mrand "math/rand"
+	"sort"
+	"strconv"
+
+	"github.com/AUKUS561/PVOABE/policy"
+)
+
+/*
+policygen 用固定种子生成访问策略与用户属性集合，使各方案的基准测试可以完全复现。
+
+使用方式:
+
+	g := policygen.New(policygen.Config{Seed: 42, FanOut: 3, ThresholdProb: 0.3, Satisfiable: 0.5})
+	w, err := g.Workload(20, 10) // 20 个属性的策略 + 10 个用户，其中 5 个满足策略
+*/
+
+// Config 控制策略树的形状与用户集合的分布
+type Config struct {
+	Seed          int64   // 随机种子，相同的 Config 生成相同的策略与用户
+	Depth         int     // 门的最大层数，0 表示不限（一直拆分到叶子）
+	FanOut        int     // 每个门的最大子节点数，小于 2 时按 2 处理（二叉树）
+	ThresholdProb float64 // 子节点数 ≥ 3 的门成为 k-of-n 门限门的概率
+	ReuseProb     float64 // 门额外引用一个已出现属性的概率，用于制造重复属性
+	Satisfiable   float64 // Users 生成的用户中满足策略的比例，取值 [0, 1]
+}
+
+// DefaultConfig 与原来的 GeneratePolicy 相同：随机二叉 AND/OR 树，每个属性出现一次，所有用户都满足策略
+func DefaultConfig(seed int64) Config {
+	return Config{Seed: seed, FanOut: 2, Satisfiable: 1}
+}
+
+// Generator 按 Config 生成策略与用户，不可被多个 goroutine 共享
+type Generator struct {
+	cfg Config
+	rng *mrand.Rand
+}
+
+func New(cfg Config) *Generator {
+	if cfg.FanOut < 2 {
+		cfg.FanOut = 2
+	}
+	return &Generator{cfg: cfg, rng: mrand.New(mrand.NewSource(cfg.Seed))}
+}
+
+// Workload 是一次基准测试的输入：策略、属性全集以及用户属性集合
+type Workload struct {
+	Policy     string
+	Attributes []string // Attr1, ..., AttrN
+	Users      [][]string
+}
+
+// Attributes 返回属性全集 Attr1, ..., Attrn
+func Attributes(n int) []string {
+	attrs := make([]string, n)
+	for i := 0; i < n; i++ {
+		attrs[i] = "Attr" + strconv.Itoa(i+1)
+	}
+	return attrs
+}
+
+// GeneratePolicy 用随机种子生成 attrCount 个属性上的二叉 AND/OR 策略，
+// 行为与各方案中原来的 GeneratePolicy 一致
+func GeneratePolicy(attrCount int) string {
+	seed, _ := rand.Int(rand.Reader, big.NewInt(math.MaxInt64))
+	return New(DefaultConfig(seed.Int64())).Policy(attrCount)
+}
+
+// Policy 生成 attrCount 个属性上的策略字符串
+func (g *Generator) Policy(attrCount int) string {
+	return g.Tree(attrCount).String()
+}
+
+// Tree 生成 attrCount 个属性上的策略树：Attr1..AttrN 打乱后随机划分给各个门，
+// 每个属性至少出现一次
+func (g *Generator) Tree(attrCount int) *policy.Node {
+	attrs := Attributes(attrCount)
+	g.rng.Shuffle(len(attrs), func(i, j int) { attrs[i], attrs[j] = attrs[j], attrs[i] })
+	var used []string
+	return g.build(attrs, 1, &used)
+}
+
+func (g *Generator) build(list []string, depth int, used *[]string) *policy.Node {
+	if len(list) == 1 {
+		*used = append(*used, list[0])
+		return policy.Attr(list[0])
+	}
+
+	// 到达最大层数时，剩余属性全部挂在这个门下
+	var parts [][]string
+	if g.cfg.Depth > 0 && depth >= g.cfg.Depth {
+		for _, a := range list {
+			parts = append(parts, []string{a})
+		}
+	} else {
+		parts = g.split(list)
+	}
+
+	children := make([]*policy.Node, 0, len(parts)+1)
+	for _, part := range parts {
+		children = append(children, g.build(part, depth+1, used))
+	}
+	if len(*used) > 0 && g.rng.Float64() < g.cfg.ReuseProb {
+		children = append(children, policy.Attr((*used)[g.rng.Intn(len(*used))]))
+	}
+
+	if len(children) >= 3 && g.rng.Float64() < g.cfg.ThresholdProb {
+		k := 2 + g.rng.Intn(len(children)-2) // [2, n-1]
+		return policy.Threshold(k, children...)
+	}
+	if g.rng.Intn(2) == 0 {
+		return policy.Or(children...)
+	}
+	return policy.And(children...)
+}
+
+// split 把 list 随机切成 2..FanOut 段非空的连续子序列
+func (g *Generator) split(list []string) [][]string {
+	maxParts := g.cfg.FanOut
+	if maxParts > len(list) {
+		maxParts = len(list)
+	}
+	n := 2 + g.rng.Intn(maxParts-1)
+
+	// 从 [1, len-1] 中取 n-1 个不同的切分点
+	cuts := g.rng.Perm(len(list) - 1)[:n-1]
+	for i := range cuts {
+		cuts[i]++
+	}
+	sort.Ints(cuts)
+
+	parts := make([][]string, 0, n)
+	prev := 0
+	for _, c := range cuts {
+		parts = append(parts, list[prev:c])
+		prev = c
+	}
+	return append(parts, list[prev:])
+}
+
+// Users 为策略 p 生成 n 个用户属性集合，其中恰好 round(Satisfiable·n) 个满足策略。
+// 满足策略的用户持有一个随机的极小满足集合；不满足的用户由极小满足集合随机删去属性得到。
+// p 必须是单调策略（不含 NOT）
+func (g *Generator) Users(p *policy.Node, n int) ([][]string, error) {
+	if containsNot(p) {
+		return nil, errors.New("policygen: Users requires a monotone policy without NOT")
+	}
+	satisfying := int(math.Round(g.cfg.Satisfiable * float64(n)))
+	if satisfying < 0 || satisfying > n {
+		return nil, errors.New("policygen: Satisfiable must be within [0, 1]")
+	}
+
+	users := make([][]string, n)
+	for _, i := range g.rng.Perm(n)[:satisfying] {
+		users[i] = g.minimal(p)
+	}
+	for i := range users {
+		if users[i] == nil {
+			users[i] = g.unsatisfying(p)
+		}
+	}
+	return users, nil
+}
+
+// minimal 返回 p 的一个随机极小满足集合（按字典序排列）
+func (g *Generator) minimal(p *policy.Node) []string {
+	set := make(map[string]bool)
+	var walk func(n *policy.Node)
+	walk = func(n *policy.Node) {
+		switch n.Op {
+		case policy.OpAttr:
+			set[n.Attr] = true
+		case policy.OpAnd:
+			for _, c := range n.Children {
+				walk(c)
+			}
+		case policy.OpOr:
+			walk(n.Children[g.rng.Intn(len(n.Children))])
+		case policy.OpThreshold:
+			for _, i := range g.rng.Perm(len(n.Children))[:n.K] {
+				walk(n.Children[i])
+			}
+		}
+	}
+	walk(p)
+
+	// 属性重复出现时，上面得到的集合不一定极小，逐个尝试去掉多余属性
+	attrs := sortedKeys(set)
+	for _, i := range g.rng.Perm(len(attrs)) {
+		delete(set, attrs[i])
+		if !p.Satisfied(sortedKeys(set)) {
+			set[attrs[i]] = true
+		}
+	}
+	return sortedKeys(set)
+}
+
+// unsatisfying 从一个极小满足集合中随机删去属性直到不再满足策略
+func (g *Generator) unsatisfying(p *policy.Node) []string {
+	attrs := g.minimal(p)
+	g.rng.Shuffle(len(attrs), func(i, j int) { attrs[i], attrs[j] = attrs[j], attrs[i] })
+	for len(attrs) > 0 && p.Satisfied(attrs) {
+		attrs = attrs[1:]
+	}
+	out := append([]string{}, attrs...)
+	sort.Strings(out)
+	return out
+}
+
+// Workload 生成 attrCount 个属性上的策略以及 users 个用户
+func (g *Generator) Workload(attrCount, users int) (*Workload, error) {
+	tree := g.Tree(attrCount)
+	sets, err := g.Users(tree, users)
+	if err != nil {
+		return nil, err
+	}
+	return &Workload{
+		Policy:     tree.String(),
+		Attributes: Attributes(attrCount),
+		Users:      sets,
+	}, nil
+}
+
+func containsNot(n *policy.Node) bool {
+	if n.Op == policy.OpNot {
+		return true
+	}
+	for _, c := range n.Children {
+		if containsNot(c) {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys(set map[string]bool) []string {
+	out := make([]string, 0, len(set))
+	for a := range set {
+		out = append(out, a)
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,70 @@
+package policygen
+
+import (
+	"testing"
+
+	"github.com/AUKUS561/PVOABE/LSSS"
+	"github.com/AUKUS561/PVOABE/policy"
+	"github.com/fentec-project/bn256"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeterministic(t *testing.T) {
+	cfg := Config{Seed: 7, Depth: 3, FanOut: 4, ThresholdProb: 0.5, ReuseProb: 0.3, Satisfiable: 0.5}
+	a, err := New(cfg).Workload(30, 10)
+	require.NoError(t, err)
+	b, err := New(cfg).Workload(30, 10)
+	require.NoError(t, err)
+	require.Equal(t, a, b, "same seed must give the same workload")
+
+	cfg.Seed = 8
+	c, err := New(cfg).Workload(30, 10)
+	require.NoError(t, err)
+	require.NotEqual(t, a.Policy, c.Policy)
+}
+
+func TestPolicyShape(t *testing.T) {
+	// 默认配置：二叉 AND/OR 树，每个属性恰好出现一次
+	p := policy.MustParse(New(DefaultConfig(1)).Policy(20))
+	require.ElementsMatch(t, Attributes(20), p.Attributes())
+	_, err := p.ToMSP()
+	require.NoError(t, err)
+
+	// 限制层数为 1 时只有一个门
+	tree := New(Config{Seed: 1, Depth: 1, FanOut: 2}).Tree(6)
+	require.Len(t, tree.Children, 6)
+
+	// 门限门可被解析并转为 MSP
+	g := New(Config{Seed: 3, FanOut: 5, ThresholdProb: 1})
+	s := g.Policy(12)
+	require.Contains(t, s, " OF (")
+	_, _, err = policy.Canonicalize(s)
+	require.NoError(t, err)
+
+	require.NotEmpty(t, GeneratePolicy(10))
+}
+
+func TestUsers(t *testing.T) {
+	g := New(Config{Seed: 11, Depth: 4, FanOut: 3, ThresholdProb: 0.5, ReuseProb: 0.5, Satisfiable: 0.3})
+	tree := g.Tree(25)
+	users, err := g.Users(tree, 20)
+	require.NoError(t, err)
+	require.Len(t, users, 20)
+
+	msp, err := tree.ToMSP()
+	require.NoError(t, err)
+	satisfied := 0
+	for _, u := range users {
+		_, planErr := LSSS.NewReconPlan(msp, u, bn256.Order)
+		if tree.Satisfied(u) {
+			satisfied++
+			require.NoError(t, planErr, "user %v satisfies %s", u, tree)
+		} else {
+			require.Error(t, planErr, "user %v does not satisfy %s", u, tree)
+		}
+	}
+	require.Equal(t, 6, satisfied)
+
+	_, err = g.Users(policy.MustParse("A AND NOT B"), 1)
+	require.Error(t, err)
+}
@@ -0,0 +1,240 @@
+package size
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+	"reflect"
+	"text/tabwriter"
+
+	"github.com/fentec-project/bn256"
+	"github.com/fentec-project/gofe/abe"
+)
+
+/*
+size 统计密文、密钥与证明中各组成部分的编码长度，用于容量规划与方案对比。
+
+长度按两种编码给出：
+  - Uncompressed: bn256 的 Marshal 输出，G1 为 (x, y) 共 64 字节，G2 为 1 字节标志加 (x, y) 共 129 字节，GT 为 Fp12 的 384 字节
+  - Compressed:   点压缩后 G1 只存 x 与符号位共 32 字节，G2 为 64 字节；GT 按代数环面 T2 压缩为 192 字节
+
+bn256 本身只实现未压缩编码，压缩长度是按上述标准压缩方式计算的理论值。
+Zp 中的标量均为 32 字节；访问结构 (M, ρ) 不计入，随密文以策略字符串传输。
+*/
+
+// Encoding 是群元素的编码方式
+type Encoding int
+
+const (
+	Uncompressed Encoding = iota
+	Compressed
+)
+
+func (e Encoding) String() string {
+	if e == Compressed {
+		return "compressed"
+	}
+	return "uncompressed"
+}
+
+// ScalarLen 是 Zp 中元素的字节数
+const ScalarLen = 32
+
+// G1Len 返回 G1 元素的字节数
+func (e Encoding) G1Len() int {
+	if e == Compressed {
+		return 32
+	}
+	return 64
+}
+
+// G2Len 返回 G2 元素的字节数
+func (e Encoding) G2Len() int {
+	if e == Compressed {
+		return 64
+	}
+	return 129
+}
+
+// GTLen 返回 GT 元素的字节数
+func (e Encoding) GTLen() int {
+	if e == Compressed {
+		return 192
+	}
+	return 384
+}
+
+// Elements 是一组数据中各类元素的个数，Bytes 为摘要、属性名等原始字节
+type Elements struct {
+	G1      int `json:"g1"`
+	G2      int `json:"g2"`
+	GT      int `json:"gt"`
+	Scalars int `json:"scalars"`
+	Bytes   int `json:"bytes"`
+}
+
+// Plus 返回 e + f
+func (e Elements) Plus(f Elements) Elements {
+	return Elements{G1: e.G1 + f.G1, G2: e.G2 + f.G2, GT: e.GT + f.GT, Scalars: e.Scalars + f.Scalars, Bytes: e.Bytes + f.Bytes}
+}
+
+// Len 返回 e 在编码 enc 下的字节数
+func (e Elements) Len(enc Encoding) int {
+	return e.G1*enc.G1Len() + e.G2*enc.G2Len() + e.GT*enc.GTLen() + e.Scalars*ScalarLen + e.Bytes
+}
+
+// Sizer 由能列出自身组成部分的类型实现，如各方案的密文与密钥
+type Sizer interface {
+	Size() Report
+}
+
+// Component 是报告中的一项，如密文的 C、C' 或某一行的份额
+type Component struct {
+	Name string `json:"name"`
+	Elements
+}
+
+// Report 按组成部分列出一个对象的大小
+type Report struct {
+	Name       string      `json:"name"`
+	Components []Component `json:"components"`
+}
+
+// Add 追加一项
+func (r *Report) Add(name string, e Elements) {
+	r.Components = append(r.Components, Component{Name: name, Elements: e})
+}
+
+// Total 返回所有组成部分的元素个数之和
+func (r Report) Total() Elements {
+	var total Elements
+	for _, c := range r.Components {
+		total = total.Plus(c.Elements)
+	}
+	return total
+}
+
+// Len 返回 r 在编码 enc 下的总字节数
+func (r Report) Len(enc Encoding) int {
+	return r.Total().Len(enc)
+}
+
+// Write 以表格形式输出各报告，每项同时给出未压缩与压缩编码的字节数
+func Write(w io.Writer, reports ...Report) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	row := func(name string, e Elements) {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t\n", name, e.G1, e.G2, e.GT, e.Scalars, e.Bytes, e.Len(Uncompressed), e.Len(Compressed))
+	}
+	for i, r := range reports {
+		if i > 0 {
+			fmt.Fprintln(tw)
+		}
+		fmt.Fprintf(tw, "%s\tG1\tG2\tGT\tZp\tbytes\tuncompressed\tcompressed\t\n", r.Name)
+		for _, c := range r.Components {
+			row("  "+c.Name, c.Elements)
+		}
+		row("  total", r.Total())
+	}
+	return tw.Flush()
+}
+
+var (
+	g1Type     = reflect.TypeOf((*bn256.G1)(nil))
+	g2Type     = reflect.TypeOf((*bn256.G2)(nil))
+	gtType     = reflect.TypeOf((*bn256.GT)(nil))
+	scalarType = reflect.TypeOf((*big.Int)(nil))
+	mspType    = reflect.TypeOf((*abe.MSP)(nil))
+)
+
+// Of 递归统计 v 中的群元素、标量与原始字节，跳过未导出字段与访问结构
+func Of(v any) Elements {
+	return of(reflect.ValueOf(v))
+}
+
+func of(v reflect.Value) Elements {
+	if !v.IsValid() {
+		return Elements{}
+	}
+	switch v.Type() {
+	case g1Type:
+		return count(v, Elements{G1: 1})
+	case g2Type:
+		return count(v, Elements{G2: 1})
+	case gtType:
+		return count(v, Elements{GT: 1})
+	case scalarType:
+		return count(v, Elements{Scalars: 1})
+	case mspType:
+		return Elements{}
+	}
+
+	var e Elements
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if !v.IsNil() {
+			e = of(v.Elem())
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				e = e.Plus(of(v.Field(i)))
+			}
+		}
+	case reflect.Map:
+		it := v.MapRange()
+		for it.Next() {
+			e = e.Plus(of(it.Value()))
+		}
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return Elements{Bytes: v.Len()}
+		}
+		for i := 0; i < v.Len(); i++ {
+			e = e.Plus(of(v.Index(i)))
+		}
+	case reflect.String:
+		e.Bytes = v.Len()
+	}
+	return e
+}
+
+func count(v reflect.Value, e Elements) Elements {
+	if v.IsNil() {
+		return Elements{}
+	}
+	return e
+}
+
+// Breakdown 为结构体 v 生成报告，每个导出字段为一项，嵌入的结构体按其字段展开；
+// 值为 nil 或不含任何元素的字段（如访问结构）不列出
+func Breakdown(name string, v any) Report {
+	r := Report{Name: name}
+	breakdown(&r, reflect.ValueOf(v))
+	return r
+}
+
+func breakdown(r *Report, v reflect.Value) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		r.Add(v.Type().String(), of(v))
+		return
+	}
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Type().Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		if f.Anonymous {
+			breakdown(r, v.Field(i))
+			continue
+		}
+		if e := of(v.Field(i)); e != (Elements{}) {
+			r.Add(f.Name, e)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package size
+
+import (
+	"bytes"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/fentec-project/bn256"
+	"github.com/fentec-project/gofe/abe"
+	"github.com/stretchr/testify/require"
+)
+
+type Header struct {
+	D *bn256.G1
+}
+
+type sample struct {
+	*Header
+	C      *bn256.GT
+	Cprime *bn256.G2
+	Rows   map[int]*bn256.G1
+	Lambda []*big.Int
+	Digest []byte
+	Attrs  []string
+	Msp    *abe.MSP
+	Empty  *bn256.G1
+	secret *big.Int
+}
+
+func TestBreakdown(t *testing.T) {
+	g1 := new(bn256.G1).ScalarBaseMult(big.NewInt(2))
+	g2 := new(bn256.G2).ScalarBaseMult(big.NewInt(2))
+	msp, err := abe.BooleanToMSP("A AND B", false)
+	require.NoError(t, err)
+	v := &sample{
+		Header: &Header{D: g1},
+		C:      bn256.Pair(g1, g2),
+		Cprime: g2,
+		Rows:   map[int]*bn256.G1{0: g1, 1: g1, 2: g1},
+		Lambda: []*big.Int{big.NewInt(1), big.NewInt(2)},
+		Digest: make([]byte, 32),
+		Attrs:  []string{"A", "BC"},
+		Msp:    msp,
+		secret: big.NewInt(7),
+	}
+
+	// 按常量计算的长度应与 bn256 的实际编码一致
+	require.Len(t, g1.Marshal(), Uncompressed.G1Len())
+	require.Len(t, g2.Marshal(), Uncompressed.G2Len())
+	require.Len(t, v.C.Marshal(), Uncompressed.GTLen())
+
+	e := Of(v)
+	require.Equal(t, Elements{G1: 4, G2: 1, GT: 1, Scalars: 2, Bytes: 35}, e)
+	require.Equal(t, 4*64+129+384+2*32+35, e.Len(Uncompressed))
+	require.Equal(t, 4*32+64+192+2*32+35, e.Len(Compressed))
+
+	r := Breakdown("sample", v)
+	var names []string
+	for _, c := range r.Components {
+		names = append(names, c.Name)
+	}
+	// 嵌入结构体展开，nil 字段、访问结构与未导出字段不列出
+	require.Equal(t, []string{"D", "C", "Cprime", "Rows", "Lambda", "Digest", "Attrs"}, names)
+	require.Equal(t, e, r.Total())
+	require.Equal(t, Elements{G1: 3}, r.Components[3].Elements)
+	require.Equal(t, e.Len(Compressed), r.Len(Compressed))
+
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, r, Report{Name: "empty"}))
+	out := buf.String()
+	require.Contains(t, out, "uncompressed")
+	require.Equal(t, 2, strings.Count(out, "total"))
+	require.Equal(t, Elements{}, Of(nil))
+}